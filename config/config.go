@@ -49,6 +49,37 @@ func (c *config) Get() *MainConfig {
 func (c *config) load(cfg *MainConfig, path string, configPath string) error {
 	// Set default values
 	viper.SetDefault("log.level", "info")
+	viper.SetDefault("amqp.promptTopic", "prompt.requests")
+	viper.SetDefault("amqp.replyTopic", "prompt.replies")
+	viper.SetDefault("amqp.poisonTopic", "prompt.poison")
+	viper.SetDefault("amqp.maxRetries", 3)
+	viper.SetDefault("events.topicPrefix", "ai-proxy")
+	viper.SetDefault("embeddings.model", "text-embedding-3-small")
+	viper.SetDefault("semanticCache.threshold", 0.95)
+	viper.SetDefault("semanticCache.ttl", 86400)
+	viper.SetDefault("semanticCache.maxEntries", 10000)
+	viper.SetDefault("semanticCache.persistence", "memory")
+	viper.SetDefault("guardrails.moderation.path", "/v1/moderations")
+	viper.SetDefault("images.maxBytes", 20<<20)
+	viper.SetDefault("images.maxWidth", 8192)
+	viper.SetDefault("images.maxHeight", 8192)
+	viper.SetDefault("images.fetchTimeout", 10)
+	viper.SetDefault("images.blobPath", "./data/blobs")
+	viper.SetDefault("summary.maxImages", 4)
+	viper.SetDefault("retention.purgeInterval", 3600)
+	viper.SetDefault("rag.chunkSize", 2000)
+	viper.SetDefault("rag.chunkOverlap", 200)
+	viper.SetDefault("rag.topK", 4)
+	viper.SetDefault("rag.minScore", 0.3)
+	viper.SetDefault("rag.maxDocumentBytes", 10<<20)
+	viper.SetDefault("rag.embedBatchSize", 64)
+	viper.SetDefault("webhook.maxAttempts", 5)
+	viper.SetDefault("webhook.initialBackoff", 2)
+	viper.SetDefault("webhook.maxBackoff", 300)
+	viper.SetDefault("webhook.timeout", 10)
+	viper.SetDefault("batch.concurrency", 4)
+	viper.SetDefault("batch.maxFileBytes", 100<<20)
+	viper.SetDefault("batch.maxRequests", 50000)
 
 	viper.AddConfigPath(path)
 	if configPath != "" {
@@ -68,6 +99,22 @@ func (c *config) load(cfg *MainConfig, path string, configPath string) error {
 		return err
 	}
 
+	// embeddings share the chat completions upstream unless configured otherwise
+	if cfg.Embeddings.Host == "" {
+		cfg.Embeddings.Host = cfg.OpenAI.Host
+	}
+	if cfg.Embeddings.ApiKey == "" {
+		cfg.Embeddings.ApiKey = cfg.OpenAI.ApiKey
+	}
+
+	// as does the moderation upstream
+	if cfg.Guardrails.Moderation.Host == "" {
+		cfg.Guardrails.Moderation.Host = cfg.OpenAI.Host
+	}
+	if cfg.Guardrails.Moderation.ApiKey == "" {
+		cfg.Guardrails.Moderation.ApiKey = cfg.OpenAI.ApiKey
+	}
+
 	// Populate struct from environment variables using reflection
 	if err := c.populateFromEnv(cfg); err != nil {
 		return err
@@ -41,7 +41,7 @@ type MainConfig struct {
 		Host string `yaml:"host" validate:"required"`
 	} `yaml:"ui"`
 	MicrosoftOauth struct {
-		TenantID     string `yaml:"tenantID" validate:"required"`
+		TenantID     string `yaml:"tenantID" validate:"required"` // "organizations" or "common" signs in users of any tenant, see auth.allowedTenants
 		ClientID     string `yaml:"clientID" validate:"required"`
 		ClientSecret string `yaml:"clientSecret" validate:"required"`
 		RedirectURL  string `yaml:"redirectURL" validate:"required"`
@@ -58,12 +58,175 @@ type MainConfig struct {
 		TokenLifetime int    `yaml:"tokenLifetime" validate:"required"`
 		TokenLimit    int    `yaml:"tokenLimit" validate:"required"`
 	} `yaml:"openAI"`
-	Services []BackendService `yaml:"services"`
+	Embeddings struct {
+		Host   string `yaml:"host"` // defaults to openAI.host
+		Path   string `yaml:"path"`
+		ApiKey string `yaml:"apiKey"` // defaults to openAI.apiKey
+		Model  string `yaml:"model"`
+	} `yaml:"embeddings"`
+	SemanticCache struct {
+		Enabled     bool    `yaml:"enabled"`
+		Users       bool    `yaml:"users"`      // also serve the first prompt of SSO users' conversations
+		Threshold   float64 `yaml:"threshold"`  // minimum cosine similarity of a hit
+		TTL         int     `yaml:"ttl"`        // seconds an answer stays in the cache, 0 keeps it forever
+		MaxEntries  int     `yaml:"maxEntries"` // per namespace, the oldest answers are evicted first
+		Persistence string  `yaml:"persistence" validate:"omitempty,oneof=memory redis"`
+	} `yaml:"semanticCache"`
+	Redaction struct {
+		HashKey string          `yaml:"hashKey"` // HMAC key of the hash mode
+		Users   RedactionPolicy `yaml:"users"`   // policy for SSO user prompts
+	} `yaml:"redaction"`
+	Guardrails struct {
+		Moderation struct {
+			Host   string `yaml:"host"` // defaults to openAI.host
+			Path   string `yaml:"path"`
+			ApiKey string `yaml:"apiKey"` // defaults to openAI.apiKey
+			Model  string `yaml:"model"`
+		} `yaml:"moderation"`
+		Users GuardrailPolicy `yaml:"users"` // policy for SSO user prompts
+	} `yaml:"guardrails"`
+	Images struct {
+		MaxBytes     int64    `yaml:"maxBytes"`     // largest accepted image
+		MaxWidth     int      `yaml:"maxWidth"`     // pixels, 0 means no limit
+		MaxHeight    int      `yaml:"maxHeight"`    // pixels, 0 means no limit
+		AllowedTypes []string `yaml:"allowedTypes"` // MIME types, empty allows png, jpeg, gif and webp
+		FetchRemote  bool     `yaml:"fetchRemote"`  // download http(s) image URLs and keep them in the blob store instead of forwarding the URL
+		FetchHosts   []string `yaml:"fetchHosts"`   // hosts remote images may be fetched from, a `*.` prefix matches subdomains, empty allows every public address
+		FetchTimeout int      `yaml:"fetchTimeout"` // seconds
+		Reencode     bool     `yaml:"reencode"`     // re-encode fetched images, dropping their metadata
+		BlobPath     string   `yaml:"blobPath"`     // directory of the local blob store
+	} `yaml:"images"`
+	Summary struct {
+		DescribeImages bool   `yaml:"describeImages"` // send images to the summarizer so it describes them, otherwise they are only marked in the summary
+		VisionModel    string `yaml:"visionModel"`    // summarizes conversations with images, defaults to the summary model
+		MaxImages      int    `yaml:"maxImages"`      // most recent images sent to the summarizer
+	} `yaml:"summary"`
+	RAG struct {
+		ChunkSize        int     `yaml:"chunkSize"`        // characters per chunk
+		ChunkOverlap     int     `yaml:"chunkOverlap"`     // characters repeated from the previous chunk
+		TopK             int     `yaml:"topK"`             // chunks injected into a prompt
+		MinScore         float64 `yaml:"minScore"`         // minimum cosine similarity of an injected chunk
+		MaxDocumentBytes int64   `yaml:"maxDocumentBytes"` // largest accepted upload
+		EmbedBatchSize   int     `yaml:"embedBatchSize"`   // chunks embedded per upstream call
+	} `yaml:"rag"`
+	Retention struct {
+		PurgeInterval int                        `yaml:"purgeInterval"` // seconds between runs of the purge job, 0 disables it
+		ContextTTL    int                        `yaml:"contextTTL"`    // seconds the cached context of an idle conversation is kept, 0 keeps it until cleared
+		Users         RetentionPolicy            `yaml:"users"`         // SSO users whose email domain has no tenant policy
+		Tenants       map[string]RetentionPolicy `yaml:"tenants"`       // email domain -> policy of its users
+		PseudonymKey  string                     `yaml:"pseudonymKey"`  // HMAC key of the pseudonyms erased users leave in the ledger, erasure is refused without one
+	} `yaml:"retention"`
+	AMQP struct {
+		Enabled     bool   `yaml:"enabled"`
+		URL         string `yaml:"url" validate:"required_if=Enabled true"`
+		PromptTopic string `yaml:"promptTopic"` // service prompt requests
+		ReplyTopic  string `yaml:"replyTopic"`  // prompt results, keyed by correlation ID
+		PoisonTopic string `yaml:"poisonTopic"` // malformed or repeatedly failing requests
+		MaxRetries  int    `yaml:"maxRetries"`
+	} `yaml:"amqp"`
+	Events struct {
+		Enabled     bool   `yaml:"enabled"`     // requires amqp.enabled
+		TopicPrefix string `yaml:"topicPrefix"` // topic is <prefix>.<event type>
+	} `yaml:"events"`
+	Webhook struct {
+		MaxAttempts    int `yaml:"maxAttempts"`
+		InitialBackoff int `yaml:"initialBackoff"` // seconds, doubled after every failed attempt
+		MaxBackoff     int `yaml:"maxBackoff"`     // seconds
+		Timeout        int `yaml:"timeout"`        // seconds per attempt
+	} `yaml:"webhook"`
+	Batch struct {
+		Concurrency  int   `yaml:"concurrency"`  // upstream calls in flight across all batches
+		MaxFileBytes int64 `yaml:"maxFileBytes"` // largest accepted input file
+		MaxRequests  int   `yaml:"maxRequests"`  // most lines accepted in one input file
+	} `yaml:"batch"`
+	Auth     Auth                    `yaml:"auth"`
+	Roles    map[string]RolePolicy   `yaml:"roles"`
+	Pricing  map[string]ModelPricing `yaml:"pricing"` // model -> price, used for the usage ledger
+	Services []BackendService        `yaml:"services"`
+
+	UserTribes map[string]string `yaml:"userTribes"` // email domain -> tribe its SSO users are charged to
+
+}
+
+// ModelPricing is the upstream price of a model in USD per 1K tokens
+type ModelPricing struct {
+	PromptPer1K     float64 `yaml:"promptPer1K"`
+	CompletionPer1K float64 `yaml:"completionPer1K"`
+}
+
+// Auth maps identity provider claims to proxy roles
+type Auth struct {
+	DefaultRole string            `yaml:"defaultRole" validate:"omitempty,oneof=admin user service"`
+	AdminEmails []string          `yaml:"adminEmails"`
+	ClaimRoles  map[string]string `yaml:"claimRoles"` // IdP group/role claim value -> proxy role
+
+	AllowedDomains       []string `yaml:"allowedDomains"`       // email domains allowed to sign in, empty allows all
+	AllowedHostedDomains []string `yaml:"allowedHostedDomains"` // Google Workspace `hd` claims, empty allows all
+	AllowedTenants       []string `yaml:"allowedTenants"`       // Microsoft `tid` claims, empty allows all
+	DeniedUsers          []string `yaml:"deniedUsers"`          // emails that are always rejected
+}
+
+// RedactionPolicy selects how PII is redacted from prompts before they are sent upstream
+type RedactionPolicy struct {
+	Mode      string   `yaml:"mode" validate:"omitempty,oneof=mask hash placeholder"` // empty disables redaction
+	Detectors []string `yaml:"detectors"`                                             // email, phone, credit_card, national_id, iban, secret; empty runs all
+}
+
+// GuardrailPolicy screens prompts before they are sent upstream and answers before they are returned
+type GuardrailPolicy struct {
+	Blocklist      []string            `yaml:"blocklist"`      // case-insensitive regular expressions
+	DeniedTopics   map[string][]string `yaml:"deniedTopics"`   // topic -> keywords, matched as whole words
+	MaxPromptChars int                 `yaml:"maxPromptChars"` // 0 means no limit
+	Moderation     bool                `yaml:"moderation"`     // also screen with the moderation upstream
+}
+
+// InjectionPolicy screens service prompts for injected instructions and their answers for leaks.
+// Injection scores range from 0 to 1, a threshold of 0 turns its action off.
+type InjectionPolicy struct {
+	AnnotateThreshold float64 `yaml:"annotateThreshold"`                                     // scores reported in the response
+	StripThreshold    float64 `yaml:"stripThreshold"`                                        // messages whose injected instructions are removed
+	BlockThreshold    float64 `yaml:"blockThreshold"`                                        // prompts that are rejected
+	Leaks             string  `yaml:"leaks" validate:"omitempty,oneof=annotate block strip"` // answers leaking the system prompt or credentials, empty disables
+	LeakMinWords      int     `yaml:"leakMinWords"`                                          // consecutive system prompt words that make a leak, defaults to 8
+}
+
+// RetentionPolicy limits how long prompts and answers are stored
+type RetentionPolicy struct {
+	Days int `yaml:"days"` // conversations idle for longer, or service prompts older than this, are deleted; 0 keeps them
+}
+
+// RolePolicy restricts what a role is allowed to do
+type RolePolicy struct {
+	AllowedModels  []string `yaml:"allowedModels"`  // empty means every model is allowed
+	MinTemperature float64  `yaml:"minTemperature"` // lowest temperature SSO users may request, 0 means no limit
+	MaxTemperature float64  `yaml:"maxTemperature"` // highest temperature SSO users may request, 0 means no limit
+	MinTopP        float64  `yaml:"minTopP"`        // lowest top_p SSO users may request, 0 means no limit
+	MaxTopP        float64  `yaml:"maxTopP"`        // highest top_p SSO users may request, 0 means no limit
+	MaxTokens      int      `yaml:"maxTokens"`      // largest max_tokens SSO users may request, 0 means no limit
+	MaxStop        int      `yaml:"maxStop"`        // most stop sequences SSO users may set, 0 means no limit
+	MaxStopLength  int      `yaml:"maxStopLength"`  // longest stop sequence in characters, 0 means no limit
+	DenySeed       bool     `yaml:"denySeed"`       // rejects a fixed seed
 }
 
 type BackendService struct {
-	Tribe    string `yaml:"tribe"`
-	Name     string `yaml:"name"`
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+	Tribe      string `yaml:"tribe"`
+	Name       string `yaml:"name"`
+	Username   string `yaml:"username"`
+	Password   string `yaml:"password"`
+	TokenLimit int    `yaml:"tokenLimit"` // tokens per openAI.tokenLifetime, 0 means unlimited
+
+	CallbackHosts  []string `yaml:"callbackHosts"`  // hosts a callback_url may point to, a `*.` prefix matches subdomains
+	CallbackSecret string   `yaml:"callbackSecret"` // HMAC-SHA256 key used to sign callbacks
+
+	ResponseCacheTTL int `yaml:"responseCacheTTL"` // seconds responses to temperature 0 prompts are cached, 0 disables the cache
+
+	SemanticCache          bool    `yaml:"semanticCache"`          // serve near-duplicate prompts from the semantic cache
+	SemanticCacheThreshold float64 `yaml:"semanticCacheThreshold"` // overrides semanticCache.threshold when set
+
+	Persona string `yaml:"persona"` // persona put at the head of every prompt, name or name@vN
+
+	Redaction  RedactionPolicy `yaml:"redaction"`
+	Guardrails GuardrailPolicy `yaml:"guardrails"`
+	Injection  InjectionPolicy `yaml:"injection"`
+	Retention  RetentionPolicy `yaml:"retention"`
 }
@@ -8,19 +8,32 @@ import (
 	"os/signal"
 	"time"
 
+	"github.com/ThreeDotsLabs/watermill"
+	watermillAMQP "github.com/ThreeDotsLabs/watermill-amqp/v2/pkg/amqp"
+	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/abialemuel/AI-Proxy-Service/config"
+	userAPIamqp "github.com/abialemuel/AI-Proxy-Service/pkg/user/api/amqp"
 	userAPIhttp "github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 
 	mainCfg "github.com/abialemuel/AI-Proxy-Service/config"
+	commonAmqp "github.com/abialemuel/AI-Proxy-Service/pkg/common/amqp"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/common/cache"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/common/cache/redis"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/correlation"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/common/mongodb"
 	oauthmanager "github.com/abialemuel/AI-Proxy-Service/pkg/common/oauth"
 	userBusiness "github.com/abialemuel/AI-Proxy-Service/pkg/user/business"
+	userContract "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/contract"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/blobstore"
+	embeddingWebService "github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/embedding_webservice"
 	gpt4WebService "github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+	imageFetcherModule "github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/image_fetcher"
+	moderationWebService "github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/moderation_webservice"
 	userRepository "github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/vectorstore"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/webhook"
 	"github.com/abialemuel/poly-kit/infrastructure/apm"
 	"github.com/abialemuel/poly-kit/infrastructure/logger"
 	"github.com/labstack/echo/v4"
@@ -89,13 +102,51 @@ func main() {
 	endpoint := fmt.Sprintf("%s%s", cfg.Get().OpenAI.Host, cfg.Get().OpenAI.Path)
 	gpt4Webservice := gpt4WebService.NewGPT4WebService(endpoint, cfg.Get().OpenAI.ApiKey)
 
+	// init embeddings webservice
+	embeddingEndpoint := fmt.Sprintf("%s%s", cfg.Get().Embeddings.Host, cfg.Get().Embeddings.Path)
+	embeddingWebservice := embeddingWebService.NewEmbeddingWebService(embeddingEndpoint, cfg.Get().Embeddings.ApiKey)
+
+	// init moderation webservice
+	moderationEndpoint := fmt.Sprintf("%s%s", cfg.Get().Guardrails.Moderation.Host, cfg.Get().Guardrails.Moderation.Path)
+	moderationWebservice := moderationWebService.NewModerationWebService(moderationEndpoint, cfg.Get().Guardrails.Moderation.ApiKey)
+
+	// init semantic cache, it stays nil when disabled
+	var semanticCache userContract.VectorStore
+	if cfg.Get().SemanticCache.Enabled {
+		semanticCache = initializeSemanticCache(cfg.Get())
+	}
+
+	// init blob store and image fetcher
+	blobStore, err := blobstore.NewLocalBlobStore(cfg.Get().Images.BlobPath)
+	if err != nil {
+		log.Get().Error(err)
+		panic(err)
+	}
+	imageFetcher := imageFetcherModule.NewImageFetcher(time.Duration(cfg.Get().Images.FetchTimeout) * time.Second)
+
+	// init webhook sender
+	webhookSender := webhook.NewWebhookSender(time.Duration(cfg.Get().Webhook.Timeout) * time.Second)
+
 	// init mongoDB
 	urlHost := fmt.Sprintf("%s:%d", cfg.Get().Mongo.Host, cfg.Get().Mongo.Port)
 	mongoDB, err := mongodb.NewMongoDB(urlHost, cfg.Get().Mongo.Username, cfg.Get().Mongo.Password, cfg.Get().Mongo.DB)
 	userRepo := userRepository.NewMongoDBRepository(mongoDB)
+	if err := userRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Get().Error(err)
+		panic(err)
+	}
+
+	// init amqp, the publisher stays nil when amqp is disabled
+	var amqpPub message.Publisher
+	var amqpSub message.Subscriber
+	var publisher userContract.Publisher
+	if cfg.Get().AMQP.Enabled {
+		amqpPub, amqpSub = initializeAMQP(cfg.Get())
+		publisher = commonAmqp.NewPublisherSubscriber(amqpPub, amqpSub)
+	}
 
 	// init userService
-	userService := newUserService(cache, cfg.Get(), gpt4Webservice, userRepo)
+	userService := newUserService(cache, cfg.Get(), gpt4Webservice, userRepo, publisher, webhookSender, embeddingWebservice, semanticCache, moderationWebservice, blobStore, imageFetcher)
 
 	// Init HTTP client
 	e := echo.New()
@@ -105,11 +156,13 @@ func main() {
 	e.Use(otelecho.Middleware(cfg.Get().App.Name))
 	// datadog echo middleware
 	e.Use(dd.Middleware())
+	e.Use(correlation.Middleware)
 	e.Use(mw.CORSWithConfig(
 		mw.CORSConfig{
-			AllowOrigins: []string{"*"},
-			AllowHeaders: []string{echo.HeaderContentType, echo.HeaderAuthorization},
-			AllowMethods: []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
+			AllowOrigins:  []string{"*"},
+			AllowHeaders:  []string{echo.HeaderContentType, echo.HeaderAuthorization, echo.HeaderCacheControl, correlation.HeaderCorrelationID},
+			AllowMethods:  []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
+			ExposeHeaders: []string{correlation.HeaderCorrelationID, "X-Cache"},
 		}))
 
 	//health check
@@ -131,11 +184,36 @@ func main() {
 
 	authGuard := authguard.NewAuthGuard(*cfg.Get())
 	authGuard.AddService(cfg.Get().Services)
+	authGuard.SetUserResolver(userService)
+	authGuard.SetServiceKeyResolver(userService)
+	authGuard.SetLoginRecorder(userService)
 
 	// Register API
-	userHandler := userAPIhttp.NewHandler(userService, googleProvider, microsoftProvider, cfg.Get())
+	userHandler := userAPIhttp.NewHandler(userService, googleProvider, microsoftProvider, cfg.Get(), authGuard)
 	userAPIhttp.RegisterPath(e, userHandler, authGuard)
 
+	// Retry webhook deliveries, also those of instances that went away
+	go userService.RunWebhookRetries(context.Background())
+
+	// End batches left behind by instances that went away
+	go userService.RunBatchRecovery(context.Background())
+
+	// Purge conversations and service prompts past their retention
+	if cfg.Get().Retention.PurgeInterval > 0 {
+		go userService.RunRetention(context.Background())
+	}
+
+	// Register async API
+	var router *message.Router
+	if cfg.Get().AMQP.Enabled {
+		router = initializeRouter(cfg.Get(), amqpPub, amqpSub, userService)
+		go func() {
+			if err := router.Run(context.Background()); err != nil {
+				log.Get().Error(err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server with
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
@@ -148,6 +226,47 @@ func main() {
 	if err := e.Shutdown(ctx); err != nil {
 		log.Get().Fatal(err)
 	}
+	if router != nil {
+		router.Close()
+	}
+}
+
+func initializeAMQP(cfg *config.MainConfig) (message.Publisher, message.Subscriber) {
+	wmLogger := watermill.NewStdLogger(false, false)
+	amqpConfig := watermillAMQP.NewDurableQueueConfig(cfg.AMQP.URL)
+
+	pub, err := watermillAMQP.NewPublisher(amqpConfig, wmLogger)
+	if err != nil {
+		log.Get().Error(err)
+		panic(err)
+	}
+	sub, err := watermillAMQP.NewSubscriber(amqpConfig, wmLogger)
+	if err != nil {
+		log.Get().Error(err)
+		panic(err)
+	}
+	return pub, sub
+}
+
+func initializeRouter(cfg *config.MainConfig, pub message.Publisher, sub message.Subscriber, userService userBusiness.UserService) *message.Router {
+	router, err := message.NewRouter(message.RouterConfig{}, watermill.NewStdLogger(false, false))
+	if err != nil {
+		log.Get().Error(err)
+		panic(err)
+	}
+
+	userAPIamqp.RegisterPath(router, pub, sub, userAPIamqp.NewHandler(userService, cfg))
+	return router
+}
+
+// initializeSemanticCache creates the in-process vector store, answers are also persisted in redis when configured
+func initializeSemanticCache(cfg *config.MainConfig) *vectorstore.MemoryStore {
+	var persist cache.CacheInterface
+	if cfg.SemanticCache.Persistence == "redis" {
+		persist = &redis.Redis{}
+	}
+	ttl := time.Duration(cfg.SemanticCache.TTL) * time.Second
+	return vectorstore.NewMemoryStore(cfg.SemanticCache.MaxEntries, ttl, persist)
 }
 
 func initializeLogger(cfg mainCfg.Config) logger.Logger {
@@ -174,7 +293,14 @@ func newUserService(
 	cfg *config.MainConfig,
 	gpt4Webservice gpt4WebService.GPT4WebService,
 	userRepo *userRepository.MongoDBRepository,
+	publisher userContract.Publisher,
+	webhookSender webhook.WebhookSender,
+	embeddingWebservice embeddingWebService.EmbeddingWebService,
+	semanticCache userContract.VectorStore,
+	moderationWebservice moderationWebService.ModerationWebService,
+	blobStore userContract.BlobStore,
+	imageFetcher userContract.ImageFetcher,
 ) userBusiness.UserService {
-	userService := userBusiness.NewUserService(userRepo, cache, cfg, gpt4Webservice)
+	userService := userBusiness.NewUserService(userRepo, cache, cfg, gpt4Webservice, publisher, webhookSender, embeddingWebservice, semanticCache, userRepo, moderationWebservice, blobStore, imageFetcher)
 	return userService
 }
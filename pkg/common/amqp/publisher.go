@@ -1,11 +1,13 @@
 package amqp
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/correlation"
 )
 
 type Publisher struct {
@@ -17,10 +19,20 @@ func NewPublisherSubscriber(pub message.Publisher, sub message.Subscriber) Publi
 	return Publisher{pub: pub, sub: sub}
 }
 
-func (p Publisher) Publish(topic string, payload interface{}) error {
-	marshaledPayload, _ := json.Marshal(payload)
+// Publish marshals the payload to JSON, the correlation ID is taken from ctx when present
+func (p Publisher) Publish(ctx context.Context, topic string, payload interface{}) error {
+	marshaledPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
 	msg := message.NewMessage(watermill.NewUUID(), marshaledPayload)
-	middleware.SetCorrelationID(watermill.NewUUID(), msg)
+
+	correlationID := correlation.FromContext(ctx)
+	if correlationID == "" {
+		correlationID = watermill.NewUUID()
+	}
+	middleware.SetCorrelationID(correlationID, msg)
+
 	if err := p.pub.Publish(topic, msg); err != nil {
 		return err
 	}
@@ -0,0 +1,44 @@
+package correlation
+
+import (
+	"context"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderCorrelationID is read from incoming requests and echoed on responses
+const HeaderCorrelationID = "X-Correlation-ID"
+
+type contextKey struct{}
+
+// WithID returns a copy of ctx carrying the correlation ID
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the correlation ID carried by ctx, or an empty string
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Middleware takes the correlation ID from the X-Correlation-ID or X-Request-ID header, or generates one,
+// and stores it in the request context
+func Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		id := req.Header.Get(HeaderCorrelationID)
+		if id == "" {
+			id = req.Header.Get(echo.HeaderXRequestID)
+		}
+		if id == "" {
+			id = watermill.NewUUID()
+		}
+
+		c.SetRequest(req.WithContext(WithID(req.Context(), id)))
+		c.Response().Header().Set(HeaderCorrelationID, id)
+
+		return next(c)
+	}
+}
@@ -0,0 +1,119 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MimeTypeText     = "text/plain"
+	MimeTypeMarkdown = "text/markdown"
+	MimeTypeHTML     = "text/html"
+	MimeTypePDF      = "application/pdf"
+)
+
+// ErrUnsupportedType is returned for documents other than text, markdown, HTML and PDF
+var ErrUnsupportedType = errors.New("unsupported document type")
+
+// DetectMimeType returns the document type from the declared content type, falling back to the file extension
+func DetectMimeType(filename string, contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case MimeTypeText, MimeTypeMarkdown, MimeTypeHTML, MimeTypePDF:
+			return mediaType
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt", ".text":
+		return MimeTypeText
+	case ".md", ".markdown":
+		return MimeTypeMarkdown
+	case ".html", ".htm":
+		return MimeTypeHTML
+	case ".pdf":
+		return MimeTypePDF
+	}
+	return contentType
+}
+
+// ExtractText returns the plain text of a document. maxBytes is the largest document accepted,
+// compressed PDF content may inflate to a fixed multiple of it; 0 takes the size of content.
+func ExtractText(mimeType string, content []byte, maxBytes int64) (string, error) {
+	switch mimeType {
+	case MimeTypeText, MimeTypeMarkdown:
+		if !utf8.Valid(content) {
+			return "", fmt.Errorf("document is not valid UTF-8")
+		}
+		return string(content), nil
+	case MimeTypeHTML:
+		return extractHTML(content)
+	case MimeTypePDF:
+		if maxBytes <= 0 {
+			maxBytes = int64(len(content))
+		}
+		return extractPDF(content, maxBytes*pdfMaxInflation)
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedType, mimeType)
+	}
+}
+
+// Chunk splits text into pieces of at most size runes, each starting overlap runes before the previous one ended.
+// Pieces end at a paragraph, line or word boundary where possible.
+func Chunk(text string, size int, overlap int) []string {
+	runes := []rune(strings.TrimSpace(text))
+	if size <= 0 {
+		size = len(runes)
+	}
+	if overlap >= size {
+		overlap = size / 2
+	}
+
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			end = breakPoint(runes, start+size/2, end)
+		}
+
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+
+		// start the next piece on a word boundary within the overlap
+		next := end - overlap
+		for next > start && next < end && !isSpace(runes[next-1]) {
+			next++
+		}
+		if next <= start || next >= end {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+// breakPoint returns the best position in (min, max] to end a chunk: after a blank line, a line break, or a space
+func breakPoint(runes []rune, min int, max int) int {
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		for i := max; i > min; i-- {
+			if strings.HasSuffix(string(runes[i-len(sep):i]), sep) {
+				return i
+			}
+		}
+	}
+	return max
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\n' || r == '\t' || r == '\r'
+}
@@ -0,0 +1,77 @@
+package document_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/document"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type Suite struct {
+	suite.Suite
+}
+
+func (s *Suite) TestExtractText() {
+	s.Run("HTML drops scripts and keeps paragraphs", func() {
+		text, err := document.ExtractText(document.MimeTypeHTML, []byte(`<html><head><title>x</title></head><body><script>var a;</script><h1>VPN</h1><p>Reset it   from the portal.</p></body></html>`), 0)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "VPN\n\nReset it from the portal.", text)
+	})
+
+	s.Run("PDF with a compressed content stream", func() {
+		var stream bytes.Buffer
+		w := zlib.NewWriter(&stream)
+		w.Write([]byte("BT /F1 12 Tf 72 712 Td (Reset your VPN) Tj 0 -14 Td [(from the) -250 (portal)] TJ ET"))
+		w.Close()
+		pdf := fmt.Sprintf("%%PDF-1.4\n4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream\nendobj\n%%%%EOF", stream.Len(), stream.String())
+
+		text, err := document.ExtractText(document.MimeTypePDF, []byte(pdf), 0)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "Reset your VPN\nfrom the portal", text)
+	})
+
+	s.Run("PDF inflating beyond the limit", func() {
+		var stream bytes.Buffer
+		w := zlib.NewWriter(&stream)
+		w.Write([]byte("BT (" + strings.Repeat("a", 1<<20) + ") Tj ET"))
+		w.Close()
+		pdf := fmt.Sprintf("%%PDF-1.4\n4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream\nendobj\n%%%%EOF", stream.Len(), stream.String())
+
+		_, err := document.ExtractText(document.MimeTypePDF, []byte(pdf), int64(len(pdf)))
+		assert.ErrorIs(s.T(), err, document.ErrPDFTooLarge)
+	})
+
+	s.Run("PDF without text", func() {
+		_, err := document.ExtractText(document.MimeTypePDF, []byte("%PDF-1.4\n%%EOF"), 0)
+		assert.ErrorIs(s.T(), err, document.ErrNoPDFText)
+	})
+
+	s.Run("Unsupported type", func() {
+		_, err := document.ExtractText("image/png", []byte{}, 0)
+		assert.ErrorIs(s.T(), err, document.ErrUnsupportedType)
+	})
+}
+
+func (s *Suite) TestChunk() {
+	text := strings.Repeat("word ", 100)
+
+	chunks := document.Chunk(text, 100, 20)
+	require.NotEmpty(s.T(), chunks)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(s.T(), len(chunk), 100)
+		assert.False(s.T(), strings.HasPrefix(chunk, "ord"), "chunks start on a word boundary")
+	}
+	// consecutive chunks overlap
+	assert.Greater(s.T(), len(strings.Join(chunks, " ")), len(strings.TrimSpace(text)))
+}
+
+func TestDocument(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
@@ -0,0 +1,78 @@
+package document
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockElements end a line of text
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true, "section": true, "article": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "pre": true, "blockquote": true,
+}
+
+// extractHTML returns the visible text of an HTML document
+func extractHTML(content []byte) (string, error) {
+	var out strings.Builder
+	skip := 0
+	tokenizer := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if tokenizer.Err() == io.EOF {
+				return collapseBlankLines(out.String()), nil
+			}
+			return "", tokenizer.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style", "noscript", "head":
+				skip++
+			}
+			if blockElements[string(name)] {
+				out.WriteString("\n")
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style", "noscript", "head":
+				if skip > 0 {
+					skip--
+				}
+			}
+			if blockElements[string(name)] {
+				out.WriteString("\n")
+			}
+		case html.TextToken:
+			if skip == 0 {
+				text := strings.Join(strings.Fields(string(tokenizer.Text())), " ")
+				if text != "" {
+					out.WriteString(text)
+					out.WriteString(" ")
+				}
+			}
+		}
+	}
+}
+
+// collapseBlankLines trims every line and keeps at most one blank line between paragraphs
+func collapseBlankLines(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if !blank && len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
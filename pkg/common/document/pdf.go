@@ -0,0 +1,237 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrNoPDFText is returned for PDFs without extractable text, e.g. scanned documents
+var ErrNoPDFText = errors.New("pdf has no extractable text")
+
+// ErrPDFTooLarge is returned for PDFs whose compressed streams inflate beyond the limit, e.g. zip bombs
+var ErrPDFTooLarge = errors.New("pdf content is too large once decompressed")
+
+// pdfMaxInflation bounds the decompressed streams of a PDF, as a multiple of the largest accepted document
+const pdfMaxInflation = 20
+
+var pdfStream = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+
+// extractPDF returns the text shown by the content streams of a PDF.
+// Only literal and hex strings of text operators are read, so PDFs relying on custom font encodings
+// or images of text yield nothing and are rejected. Streams may inflate to at most limit bytes in total.
+func extractPDF(content []byte, limit int64) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("%PDF")) {
+		return "", errors.New("not a pdf document")
+	}
+
+	var out strings.Builder
+	for _, loc := range pdfStream.FindAllSubmatchIndex(content, -1) {
+		dict := content[loc[2]:loc[3]]
+		start := loc[1]
+		end := bytes.Index(content[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		data := content[start : start+end]
+
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			reader, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				continue
+			}
+			// streams are often followed by garbage, keep what inflated
+			data, _ = io.ReadAll(io.LimitReader(reader, limit+1))
+			reader.Close()
+			if int64(len(data)) > limit {
+				return "", ErrPDFTooLarge
+			}
+			limit -= int64(len(data))
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// other filters are used for images and fonts
+			continue
+		}
+		if !bytes.Contains(data, []byte("BT")) {
+			continue
+		}
+		out.WriteString(pdfContentText(data))
+		out.WriteString("\n")
+	}
+
+	text := collapseBlankLines(out.String())
+	if text == "" {
+		return "", ErrNoPDFText
+	}
+	return text, nil
+}
+
+// pdfContentText interprets the text operators of a content stream
+func pdfContentText(data []byte) string {
+	var out strings.Builder
+	var operands []string
+	inText := false
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := pdfLiteralString(data[i:])
+			if inText {
+				operands = append(operands, "("+s)
+			}
+			i += n
+		case c == '<' && i+1 < len(data) && data[i+1] != '<':
+			end := bytes.IndexByte(data[i:], '>')
+			if end < 0 {
+				return out.String()
+			}
+			if inText {
+				operands = append(operands, "("+pdfHexString(data[i+1:i+end]))
+			}
+			i += end + 1
+		case c == '[' || c == ']' || c == '{' || c == '}':
+			i++
+		case isPDFSpace(c):
+			i++
+		default:
+			start := i
+			for i < len(data) && !isPDFSpace(data[i]) && !strings.ContainsRune("()<>[]{}/%", rune(data[i])) {
+				i++
+			}
+			if c == '/' {
+				i++
+				for i < len(data) && !isPDFSpace(data[i]) && !strings.ContainsRune("()<>[]{}/%", rune(data[i])) {
+					i++
+				}
+			}
+			if i == start {
+				i++
+				continue
+			}
+			token := string(data[start:i])
+
+			switch token {
+			case "BT":
+				inText = true
+				operands = nil
+			case "ET":
+				inText = false
+				out.WriteString("\n")
+				operands = nil
+			case "Tj", "TJ", "'", "\"":
+				if token == "'" || token == "\"" {
+					out.WriteString("\n")
+				}
+				for _, operand := range operands {
+					if strings.HasPrefix(operand, "(") {
+						out.WriteString(operand[1:])
+					} else if n, err := strconv.ParseFloat(operand, 64); err == nil && n < -200 {
+						// a large negative kerning inside TJ is a word gap
+						out.WriteString(" ")
+					}
+				}
+				operands = nil
+			case "Td", "TD":
+				// a vertical move starts a new line, a horizontal one a new word
+				if len(operands) >= 2 && operands[len(operands)-1] != "0" {
+					out.WriteString("\n")
+				} else {
+					out.WriteString(" ")
+				}
+				operands = nil
+			case "T*", "Tm":
+				out.WriteString("\n")
+				operands = nil
+			default:
+				if inText {
+					operands = append(operands, token)
+				}
+			}
+		}
+	}
+	return out.String()
+}
+
+// pdfLiteralString reads a (...) string starting at data[0] and returns it with the number of bytes consumed
+func pdfLiteralString(data []byte) (string, int) {
+	var out strings.Builder
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '\\' && i+1 < len(data):
+			i++
+			switch e := data[i]; e {
+			case 'n':
+				out.WriteByte('\n')
+			case 'r':
+				out.WriteByte('\r')
+			case 't':
+				out.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7' {
+						j++
+					}
+					v, _ := strconv.ParseUint(string(data[i:j]), 8, 8)
+					out.WriteByte(byte(v))
+					i = j - 1
+				} else {
+					out.WriteByte(e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				out.WriteByte(c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return out.String(), i + 1
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), len(data)
+}
+
+// pdfHexString decodes a <...> string, dropping control bytes such as the high byte of two-byte codes
+func pdfHexString(data []byte) string {
+	hex := strings.Map(func(r rune) rune {
+		if isPDFSpace(byte(r)) {
+			return -1
+		}
+		return r
+	}, string(data))
+	if len(hex)%2 == 1 {
+		hex += "0"
+	}
+
+	var out strings.Builder
+	for i := 0; i+1 < len(hex); i += 2 {
+		v, err := strconv.ParseUint(hex[i:i+2], 16, 8)
+		if err == nil && v >= 0x20 {
+			out.WriteByte(byte(v))
+		}
+	}
+	return out.String()
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
@@ -0,0 +1,105 @@
+package guardrail
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rules reported in a Violation
+const (
+	RuleBlocklist = "blocklist"
+	RuleTopic     = "topic"
+	RuleLength    = "length"
+)
+
+// ErrInvalidRule is returned by New for a blocklist pattern that doesn't compile
+var ErrInvalidRule = errors.New("invalid guardrail rule")
+
+// Violation describes the first rule a text broke
+type Violation struct {
+	Rule   string
+	Detail string // the blocklist pattern, the topic or the length limit
+}
+
+type topic struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+type blocked struct {
+	source  string
+	pattern *regexp.Regexp
+}
+
+// Guard screens text against a blocklist, a topic deny-list and a length limit
+type Guard struct {
+	blocklist []blocked
+	topics    []topic
+	maxChars  int
+}
+
+// New compiles the rules of a guard.
+// Blocklist entries are case-insensitive regular expressions, topics map a name to keywords matched as whole words,
+// maxChars of 0 means no length limit.
+func New(blocklist []string, topics map[string][]string, maxChars int) (*Guard, error) {
+	g := &Guard{maxChars: maxChars}
+	for _, source := range blocklist {
+		pattern, err := regexp.Compile("(?i)" + source)
+		if err != nil {
+			return nil, fmt.Errorf("%w: blocklist %q: %v", ErrInvalidRule, source, err)
+		}
+		g.blocklist = append(g.blocklist, blocked{source: source, pattern: pattern})
+	}
+
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		keywords := []string{}
+		for _, keyword := range topics[name] {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				keywords = append(keywords, regexp.QuoteMeta(keyword))
+			}
+		}
+		if len(keywords) == 0 {
+			continue
+		}
+		pattern := regexp.MustCompile(`(?i)\b(?:` + strings.Join(keywords, "|") + `)\b`)
+		g.topics = append(g.topics, topic{name: name, pattern: pattern})
+	}
+	return g, nil
+}
+
+// Empty reports whether the guard has no rules
+func (g *Guard) Empty() bool {
+	return len(g.blocklist) == 0 && len(g.topics) == 0 && g.maxChars <= 0
+}
+
+// CheckLength returns a violation when text is longer than the length limit
+func (g *Guard) CheckLength(text string) *Violation {
+	if g.maxChars > 0 && utf8.RuneCountInString(text) > g.maxChars {
+		return &Violation{Rule: RuleLength, Detail: fmt.Sprintf("%d characters", g.maxChars)}
+	}
+	return nil
+}
+
+// Check returns the first blocklist pattern or denied topic text matches, or nil
+func (g *Guard) Check(text string) *Violation {
+	for _, b := range g.blocklist {
+		if b.pattern.MatchString(text) {
+			return &Violation{Rule: RuleBlocklist, Detail: b.source}
+		}
+	}
+	for _, t := range g.topics {
+		if t.pattern.MatchString(text) {
+			return &Violation{Rule: RuleTopic, Detail: t.name}
+		}
+	}
+	return nil
+}
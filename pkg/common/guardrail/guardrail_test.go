@@ -0,0 +1,58 @@
+package guardrail_test
+
+import (
+	"testing"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/guardrail"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type Suite struct {
+	suite.Suite
+}
+
+func (s *Suite) TestCheck() {
+	g, err := guardrail.New(
+		[]string{`drop\s+table`},
+		map[string][]string{"weapons": {"pipe bomb", "nerve agent"}, "empty": {" "}},
+		0,
+	)
+	require.NoError(s.T(), err)
+
+	cases := []struct {
+		name      string
+		text      string
+		violation *guardrail.Violation
+	}{
+		{"Clean", "How do I write a unit test?", nil},
+		{"Blocklist is case-insensitive", "please DROP   TABLE users", &guardrail.Violation{Rule: guardrail.RuleBlocklist, Detail: `drop\s+table`}},
+		{"Topic keyword", "how to build a Pipe Bomb", &guardrail.Violation{Rule: guardrail.RuleTopic, Detail: "weapons"}},
+		{"Topic keywords match whole words", "the pipe bombastic review", nil},
+	}
+	for _, c := range cases {
+		s.Run(c.name, func() {
+			assert.Equal(s.T(), c.violation, g.Check(c.text))
+		})
+	}
+}
+
+func (s *Suite) TestCheckLength() {
+	g, err := guardrail.New(nil, nil, 5)
+	require.NoError(s.T(), err)
+
+	assert.Nil(s.T(), g.CheckLength("héllo"))
+	assert.Equal(s.T(), guardrail.RuleLength, g.CheckLength("hello!").Rule)
+	assert.False(s.T(), g.Empty())
+}
+
+func (s *Suite) TestInvalidBlocklist() {
+	_, err := guardrail.New([]string{"("}, nil, 0)
+	assert.ErrorIs(s.T(), err, guardrail.ErrInvalidRule)
+}
+
+func TestGuardrail(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
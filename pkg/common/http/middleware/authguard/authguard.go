@@ -1,8 +1,12 @@
 package authguard
 
 import (
+	"context"
 	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
@@ -21,6 +25,7 @@ import (
 // Constants for handling JWT and keys
 var (
 	UserAttr          = "userAttr"
+	RoleAttr          = "roleAttr"
 	PrefixHeader      = "Bearer "
 	PrefixHeaderBasic = "Basic "
 	googleIssuer      = "https://accounts.google.com"
@@ -29,6 +34,20 @@ var (
 	microsoftCertsURL = "https://login.microsoftonline.com/common/discovery/keys"
 )
 
+// multiTenants are the tenant IDs of Microsoft apps that accept users from other tenants,
+// their tokens are issued by the tenant of the user
+var multiTenants = []string{"common", "organizations"}
+
+// ErrUserStateUnavailable is returned by SignIn when the stored state of a user could not be read
+var ErrUserStateUnavailable = errors.New("user state is unavailable")
+
+// Roles understood by the proxy
+const (
+	RoleAdmin   = "admin"
+	RoleUser    = "user"
+	RoleService = "service"
+)
+
 type JWK struct {
 	Kty string   `json:"kty"`
 	Use string   `json:"use"`
@@ -42,9 +61,15 @@ type JWKS struct {
 
 // JwtClaims represents the claims extracted from the JWT
 type JwtClaims struct {
-	Picture string `json:"picture"`
-	Email   string `json:"email"`
-	Name    string `json:"name"`
+	Picture string   `json:"picture"`
+	Email   string   `json:"email"`
+	Name    string   `json:"name"`
+	Roles   []string `json:"roles"`  // Microsoft app roles
+	Groups  []string `json:"groups"` // Microsoft security groups
+
+	EmailVerified bool   `json:"email_verified"` // Google only
+	HostedDomain  string `json:"hd"`             // Google Workspace domain
+	TenantID      string `json:"tid"`            // Microsoft tenant
 	goJwt.RegisteredClaims
 }
 
@@ -53,12 +78,43 @@ type BasicAuth struct {
 	Password string `json:"password"`
 }
 
+// StoredUser is the per-user state kept outside the token
+type StoredUser struct {
+	Role    string // empty means the role is derived from claims
+	Blocked bool
+}
+
+// UserResolver looks up user state stored outside the token, e.g. in the users collection.
+// A user that isn't stored resolves to the zero StoredUser, an error means the state couldn't be read.
+type UserResolver interface {
+	ResolveUser(ctx context.Context, email string) (StoredUser, error)
+}
+
+// StoredServiceKey is the password of a service set outside the configuration
+type StoredServiceKey struct {
+	PasswordHash string // hex SHA-256 of the password
+}
+
+// ServiceKeyResolver looks up service passwords stored outside the configuration, e.g. after a rotation.
+// A service without one resolves to the zero StoredServiceKey and keeps its configured password.
+type ServiceKeyResolver interface {
+	ResolveServiceKey(ctx context.Context, service string) (StoredServiceKey, error)
+}
+
+// LoginRecorder is told about sign-ins the guard denies, e.g. to publish them for auditing
+type LoginRecorder interface {
+	RecordLogin(ctx context.Context, provider string, email string, err error)
+}
+
 // AuthGuard holds dependencies like API key and configuration
 type AuthGuard struct {
-	cfg       config.MainConfig
-	certs     map[string]*rsa.PublicKey
-	certsLock sync.RWMutex
-	services  map[string]BasicAuth
+	cfg           config.MainConfig
+	certs         map[string]*rsa.PublicKey
+	certsLock     sync.RWMutex
+	services      map[string]BasicAuth
+	userResolver  UserResolver
+	keyResolver   ServiceKeyResolver
+	loginRecorder LoginRecorder
 }
 
 // NewAuthGuard creates a new instance of AuthGuard
@@ -80,6 +136,21 @@ func (g *AuthGuard) AddService(services []config.BackendService) {
 	}
 }
 
+// SetUserResolver sets the resolver consulted for blocked users and before the configured claim mapping
+func (g *AuthGuard) SetUserResolver(resolver UserResolver) {
+	g.userResolver = resolver
+}
+
+// SetServiceKeyResolver sets the resolver consulted for service passwords before the configured ones
+func (g *AuthGuard) SetServiceKeyResolver(resolver ServiceKeyResolver) {
+	g.keyResolver = resolver
+}
+
+// SetLoginRecorder sets the recorder told about every user the guard denies
+func (g *AuthGuard) SetLoginRecorder(recorder LoginRecorder) {
+	g.loginRecorder = recorder
+}
+
 // Bearer middleware validates JWT tokens, handling multiple OAuth2 providers
 func (g *AuthGuard) Bearer(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -89,18 +160,64 @@ func (g *AuthGuard) Bearer(next echo.HandlerFunc) echo.HandlerFunc {
 			return c.JSON(http.StatusUnauthorized, common.NewUnauthorizedResponse("Authorization header missing/invalid"))
 		}
 
+		// an expired or malformed token is routine and not recorded, only denied users are
 		token := strings.TrimPrefix(authHeader, PrefixHeader)
 		claims, err := g.ParseAndVerify(token)
 		if err != nil {
 			return c.JSON(http.StatusUnauthorized, common.NewUnauthorizedResponse(err.Error()))
 		}
 
+		if err := g.authorize(claims); err != nil {
+			g.recordRejection(c, claims, err)
+			return c.JSON(http.StatusForbidden, common.NewForbiddenResponse(err.Error()))
+		}
+
+		var stored StoredUser
+		if g.userResolver != nil {
+			// without the stored state a blocked user would get in and role overrides would be lost
+			if stored, err = g.userResolver.ResolveUser(c.Request().Context(), claims.Email); err != nil {
+				return c.JSON(http.StatusServiceUnavailable, common.NewServiceUnavailableResponse(ErrUserStateUnavailable.Error()))
+			}
+		}
+		if stored.Blocked {
+			err = blockedError(claims.Email)
+			g.recordRejection(c, claims, err)
+			return c.JSON(http.StatusForbidden, common.NewForbiddenResponse(err.Error()))
+		}
+
 		c.Set(UserAttr, claims)
+		c.Set(RoleAttr, g.resolveRole(claims, stored))
 
 		return next(c)
 	}
 }
 
+// SignIn checks a freshly issued ID token the way Bearer would, so a sign-in is only reported as
+// successful for users who are let in. The claims are returned as far as they could be verified.
+func (g *AuthGuard) SignIn(ctx context.Context, idToken string) (JwtClaims, error) {
+	claims, err := g.ParseAndVerify(idToken)
+	if err != nil {
+		return JwtClaims{}, err
+	}
+	if err := g.authorize(claims); err != nil {
+		return claims, err
+	}
+	if g.userResolver != nil {
+		stored, err := g.userResolver.ResolveUser(ctx, claims.Email)
+		if err != nil {
+			return claims, ErrUserStateUnavailable
+		}
+		if stored.Blocked {
+			return claims, blockedError(claims.Email)
+		}
+	}
+	return claims, nil
+}
+
+func blockedError(email string) error {
+	return fmt.Errorf("user %s is blocked", email)
+}
+
 // Basic middleware validates Basic Auth tokens
 func (g *AuthGuard) Basic(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -121,18 +238,199 @@ func (g *AuthGuard) Basic(next echo.HandlerFunc) echo.HandlerFunc {
 			return c.JSON(http.StatusUnauthorized, common.NewUnauthorizedResponse("Invalid token"))
 		}
 
+		var stored StoredServiceKey
+		if g.keyResolver != nil {
+			// a rotated password must not be bypassed with the configured one
+			var err error
+			if stored, err = g.keyResolver.ResolveServiceKey(c.Request().Context(), serviceHeader); err != nil {
+				return c.JSON(http.StatusServiceUnavailable, common.NewServiceUnavailableResponse("service key is unavailable"))
+			}
+		}
+
 		// compare with the stored credentials
-		if username != g.services[serviceHeader].Username || password != g.services[serviceHeader].Password {
+		if !g.serviceCredentialsMatch(serviceHeader, stored, username, password) {
 			return c.JSON(http.StatusUnauthorized, common.NewUnauthorizedResponse("Invalid Basic Auth token"))
 		}
 
 		// set the service name to the context
 		c.Set("service", serviceHeader)
+		c.Set(RoleAttr, RoleService)
 
 		return next(c)
 	}
 }
 
+// serviceCredentialsMatch checks Basic Auth credentials against the stored password of a service,
+// or its configured one when none is stored
+func (g *AuthGuard) serviceCredentialsMatch(service string, stored StoredServiceKey, username string, password string) bool {
+	configured := g.services[service]
+	if username != configured.Username {
+		return false
+	}
+	if stored.PasswordHash == "" {
+		return password == configured.Password
+	}
+	sum := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(stored.PasswordHash)) == 1
+}
+
+// BearerOrBasic middleware accepts SSO users and backend services on the same route,
+// it dispatches to Basic or Bearer depending on the Authorization scheme
+func (g *AuthGuard) BearerOrBasic(next echo.HandlerFunc) echo.HandlerFunc {
+	bearer, basic := g.Bearer(next), g.Basic(next)
+	return func(c echo.Context) error {
+		if strings.HasPrefix(c.Request().Header.Get("Authorization"), PrefixHeaderBasic) {
+			return basic(c)
+		}
+		return bearer(c)
+	}
+}
+
+// OptionalBearer middleware lets anonymous requests through, a request with a Bearer token must pass Bearer.
+// Handlers find the user in UserAttr only when one signed in.
+func (g *AuthGuard) OptionalBearer(next echo.HandlerFunc) echo.HandlerFunc {
+	bearer := g.Bearer(next)
+	return func(c echo.Context) error {
+		if strings.HasPrefix(c.Request().Header.Get("Authorization"), PrefixHeader) {
+			return bearer(c)
+		}
+		return next(c)
+	}
+}
+
+// RequireRoles middleware rejects requests whose role is not one of the given roles.
+// It must be chained after Bearer or Basic.
+func (g *AuthGuard) RequireRoles(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get(RoleAttr).(string)
+			for _, allowed := range roles {
+				if role == allowed {
+					return next(c)
+				}
+			}
+			return c.JSON(http.StatusForbidden, common.NewForbiddenResponse(fmt.Sprintf("role %q is not allowed to access this resource", role)))
+		}
+	}
+}
+
+// authorize applies the configured domain, tenant and user allow/deny lists to verified claims
+func (g *AuthGuard) authorize(claims JwtClaims) error {
+	if claims.Email == "" {
+		return errors.New("token has no email claim")
+	}
+	if containsFold(g.cfg.Auth.DeniedUsers, claims.Email) {
+		return fmt.Errorf("user %s is denied", claims.Email)
+	}
+
+	if claims.Issuer == googleIssuer {
+		if !claims.EmailVerified {
+			return fmt.Errorf("email %s is not verified", claims.Email)
+		}
+		if len(g.cfg.Auth.AllowedHostedDomains) > 0 && !containsFold(g.cfg.Auth.AllowedHostedDomains, claims.HostedDomain) {
+			return fmt.Errorf("google workspace domain %q is not allowed", claims.HostedDomain)
+		}
+	}
+	if g.microsoftIssuer(claims.Issuer) {
+		// a multi-tenant app sees tokens of every tenant, the issuer must be the tenant the token claims
+		if !strings.EqualFold(claims.Issuer, fmt.Sprintf(microsoftIssuer, claims.TenantID)) {
+			return fmt.Errorf("microsoft tenant %q did not issue the token", claims.TenantID)
+		}
+		if len(g.cfg.Auth.AllowedTenants) > 0 && !containsFold(g.cfg.Auth.AllowedTenants, claims.TenantID) {
+			return fmt.Errorf("microsoft tenant %q is not allowed", claims.TenantID)
+		}
+	}
+
+	if len(g.cfg.Auth.AllowedDomains) > 0 {
+		domain := claims.Email[strings.LastIndex(claims.Email, "@")+1:]
+		if !containsFold(g.cfg.Auth.AllowedDomains, domain) {
+			return fmt.Errorf("email domain %q is not allowed", domain)
+		}
+	}
+
+	return nil
+}
+
+// recordRejection tells the login recorder, if any, why a verified user was denied
+func (g *AuthGuard) recordRejection(c echo.Context, claims JwtClaims, err error) {
+	if g.loginRecorder == nil {
+		return
+	}
+	g.loginRecorder.RecordLogin(c.Request().Context(), g.provider(claims.Issuer), claims.Email, err)
+}
+
+// provider names the identity provider of an issuer, empty when it is unknown
+func (g *AuthGuard) provider(issuer string) string {
+	switch {
+	case issuer == googleIssuer:
+		return "google"
+	case g.microsoftIssuer(issuer):
+		return "microsoft"
+	}
+	return ""
+}
+
+// microsoftIssuer reports whether issuer is the configured Microsoft tenant or,
+// for a multi-tenant app, any Microsoft tenant
+func (g *AuthGuard) microsoftIssuer(issuer string) bool {
+	if issuer == fmt.Sprintf(microsoftIssuer, g.cfg.MicrosoftOauth.TenantID) {
+		return true
+	}
+	if !containsFold(multiTenants, g.cfg.MicrosoftOauth.TenantID) {
+		return false
+	}
+	prefix, suffix, _ := strings.Cut(microsoftIssuer, "%s")
+	tenant, ok := strings.CutPrefix(issuer, prefix)
+	if !ok {
+		return false
+	}
+	tenant, ok = strings.CutSuffix(tenant, suffix)
+	return ok && tenant != "" && !strings.Contains(tenant, "/")
+}
+
+// containsFold reports whether list contains value, ignoring case
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRole derives the proxy role of a user: stored role first, then configured admins,
+// then IdP role/group claims, then the default role
+func (g *AuthGuard) resolveRole(claims JwtClaims, stored StoredUser) string {
+	if stored.Role != "" {
+		return stored.Role
+	}
+
+	if containsFold(g.cfg.Auth.AdminEmails, claims.Email) {
+		return RoleAdmin
+	}
+
+	role := ""
+	for _, claim := range append(claims.Roles, claims.Groups...) {
+		// viper lowercases map keys
+		mapped, ok := g.cfg.Auth.ClaimRoles[strings.ToLower(claim)]
+		if !ok {
+			continue
+		}
+		if mapped == RoleAdmin {
+			return RoleAdmin
+		}
+		role = mapped
+	}
+	if role != "" {
+		return role
+	}
+
+	if g.cfg.Auth.DefaultRole != "" {
+		return g.cfg.Auth.DefaultRole
+	}
+	return RoleUser
+}
+
 // ParseAndVerify handles JWT parsing and verification for multiple providers
 func (g *AuthGuard) ParseAndVerify(accessToken string) (JwtClaims, error) {
 	// Check if the token is a JWT
@@ -158,10 +456,10 @@ func (g *AuthGuard) verifyJWT(accessToken string) (JwtClaims, error) {
 		}
 
 		// Determine the provider by the `iss` claim
-		switch claims.Issuer {
-		case googleIssuer:
+		switch {
+		case claims.Issuer == googleIssuer:
 			return g.getGooglePublicKey(token.Header["kid"].(string))
-		case fmt.Sprintf(microsoftIssuer, g.cfg.MicrosoftOauth.TenantID):
+		case g.microsoftIssuer(claims.Issuer):
 			return g.getMicrosoftPublicKey(token.Header["kid"].(string))
 		default:
 			return nil, fmt.Errorf("issuer not recognized: %s", claims.Issuer)
@@ -187,10 +485,10 @@ func (g *AuthGuard) verifyJWT(accessToken string) (JwtClaims, error) {
 
 // verifyAudience checks if the audience claim matches the expected audience
 func (g *AuthGuard) verifyAudience(issuer string, aud []string) bool {
-	switch issuer {
-	case googleIssuer:
+	switch {
+	case issuer == googleIssuer:
 		return aud[0] == g.cfg.GoogleOauth.ClientID
-	case fmt.Sprintf(microsoftIssuer, g.cfg.MicrosoftOauth.TenantID):
+	case g.microsoftIssuer(issuer):
 		return aud[0] == g.cfg.MicrosoftOauth.ClientID
 	}
 
@@ -35,6 +35,42 @@ func NewForbiddenResponse(msg string) DefaultResponse {
 	}
 }
 
+// NewNotFoundResponse default not found response
+func NewNotFoundResponse(msg string) DefaultResponse {
+	return DefaultResponse{
+		404,
+		NotFoundStatus,
+		msg,
+	}
+}
+
+// NewTooManyRequestResponse default too many request response
+func NewTooManyRequestResponse(msg string) DefaultResponse {
+	return DefaultResponse{
+		429,
+		TooManyRequestStatus,
+		msg,
+	}
+}
+
+// NewContentFilteredResponse default content filtered response
+func NewContentFilteredResponse(msg string) DefaultResponse {
+	return DefaultResponse{
+		422,
+		ContentFilteredStatus,
+		msg,
+	}
+}
+
+// NewServiceUnavailableResponse default service unavailable response
+func NewServiceUnavailableResponse(msg string) DefaultResponse {
+	return DefaultResponse{
+		503,
+		UnavailableStatus,
+		msg,
+	}
+}
+
 // NewDefaultSuccessResponse default validation error response
 func NewDefaultSuccessResponse() DefaultResponse {
 	return DefaultResponse{
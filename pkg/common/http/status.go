@@ -1,15 +1,17 @@
 package common
 
 const (
-	SuccessStatus        = "SUCCESS"
-	UnauthorizedStatus   = "UNAUTHORIZED"
-	ValidationErrStatus  = "VALIDATION_ERROR"
-	BadRequestStatus     = "BAD_REQUEST"
-	ForbiddenStatus      = "FORBIDDEN"
-	InternalErrStatus    = "SERVER_ERROR"
-	NotFoundStatus       = "NOT_FOUND"
-	NotAcceptableStatus  = "NOT_ACCEPTABLE"
-	TooEarlyStatus       = "TOO_EARLY"
-	TooManyRequestStatus = "TOO_MANY_REQUEST"
-	DuplicateStatus      = "DUPLICATE"
+	SuccessStatus         = "SUCCESS"
+	UnauthorizedStatus    = "UNAUTHORIZED"
+	ValidationErrStatus   = "VALIDATION_ERROR"
+	BadRequestStatus      = "BAD_REQUEST"
+	ForbiddenStatus       = "FORBIDDEN"
+	InternalErrStatus     = "SERVER_ERROR"
+	NotFoundStatus        = "NOT_FOUND"
+	NotAcceptableStatus   = "NOT_ACCEPTABLE"
+	TooEarlyStatus        = "TOO_EARLY"
+	TooManyRequestStatus  = "TOO_MANY_REQUEST"
+	DuplicateStatus       = "DUPLICATE"
+	ContentFilteredStatus = "CONTENT_FILTERED"
+	UnavailableStatus     = "SERVICE_UNAVAILABLE"
 )
@@ -0,0 +1,113 @@
+package injection
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Signals reported by Score
+const (
+	SignalOverride      = "instruction_override"
+	SignalRolePlay      = "role_play"
+	SignalPromptExfil   = "prompt_exfiltration"
+	SignalChatMarkup    = "chat_markup"
+	SignalJailbreak     = "jailbreak"
+	SignalHiddenCommand = "hidden_command"
+)
+
+type pattern struct {
+	signal string
+	weight float64
+	regexp *regexp.Regexp
+}
+
+// patterns of common injections, weights are the probability a match alone is an injection
+var patterns = []pattern{
+	{SignalOverride, 0.7, regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,20}\b(all|any|the|your)?\s*(previous|prior|above|earlier|preceding|system)\s+(instructions?|prompts?|rules|directions|context)`)},
+	{SignalOverride, 0.5, regexp.MustCompile(`(?i)\b(new|updated|real)\s+instructions?\s*:`)},
+	{SignalRolePlay, 0.4, regexp.MustCompile(`(?i)\byou\s+are\s+now\b|\bfrom\s+now\s+on,?\s+you\b|\bpretend\s+(to\s+be|you\s+are)\b`)},
+	{SignalPromptExfil, 0.6, regexp.MustCompile(`(?i)\b(reveal|print|show|repeat|output|leak)\b.{0,20}\b(system\s+prompt|initial\s+instructions|hidden\s+instructions|your\s+instructions)`)},
+	{SignalChatMarkup, 0.6, regexp.MustCompile(`(?i)<\|im_(start|end)\|>|\[/?INST\]|<</?SYS>>|^\s*#{2,}\s*(system|assistant)\s*:`)},
+	{SignalJailbreak, 0.6, regexp.MustCompile(`(?i)\b(jailbreak|developer\s+mode|DAN\s+mode|do\s+anything\s+now)\b`)},
+	{SignalHiddenCommand, 0.4, regexp.MustCompile(`(?i)<!--.{0,200}\b(assistant|ai|model|llm)\b.{0,200}-->`)},
+}
+
+// Score returns how likely text carries injected instructions, between 0 and 1, and the signals found.
+// Scores of independent signals combine like probabilities, repeating a signal doesn't raise the score.
+func Score(text string) (float64, []string) {
+	miss := 1.0
+	weights := map[string]float64{}
+	for _, p := range patterns {
+		if p.regexp.MatchString(text) && p.weight > weights[p.signal] {
+			weights[p.signal] = p.weight
+		}
+	}
+
+	signals := []string{}
+	for signal, weight := range weights {
+		miss *= 1 - weight
+		signals = append(signals, signal)
+	}
+	sort.Strings(signals)
+	return 1 - miss, signals
+}
+
+// Strip replaces every injection pattern in text with replacement
+func Strip(text string, replacement string) string {
+	for _, p := range patterns {
+		text = p.regexp.ReplaceAllLiteralString(text, replacement)
+	}
+	return text
+}
+
+var word = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// LeakSpans returns the [start, end) byte offsets of runs of at least minWords consecutive words of source found in text.
+// Words are compared case-insensitively, punctuation and whitespace are ignored.
+func LeakSpans(text string, source string, minWords int) [][2]int {
+	if minWords <= 0 {
+		return nil
+	}
+	sourceWords := word.FindAllString(strings.ToLower(source), -1)
+	if len(sourceWords) < minWords {
+		return nil
+	}
+	grams := map[string]bool{}
+	for i := 0; i+minWords <= len(sourceWords); i++ {
+		grams[strings.Join(sourceWords[i:i+minWords], " ")] = true
+	}
+
+	locs := word.FindAllStringIndex(text, -1)
+	words := make([]string, len(locs))
+	for i, loc := range locs {
+		words[i] = strings.ToLower(text[loc[0]:loc[1]])
+	}
+
+	spans := [][2]int{}
+	for i := 0; i+minWords <= len(words); i++ {
+		if !grams[strings.Join(words[i:i+minWords], " ")] {
+			continue
+		}
+		start, end := locs[i][0], locs[i+minWords-1][1]
+		if n := len(spans); n > 0 && start <= spans[n-1][1] {
+			spans[n-1][1] = end
+			continue
+		}
+		spans = append(spans, [2]int{start, end})
+	}
+	return spans
+}
+
+// Remove replaces the spans of text with replacement, spans must be sorted and not overlap
+func Remove(text string, spans [][2]int, replacement string) string {
+	var out strings.Builder
+	last := 0
+	for _, span := range spans {
+		out.WriteString(text[last:span[0]])
+		out.WriteString(replacement)
+		last = span[1]
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
@@ -0,0 +1,174 @@
+package media
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+)
+
+// Image types recognized by Inspect
+const (
+	TypePNG  = "image/png"
+	TypeJPEG = "image/jpeg"
+	TypeGIF  = "image/gif"
+	TypeWebP = "image/webp"
+)
+
+// ErrInvalidImage is returned for data that isn't a well-formed image of a recognized type
+var ErrInvalidImage = errors.New("invalid image")
+
+// Info describes an image without its pixels
+type Info struct {
+	Type   string
+	Width  int
+	Height int
+}
+
+// Extension returns the file extension of an image type, without the dot
+func Extension(imageType string) string {
+	switch imageType {
+	case TypeJPEG:
+		return "jpg"
+	default:
+		return strings.TrimPrefix(imageType, "image/")
+	}
+}
+
+// TypeOfExtension returns the image type of a file extension, the reverse of Extension
+func TypeOfExtension(ext string) string {
+	switch strings.ToLower(strings.TrimPrefix(ext, ".")) {
+	case "png":
+		return TypePNG
+	case "jpg", "jpeg":
+		return TypeJPEG
+	case "gif":
+		return TypeGIF
+	case "webp":
+		return TypeWebP
+	}
+	return ""
+}
+
+// Inspect sniffs the type of an image from its content and reads its dimensions without decoding the pixels
+func Inspect(data []byte) (Info, error) {
+	info := Info{Type: http.DetectContentType(data)}
+	switch info.Type {
+	case TypePNG, TypeJPEG, TypeGIF:
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return Info{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		info.Width, info.Height = config.Width, config.Height
+	case TypeWebP:
+		width, height, err := webpSize(data)
+		if err != nil {
+			return Info{}, err
+		}
+		info.Width, info.Height = width, height
+	default:
+		return Info{}, fmt.Errorf("%w: unsupported type %s", ErrInvalidImage, info.Type)
+	}
+	if info.Width <= 0 || info.Height <= 0 {
+		return Info{}, fmt.Errorf("%w: empty image", ErrInvalidImage)
+	}
+	return info, nil
+}
+
+// webpSize reads the canvas size from the first chunk of a WebP file
+func webpSize(data []byte) (int, int, error) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, fmt.Errorf("%w: truncated webp", ErrInvalidImage)
+	}
+	chunk := data[20:]
+	switch string(data[12:16]) {
+	case "VP8 ":
+		// lossy: a frame tag, the start code, then 14 bit width and height
+		if !bytes.Equal(chunk[3:6], []byte{0x9d, 0x01, 0x2a}) {
+			return 0, 0, fmt.Errorf("%w: bad vp8 start code", ErrInvalidImage)
+		}
+		width := int(binary.LittleEndian.Uint16(chunk[6:8]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(chunk[8:10]) & 0x3fff)
+		return width, height, nil
+	case "VP8L":
+		// lossless: a signature byte, then width and height minus one in 14 bits each
+		if chunk[0] != 0x2f {
+			return 0, 0, fmt.Errorf("%w: bad vp8l signature", ErrInvalidImage)
+		}
+		bits := binary.LittleEndian.Uint32(chunk[1:5])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, nil
+	case "VP8X":
+		// extended: flags, reserved bytes, then canvas width and height minus one in 24 bits each
+		width := int(uint32(chunk[4])|uint32(chunk[5])<<8|uint32(chunk[6])<<16) + 1
+		height := int(uint32(chunk[7])|uint32(chunk[8])<<8|uint32(chunk[9])<<16) + 1
+		return width, height, nil
+	}
+	return 0, 0, fmt.Errorf("%w: unknown webp chunk", ErrInvalidImage)
+}
+
+// Reencode decodes an image and encodes it again in the same type, which drops metadata such as EXIF and anything
+// smuggled after the pixels. WebP can't be decoded by the standard library and is returned as is.
+func Reencode(data []byte, info Info) ([]byte, error) {
+	var out bytes.Buffer
+	switch info.Type {
+	case TypePNG:
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		err = png.Encode(&out, img)
+		if err != nil {
+			return nil, err
+		}
+	case TypeJPEG:
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: 90})
+		if err != nil {
+			return nil, err
+		}
+	case TypeGIF:
+		img, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		err = gif.EncodeAll(&out, img)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return data, nil
+	}
+	return out.Bytes(), nil
+}
+
+// ParseDataURI decodes a base64 data URI into its media type and content
+func ParseDataURI(uri string) (string, []byte, error) {
+	rest, ok := strings.CutPrefix(uri, "data:")
+	if !ok {
+		return "", nil, fmt.Errorf("%w: not a data uri", ErrInvalidImage)
+	}
+	header, payload, ok := strings.Cut(rest, ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return "", nil, fmt.Errorf("%w: data uri must be base64 encoded", ErrInvalidImage)
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	return strings.TrimSuffix(header, ";base64"), data, nil
+}
+
+// DataURI encodes content as a base64 data URI
+func DataURI(mediaType string, data []byte) string {
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
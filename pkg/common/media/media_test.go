@@ -0,0 +1,65 @@
+package media_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/media"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type Suite struct {
+	suite.Suite
+}
+
+func (s *Suite) pngImage(width, height int) []byte {
+	var buf bytes.Buffer
+	require.NoError(s.T(), png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func (s *Suite) TestInspect() {
+	info, err := media.Inspect(s.pngImage(3, 2))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), media.Info{Type: media.TypePNG, Width: 3, Height: 2}, info)
+
+	// lossless webp header of a 3x2 canvas
+	webp := []byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00\x2f")
+	webp = append(webp, 0x02, 0x40, 0x00, 0x00, 0, 0, 0, 0, 0)
+	info, err = media.Inspect(webp)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), media.Info{Type: media.TypeWebP, Width: 3, Height: 2}, info)
+
+	_, err = media.Inspect([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	assert.ErrorIs(s.T(), err, media.ErrInvalidImage)
+}
+
+func (s *Suite) TestDataURI() {
+	data := s.pngImage(1, 1)
+	mediaType, decoded, err := media.ParseDataURI(media.DataURI(media.TypePNG, data))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), media.TypePNG, mediaType)
+	assert.Equal(s.T(), data, decoded)
+
+	_, _, err = media.ParseDataURI("data:image/png,rawbytes")
+	assert.ErrorIs(s.T(), err, media.ErrInvalidImage)
+}
+
+func (s *Suite) TestReencodeDropsTrailingData() {
+	data := append(s.pngImage(2, 2), []byte("<?php system($_GET['c']); ?>")...)
+	info, err := media.Inspect(data)
+	require.NoError(s.T(), err)
+
+	out, err := media.Reencode(data, info)
+	require.NoError(s.T(), err)
+	assert.NotContains(s.T(), string(out), "php")
+}
+
+func TestMedia(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
@@ -0,0 +1,250 @@
+package prompttemplate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Variable types accepted in declarations
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+	TypeObject  = "object"
+)
+
+var (
+	// ErrInvalidTemplate is returned when a template doesn't parse or uses undeclared variables
+	ErrInvalidTemplate = errors.New("invalid template")
+	// ErrInvalidVariables is returned when the variables given to Render don't match the declarations
+	ErrInvalidVariables = errors.New("invalid variables")
+)
+
+// Variable declares a template variable
+type Variable struct {
+	Name     string
+	Type     string
+	Required bool
+	Default  interface{}
+}
+
+// Message is a message of a template, Template uses text/template syntax with the variables as fields of dot
+type Message struct {
+	Role     string
+	Template string
+}
+
+// Rendered is a message with its template executed
+type Rendered struct {
+	Role string
+	Text string
+}
+
+// Validate parses every message and checks that the variables are well declared and the only ones referenced
+func Validate(messages []Message, variables []Variable) error {
+	if len(messages) == 0 {
+		return fmt.Errorf("%w: no messages", ErrInvalidTemplate)
+	}
+
+	declared := map[string]bool{}
+	for _, v := range variables {
+		if v.Name == "" || declared[v.Name] {
+			return fmt.Errorf("%w: variable %q is empty or declared twice", ErrInvalidTemplate, v.Name)
+		}
+		declared[v.Name] = true
+		if zero(v.Type) == nil {
+			return fmt.Errorf("%w: variable %s has unknown type %q", ErrInvalidTemplate, v.Name, v.Type)
+		}
+		if v.Default != nil {
+			if _, err := convert(v.Type, v.Default); err != nil {
+				return fmt.Errorf("%w: default of %s: %v", ErrInvalidTemplate, v.Name, err)
+			}
+		}
+	}
+
+	for i, m := range messages {
+		tmpl, err := parseMessage(i, m)
+		if err != nil {
+			return err
+		}
+		for _, name := range referenced(tmpl.Tree.Root) {
+			if !declared[name] {
+				return fmt.Errorf("%w: message %d uses undeclared variable %s", ErrInvalidTemplate, i, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Render checks the values against the declarations and executes every message
+func Render(messages []Message, variables []Variable, values map[string]interface{}) ([]Rendered, error) {
+	data := map[string]interface{}{}
+	declared := map[string]bool{}
+	for _, v := range variables {
+		declared[v.Name] = true
+		value, ok := values[v.Name]
+		switch {
+		case ok:
+			converted, err := convert(v.Type, value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidVariables, v.Name, err)
+			}
+			data[v.Name] = converted
+		case v.Required:
+			return nil, fmt.Errorf("%w: %s is required", ErrInvalidVariables, v.Name)
+		case v.Default != nil:
+			data[v.Name], _ = convert(v.Type, v.Default)
+		default:
+			data[v.Name] = zero(v.Type)
+		}
+	}
+	for name := range values {
+		if !declared[name] {
+			return nil, fmt.Errorf("%w: %s is not declared", ErrInvalidVariables, name)
+		}
+	}
+
+	res := []Rendered{}
+	for i, m := range messages {
+		tmpl, err := parseMessage(i, m)
+		if err != nil {
+			return nil, err
+		}
+		var text strings.Builder
+		if err := tmpl.Execute(&text, data); err != nil {
+			return nil, fmt.Errorf("%w: message %d: %v", ErrInvalidVariables, i, err)
+		}
+		res = append(res, Rendered{Role: m.Role, Text: text.String()})
+	}
+	return res, nil
+}
+
+func parseMessage(i int, m Message) (*template.Template, error) {
+	if m.Role == "" {
+		return nil, fmt.Errorf("%w: message %d has no role", ErrInvalidTemplate, i)
+	}
+	tmpl, err := template.New(fmt.Sprintf("message-%d", i)).Option("missingkey=error").Parse(m.Template)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// referenced returns the root variables used by a template. Fields inside range and with blocks
+// are relative to the new dot and skipped, $.name always refers to the root.
+func referenced(root parse.Node) []string {
+	names := []string{}
+	var walk func(node parse.Node, rootDot bool)
+	walk = func(node parse.Node, rootDot bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, rootDot)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, rootDot)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, rootDot)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, rootDot)
+			}
+		case *parse.FieldNode:
+			if rootDot {
+				names = append(names, n.Ident[0])
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				names = append(names, n.Ident[1])
+			}
+		case *parse.IfNode:
+			walk(n.Pipe, rootDot)
+			walk(n.List, rootDot)
+			walk(n.ElseList, rootDot)
+		case *parse.RangeNode:
+			walk(n.Pipe, rootDot)
+			walk(n.List, false)
+			walk(n.ElseList, rootDot)
+		case *parse.WithNode:
+			walk(n.Pipe, rootDot)
+			walk(n.List, false)
+			walk(n.ElseList, rootDot)
+		case *parse.TemplateNode:
+			walk(n.Pipe, rootDot)
+		}
+	}
+	walk(root, true)
+	return names
+}
+
+// convert checks a JSON decoded value against a declared type, whole numbers become int64 for integers
+func convert(typ string, value interface{}) (interface{}, error) {
+	switch typ {
+	case TypeString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case TypeNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		}
+	case TypeInteger:
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) {
+				return int64(v), nil
+			}
+		case int:
+			return int64(v), nil
+		}
+	case TypeBoolean:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case TypeArray:
+		if v, ok := value.([]interface{}); ok {
+			return v, nil
+		}
+	case TypeObject:
+		if v, ok := value.(map[string]interface{}); ok {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("expected %s, got %T", typ, value)
+}
+
+// zero returns the value of an optional variable without default
+func zero(typ string) interface{} {
+	switch typ {
+	case TypeString:
+		return ""
+	case TypeNumber:
+		return float64(0)
+	case TypeInteger:
+		return int64(0)
+	case TypeBoolean:
+		return false
+	case TypeArray:
+		return []interface{}{}
+	case TypeObject:
+		return map[string]interface{}{}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package prompttemplate_test
+
+import (
+	"testing"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/prompttemplate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type Suite struct {
+	suite.Suite
+}
+
+var (
+	messages = []prompttemplate.Message{
+		{Role: "system", Template: "You review {{.language}} code{{if .strict}} strictly{{end}}."},
+		{Role: "user", Template: "{{range .files}}- {{.}}\n{{end}}Max findings: {{$.max_findings}}"},
+	}
+	variables = []prompttemplate.Variable{
+		{Name: "language", Type: prompttemplate.TypeString, Required: true},
+		{Name: "strict", Type: prompttemplate.TypeBoolean},
+		{Name: "files", Type: prompttemplate.TypeArray, Required: true},
+		{Name: "max_findings", Type: prompttemplate.TypeInteger, Default: float64(5)},
+	}
+)
+
+func (s *Suite) TestValidate() {
+	s.Run("Valid template", func() {
+		assert.NoError(s.T(), prompttemplate.Validate(messages, variables))
+	})
+
+	s.Run("Undeclared variable", func() {
+		err := prompttemplate.Validate([]prompttemplate.Message{{Role: "user", Template: "{{.missing}}"}}, variables)
+		assert.ErrorIs(s.T(), err, prompttemplate.ErrInvalidTemplate)
+	})
+
+	s.Run("Syntax error", func() {
+		err := prompttemplate.Validate([]prompttemplate.Message{{Role: "user", Template: "{{.language"}}, variables)
+		assert.ErrorIs(s.T(), err, prompttemplate.ErrInvalidTemplate)
+	})
+
+	s.Run("Default of the wrong type", func() {
+		err := prompttemplate.Validate(messages, []prompttemplate.Variable{{Name: "language", Type: prompttemplate.TypeString, Default: 1.5}})
+		assert.ErrorIs(s.T(), err, prompttemplate.ErrInvalidTemplate)
+	})
+}
+
+func (s *Suite) TestRender() {
+	s.Run("Renders with defaults", func() {
+		rendered, err := prompttemplate.Render(messages, variables, map[string]interface{}{
+			"language": "Go",
+			"files":    []interface{}{"main.go", "user.go"},
+		})
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), []prompttemplate.Rendered{
+			{Role: "system", Text: "You review Go code."},
+			{Role: "user", Text: "- main.go\n- user.go\nMax findings: 5"},
+		}, rendered)
+	})
+
+	s.Run("Missing required variable", func() {
+		_, err := prompttemplate.Render(messages, variables, map[string]interface{}{"language": "Go"})
+		assert.ErrorIs(s.T(), err, prompttemplate.ErrInvalidVariables)
+	})
+
+	s.Run("Wrong type", func() {
+		_, err := prompttemplate.Render(messages, variables, map[string]interface{}{
+			"language":     "Go",
+			"files":        []interface{}{},
+			"max_findings": 2.5,
+		})
+		assert.ErrorIs(s.T(), err, prompttemplate.ErrInvalidVariables)
+	})
+
+	s.Run("Undeclared variable", func() {
+		_, err := prompttemplate.Render(messages, variables, map[string]interface{}{
+			"language": "Go",
+			"files":    []interface{}{},
+			"extra":    true,
+		})
+		assert.ErrorIs(s.T(), err, prompttemplate.ErrInvalidVariables)
+	})
+}
+
+func TestPromptTemplate(t *testing.T) {
+	suite.Run(t, &Suite{})
+}
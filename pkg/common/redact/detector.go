@@ -0,0 +1,153 @@
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Detectors registered by this package
+const (
+	DetectorEmail      = "email"
+	DetectorPhone      = "phone"
+	DetectorCreditCard = "credit_card"
+	DetectorNationalID = "national_id"
+	DetectorIBAN       = "iban"
+	DetectorSecret     = "secret"
+)
+
+// Detector finds sensitive values in text
+type Detector interface {
+	Name() string
+	// Find returns the [start, end) byte offsets of every match
+	Find(text string) [][2]int
+}
+
+var registry = map[string]Detector{}
+
+// Register adds a detector, replacing a registered one with the same name
+func Register(detector Detector) {
+	registry[detector.Name()] = detector
+}
+
+// Names returns the names of the registered detectors
+func Names() []string {
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(regexDetector{name: DetectorEmail, pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)})
+	Register(regexDetector{name: DetectorPhone, pattern: regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?\(?\d{2,4}\)?[\s.\-]?\d{3,4}[\s.\-]?\d{3,4}`), check: phoneDigits})
+	Register(regexDetector{name: DetectorCreditCard, pattern: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`), check: luhn})
+	Register(regexDetector{name: DetectorNationalID, pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b|\b\d{16}\b`), check: nationalID})
+	Register(regexDetector{name: DetectorIBAN, pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`), check: ibanChecksum})
+	Register(regexDetector{name: DetectorSecret, pattern: regexp.MustCompile(
+		`\bsk-[A-Za-z0-9_\-]{20,}|\bAKIA[0-9A-Z]{16}\b|\bgh[pousr]_[A-Za-z0-9]{36}\b|\bxox[abprs]-[A-Za-z0-9\-]{10,}|` +
+			`(?i)(?:api[_\-]?key|secret|token|password|passwd)["']?\s*[:=]\s*["']?([^\s"',;]{8,})`)})
+}
+
+// regexDetector reports the matches of a pattern that pass check. When the pattern has a capture group
+// only the group is reported, so `password: hunter22` keeps its key.
+type regexDetector struct {
+	name    string
+	pattern *regexp.Regexp
+	check   func(match string) bool
+}
+
+func (d regexDetector) Name() string { return d.name }
+
+func (d regexDetector) Find(text string) [][2]int {
+	res := [][2]int{}
+	for _, loc := range d.pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if len(loc) >= 4 && loc[2] >= 0 {
+			start, end = loc[2], loc[3]
+		}
+		if d.check != nil && !d.check(text[start:end]) {
+			continue
+		}
+		res = append(res, [2]int{start, end})
+	}
+	return res
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// phoneDigits keeps numbers long enough to be phone numbers
+func phoneDigits(match string) bool {
+	n := len(digits(match))
+	return n >= 9 && n <= 15
+}
+
+// luhn validates a card number checksum
+func luhn(match string) bool {
+	number := digits(match)
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < len(number); i++ {
+		d := int(number[len(number)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// nationalID validates US social security numbers and Indonesian NIKs by their structure
+func nationalID(match string) bool {
+	if strings.Contains(match, "-") {
+		area := match[:3]
+		return area != "000" && area != "666" && area[0] != '9' && match[4:6] != "00" && match[7:] != "0000"
+	}
+
+	// NIK: region(6) day(2, +40 for women) month(2) year(2) serial(4)
+	day, _ := strconv.Atoi(match[6:8])
+	month, _ := strconv.Atoi(match[8:10])
+	if day > 40 {
+		day -= 40
+	}
+	return match[:2] >= "11" && day >= 1 && day <= 31 && month >= 1 && month <= 12 && match[12:] != "0000"
+}
+
+// ibanChecksum validates the mod-97 checksum of an IBAN
+func ibanChecksum(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	rearranged := iban[4:] + iban[:4]
+	remainder := 0
+	for _, r := range rearranged {
+		var v int
+		switch {
+		case r >= '0' && r <= '9':
+			v = int(r - '0')
+			remainder = (remainder*10 + v) % 97
+		case r >= 'A' && r <= 'Z':
+			v = int(r-'A') + 10
+			remainder = (remainder*100 + v) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
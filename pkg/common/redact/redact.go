@@ -0,0 +1,136 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redaction modes
+const (
+	ModeMask        = "mask"        // replaces a match with [REDACTED:<detector>]
+	ModeHash        = "hash"        // replaces a match with [<detector>:<keyed hash>], equal values get equal hashes
+	ModePlaceholder = "placeholder" // replaces a match with <DETECTOR_N>, restored in responses with Restore
+)
+
+// placeholderPattern matches the placeholders written in ModePlaceholder
+var placeholderPattern = regexp.MustCompile(`<[A-Z_]+_\d+>`)
+
+// Redactor replaces the matches of its detectors in text
+type Redactor struct {
+	mode      string
+	detectors []Detector
+	hashKey   []byte
+}
+
+// Vault maps placeholders to the values they replace. It is filled by Redact in ModePlaceholder and
+// may be kept across calls so a value keeps its placeholder.
+type Vault map[string]string
+
+// New returns a redactor using the named detectors, all registered detectors when names is empty
+func New(mode string, names []string, hashKey string) (*Redactor, error) {
+	switch mode {
+	case ModeMask, ModeHash, ModePlaceholder:
+	default:
+		return nil, fmt.Errorf("unknown redaction mode %q", mode)
+	}
+	if len(names) == 0 {
+		names = Names()
+	}
+
+	r := &Redactor{mode: mode, hashKey: []byte(hashKey)}
+	for _, name := range names {
+		detector, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown detector %q", name)
+		}
+		r.detectors = append(r.detectors, detector)
+	}
+	return r, nil
+}
+
+// Redact replaces every detected value in text and returns the names of the detectors that matched
+func (r *Redactor) Redact(text string, vault Vault) (string, []string) {
+	type span struct {
+		start, end int
+		detector   string
+	}
+	spans := []span{}
+	for _, detector := range r.detectors {
+		for _, loc := range detector.Find(text) {
+			spans = append(spans, span{loc[0], loc[1], detector.Name()})
+		}
+	}
+	if len(spans) == 0 {
+		return text, nil
+	}
+
+	// earlier and then longer matches win, overlapping ones are dropped
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+
+	var out strings.Builder
+	found := map[string]bool{}
+	detected := []string{}
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			continue
+		}
+		out.WriteString(text[last:s.start])
+		out.WriteString(r.replacement(s.detector, text[s.start:s.end], vault))
+		last = s.end
+		if !found[s.detector] {
+			found[s.detector] = true
+			detected = append(detected, s.detector)
+		}
+	}
+	out.WriteString(text[last:])
+	return out.String(), detected
+}
+
+// Restore puts the values of a vault back in place of their placeholders
+func Restore(text string, vault Vault) string {
+	if len(vault) == 0 {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if value, ok := vault[placeholder]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+func (r *Redactor) replacement(detector string, value string, vault Vault) string {
+	switch r.mode {
+	case ModeHash:
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(value))
+		return fmt.Sprintf("[%s:%s]", detector, hex.EncodeToString(mac.Sum(nil))[:12])
+	case ModePlaceholder:
+		prefix := strings.ToUpper(detector)
+		n := 0
+		for placeholder, v := range vault {
+			if v == value && strings.HasPrefix(placeholder, "<"+prefix+"_") {
+				return placeholder
+			}
+			if strings.HasPrefix(placeholder, "<"+prefix+"_") {
+				n++
+			}
+		}
+		placeholder := fmt.Sprintf("<%s_%d>", prefix, n+1)
+		vault[placeholder] = value
+		return placeholder
+	default:
+		return fmt.Sprintf("[REDACTED:%s]", detector)
+	}
+}
@@ -0,0 +1,88 @@
+package redact_test
+
+import (
+	"testing"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/redact"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type Suite struct {
+	suite.Suite
+}
+
+func (s *Suite) TestDetectors() {
+	cases := []struct {
+		name     string
+		detector string
+		text     string
+		match    bool
+	}{
+		{"Email", redact.DetectorEmail, "mail jane.doe@example.co.id today", true},
+		{"Phone", redact.DetectorPhone, "call +62 812-3456-7890", true},
+		{"Short number is not a phone", redact.DetectorPhone, "order 12345", false},
+		{"Card passing Luhn", redact.DetectorCreditCard, "card 4111 1111 1111 1111", true},
+		{"Card failing Luhn", redact.DetectorCreditCard, "card 4111 1111 1111 1112", false},
+		{"SSN", redact.DetectorNationalID, "ssn 123-45-6789", true},
+		{"NIK", redact.DetectorNationalID, "nik 3174054508900001", true},
+		{"IBAN", redact.DetectorIBAN, "iban GB82 WEST 1234 5698 7654 32", true},
+		{"IBAN with a bad checksum", redact.DetectorIBAN, "iban GB83 WEST 1234 5698 7654 32", false},
+		{"API key", redact.DetectorSecret, "key sk-proj-abcdefghijklmnopqrstuvwx", true},
+		{"Assigned password", redact.DetectorSecret, "password: hunter2hunter2", true},
+	}
+	for _, c := range cases {
+		s.Run(c.name, func() {
+			r, err := redact.New(redact.ModeMask, []string{c.detector}, "")
+			require.NoError(s.T(), err)
+			_, detected := r.Redact(c.text, nil)
+			assert.Equal(s.T(), c.match, len(detected) > 0)
+		})
+	}
+}
+
+func (s *Suite) TestModes() {
+	text := "Mail jane@example.com and password=hunter2hunter2, again jane@example.com"
+
+	s.Run("Mask", func() {
+		r, err := redact.New(redact.ModeMask, nil, "")
+		require.NoError(s.T(), err)
+		redacted, detected := r.Redact(text, nil)
+		assert.Equal(s.T(), "Mail [REDACTED:email] and password=[REDACTED:secret], again [REDACTED:email]", redacted)
+		assert.ElementsMatch(s.T(), []string{redact.DetectorEmail, redact.DetectorSecret}, detected)
+	})
+
+	s.Run("Hash keeps equal values equal", func() {
+		r, err := redact.New(redact.ModeHash, []string{redact.DetectorEmail}, "key")
+		require.NoError(s.T(), err)
+		redacted, _ := r.Redact("a@example.com b@example.com a@example.com", nil)
+		assert.Regexp(s.T(), `^\[email:([0-9a-f]{12})\] \[email:[0-9a-f]{12}\] \[email:([0-9a-f]{12})\]$`, redacted)
+		assert.NotContains(s.T(), redacted, "example")
+	})
+
+	s.Run("Placeholders are restored", func() {
+		r, err := redact.New(redact.ModePlaceholder, nil, "")
+		require.NoError(s.T(), err)
+		vault := redact.Vault{}
+		redacted, _ := r.Redact(text, vault)
+		assert.Equal(s.T(), "Mail <EMAIL_1> and password=<SECRET_1>, again <EMAIL_1>", redacted)
+
+		// a later call keeps numbering from the vault
+		redacted, _ = r.Redact("cc bob@example.com", vault)
+		assert.Equal(s.T(), "cc <EMAIL_2>", redacted)
+
+		assert.Equal(s.T(), "Reply to jane@example.com and bob@example.com, not <EMAIL_9>",
+			redact.Restore("Reply to <EMAIL_1> and <EMAIL_2>, not <EMAIL_9>", vault))
+	})
+
+	s.Run("Unknown detector", func() {
+		_, err := redact.New(redact.ModeMask, []string{"dna"}, "")
+		assert.Error(s.T(), err)
+	})
+}
+
+func TestRedact(t *testing.T) {
+	suite.Run(t, &Suite{})
+}
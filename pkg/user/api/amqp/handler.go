@@ -1,15 +1,127 @@
 package amqp
 
 import (
-	oauthmanager "github.com/abialemuel/AI-Proxy-Service/pkg/common/oauth"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/abialemuel/AI-Proxy-Service/config"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/correlation"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
 )
 
+// ErrPoisonMessage marks a message that can never be processed, it skips retries and goes to the poison topic
+var ErrPoisonMessage = errors.New("poison message")
+
 type Handler struct {
-	service      business.UserService
-	oauthManager oauthmanager.OAuth2Provider
+	service business.UserService
+	config  *config.MainConfig
+}
+
+func NewHandler(s business.UserService, cfg *config.MainConfig) *Handler {
+	return &Handler{service: s, config: cfg}
+}
+
+// ServicePromptHandler runs a service prompt and produces the reply message
+func (h *Handler) ServicePromptHandler(msg *message.Message) ([]*message.Message, error) {
+	ctx, span := apm.StartTransaction(msg.Context(), "Handler::AMQPServicePrompt")
+	defer apm.EndTransaction(span)
+
+	correlationID := middleware.MessageCorrelationID(msg)
+	if correlationID == "" {
+		correlationID = msg.UUID
+	}
+	ctx = correlation.WithID(ctx, correlationID)
+
+	req := new(ServicePromptMessage)
+	if err := json.Unmarshal(msg.Payload, req); err != nil {
+		return nil, fmt.Errorf("%w: invalid payload: %v", ErrPoisonMessage, err)
+	}
+	if errMsg, check := validator.Validation(req); !check {
+		return nil, fmt.Errorf("%w: %s", ErrPoisonMessage, errMsg)
+	}
+	if !h.isService(req.ServiceName) {
+		return nil, fmt.Errorf("%w: service %s not allowed", ErrPoisonMessage, req.ServiceName)
+	}
+
+	longCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	var reply ServicePromptReply
+	res, err := h.service.ServicePrompt(longCtx, core.ServicePromptRequest{
+		ServiceName: req.ServiceName,
+		Role:        authguard.RoleService,
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		TopP:        req.TopP,
+		Messages:    ToCoreMessage(req.Messages),
+		Persona:     req.Persona,
+		Template:    req.Template,
+		Variables:   req.Variables,
+	})
+	if errors.Is(err, core.ErrModelNotAllowed) || errors.Is(err, core.ErrQuotaExceeded) ||
+		errors.Is(err, core.ErrInvalidRequest) || errors.Is(err, core.ErrNotFound) ||
+		errors.Is(err, core.ErrContentFiltered) {
+		// retrying won't help, tell the caller instead
+		reply = NewServicePromptErrorReply(correlationID, err)
+	} else if err != nil {
+		return nil, err
+	} else {
+		reply = NewServicePromptReply(correlationID, res)
+	}
+
+	payload, err := json.Marshal(reply)
+	if err != nil {
+		return nil, err
+	}
+	replyMsg := message.NewMessage(watermill.NewUUID(), payload)
+	middleware.SetCorrelationID(correlationID, replyMsg)
+
+	return []*message.Message{replyMsg}, nil
+}
+
+// RetryTransient retries failing messages, except poison messages which are passed on untouched
+func (h *Handler) RetryTransient(next message.HandlerFunc) message.HandlerFunc {
+	retry := middleware.Retry{
+		MaxRetries:      h.config.AMQP.MaxRetries,
+		InitialInterval: 500 * time.Millisecond,
+		MaxInterval:     10 * time.Second,
+		Multiplier:      2,
+	}
+
+	return func(msg *message.Message) ([]*message.Message, error) {
+		var poisonErr error
+		produced, err := retry.Middleware(func(msg *message.Message) ([]*message.Message, error) {
+			produced, err := next(msg)
+			if errors.Is(err, ErrPoisonMessage) {
+				poisonErr = err
+				return nil, nil
+			}
+			return produced, err
+		})(msg)
+		if poisonErr != nil {
+			return nil, poisonErr
+		}
+		return produced, err
+	}
 }
 
-func NewHandler(s business.UserService) *Handler {
-	return &Handler{service: s}
+// isService checks if the service is configured
+func (h *Handler) isService(name string) bool {
+	for _, service := range h.config.Services {
+		if service.Name == name {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,159 @@
+package amqp_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/abialemuel/AI-Proxy-Service/config"
+	userAPIamqp "github.com/abialemuel/AI-Proxy-Service/pkg/user/api/amqp"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/contract"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fakeRepository struct {
+	contract.Repository
+}
+
+func (fakeRepository) InsertUsage(ctx context.Context, usage repository.Usage) error {
+	return nil
+}
+
+func (fakeRepository) InsertMessages(ctx context.Context, messages []repository.Message) error {
+	return nil
+}
+
+func (fakeRepository) GetRoleModels(ctx context.Context, role string) (repository.RoleModels, error) {
+	return repository.RoleModels{}, mongo.ErrNoDocuments
+}
+
+type fakeGPT4WebService struct{}
+
+func (fakeGPT4WebService) Prompt(ctx context.Context, payload gpt4_webservice.GPT4PromptRequestDao) (gpt4_webservice.GPT4PromptResponseDao, error) {
+	return gpt4_webservice.GPT4PromptResponseDao{
+		Model:   payload.Model,
+		Choices: []gpt4_webservice.Choices{{Message: gpt4_webservice.Message{Role: "assistant", Content: "pong"}}},
+		Usage:   gpt4_webservice.Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+	}, nil
+}
+
+type Suite struct {
+	cfg     *config.MainConfig
+	pubSub  *gochannel.GoChannel
+	router  *message.Router
+	replies <-chan *message.Message
+	poison  <-chan *message.Message
+	suite.Suite
+}
+
+func (s *Suite) SetupSuite() {
+	s.cfg = &config.MainConfig{}
+	s.cfg.AMQP.PromptTopic = "prompt.requests"
+	s.cfg.AMQP.ReplyTopic = "prompt.replies"
+	s.cfg.AMQP.PoisonTopic = "prompt.poison"
+	s.cfg.AMQP.MaxRetries = 1
+	s.cfg.Services = []config.BackendService{{Tribe: "tribeA", Name: "code-review"}}
+
+	logger := watermill.NopLogger{}
+	s.pubSub = gochannel.NewGoChannel(gochannel.Config{}, logger)
+
+	var err error
+	s.replies, err = s.pubSub.Subscribe(context.Background(), s.cfg.AMQP.ReplyTopic)
+	require.NoError(s.T(), err)
+	s.poison, err = s.pubSub.Subscribe(context.Background(), s.cfg.AMQP.PoisonTopic)
+	require.NoError(s.T(), err)
+
+	s.router, err = message.NewRouter(message.RouterConfig{}, logger)
+	require.NoError(s.T(), err)
+
+	service := business.NewUserService(fakeRepository{}, nil, s.cfg, fakeGPT4WebService{}, nil, nil, nil, nil, nil, nil, nil, nil)
+	userAPIamqp.RegisterPath(s.router, s.pubSub, s.pubSub, userAPIamqp.NewHandler(service, s.cfg))
+
+	go s.router.Run(context.Background())
+	<-s.router.Running()
+}
+
+func (s *Suite) TearDownSuite() {
+	s.router.Close()
+}
+
+func (s *Suite) publish(payload []byte, correlationID string) {
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	middleware.SetCorrelationID(correlationID, msg)
+	require.NoError(s.T(), s.pubSub.Publish(s.cfg.AMQP.PromptTopic, msg))
+}
+
+func (s *Suite) receive(ch <-chan *message.Message) *message.Message {
+	select {
+	case msg := <-ch:
+		msg.Ack()
+		return msg
+	case <-time.After(5 * time.Second):
+		s.T().Fatal("timeout waiting for message")
+		return nil
+	}
+}
+
+func (s *Suite) TestServicePrompt() {
+	s.Run("Reply carries correlation ID", func() {
+		text, temperature := "ping", 0.2
+		payload, _ := json.Marshal(userAPIamqp.ServicePromptMessage{
+			ServiceName: "code-review",
+			Model:       "gpt-4o-mini",
+			Temperature: &temperature,
+			MaxTokens:   10,
+			TopP:        1,
+			Messages:    []userAPIamqp.Message{{Role: "user", Content: []userAPIamqp.Content{{Type: "text", Text: &text}}}},
+		})
+		s.publish(payload, "corr-1")
+
+		msg := s.receive(s.replies)
+		var reply userAPIamqp.ServicePromptReply
+		require.NoError(s.T(), json.Unmarshal(msg.Payload, &reply))
+		assert.Equal(s.T(), "corr-1", reply.CorrelationID)
+		assert.Equal(s.T(), "corr-1", middleware.MessageCorrelationID(msg))
+		assert.Equal(s.T(), "success", reply.Status)
+		assert.Equal(s.T(), "pong", reply.Content)
+		assert.Equal(s.T(), 2, reply.Usage.TotalTokens)
+	})
+
+	s.Run("Malformed payload goes to poison topic", func() {
+		s.publish([]byte("{not json"), "corr-2")
+
+		msg := s.receive(s.poison)
+		assert.Equal(s.T(), "corr-2", middleware.MessageCorrelationID(msg))
+		assert.Contains(s.T(), msg.Metadata.Get(middleware.ReasonForPoisonedKey), "poison message")
+	})
+
+	s.Run("Unknown service goes to poison topic", func() {
+		text, temperature := "ping", 0.2
+		payload, _ := json.Marshal(userAPIamqp.ServicePromptMessage{
+			ServiceName: "unknown",
+			Model:       "gpt-4o-mini",
+			Temperature: &temperature,
+			MaxTokens:   10,
+			TopP:        1,
+			Messages:    []userAPIamqp.Message{{Role: "user", Content: []userAPIamqp.Content{{Type: "text", Text: &text}}}},
+		})
+		s.publish(payload, "corr-3")
+
+		msg := s.receive(s.poison)
+		assert.Contains(s.T(), msg.Metadata.Get(middleware.ReasonForPoisonedKey), "not allowed")
+	})
+}
+
+func TestAMQPHandler(t *testing.T) {
+	suite.Run(t, &Suite{})
+}
@@ -0,0 +1,99 @@
+package amqp
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+// ServicePromptMessage is the payload published on the prompt topic
+type ServicePromptMessage struct {
+	ServiceName string    `json:"service_name" validate:"required"`
+	Model       string    `json:"model"` // may be left to the persona
+	Temperature *float64  `json:"temperature" validate:"omitempty,gte=0,lte=2"`
+	MaxTokens   int       `json:"max_tokens" validate:"omitempty,min=1"`
+	TopP        float64   `json:"top_p" validate:"omitempty,gt=0,lte=1"`
+	Messages    []Message `json:"messages" validate:"required_without=Template,dive"`
+	Persona     string    `json:"persona" validate:"max=80"`
+
+	Template  string                 `json:"template" validate:"max=80"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type Message struct {
+	Role    string    `json:"role" validate:"required"`
+	Content []Content `json:"content" validate:"required"`
+}
+
+type Content struct {
+	Type     string    `json:"type" validate:"required"`
+	Text     *string   `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ServicePromptReply is the payload published on the reply topic, it carries the request's correlation ID
+type ServicePromptReply struct {
+	CorrelationID string `json:"correlation_id"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+	Model         string `json:"model,omitempty"`
+	Content       string `json:"content,omitempty"`
+	Usage         Usage  `json:"usage"`
+	Cached        bool   `json:"cached"`
+
+	Inspection *core.PromptInspection `json:"inspection,omitempty"`
+	MessageID  string                 `json:"message_id,omitempty"`
+}
+
+type Usage struct {
+	CompletionTokens int `json:"completion_tokens"`
+	PromptTokens     int `json:"prompt_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func ToCoreMessage(req []Message) (res []core.MessageRequest) {
+	for _, v := range req {
+		var message core.MessageRequest
+		message.Role = v.Role
+		for _, c := range v.Content {
+			var content core.Content
+			content.Type = c.Type
+			content.Text = c.Text
+			if c.ImageURL != nil {
+				content.ImageURL = &core.ImageURL{
+					URL:    c.ImageURL.URL,
+					Detail: c.ImageURL.Detail,
+				}
+			}
+			message.Content = append(message.Content, content)
+		}
+		res = append(res, message)
+	}
+	return res
+}
+
+func NewServicePromptReply(correlationID string, v core.ServicePromGPTResponse) ServicePromptReply {
+	return ServicePromptReply{
+		CorrelationID: correlationID,
+		Status:        "success",
+		Model:         v.GPT4PromptResponse.Model,
+		Content:       v.GPT4PromptResponse.Choices[0].Message.Content,
+		Usage: Usage{
+			CompletionTokens: v.GPT4PromptResponse.Usage.CompletionTokens,
+			PromptTokens:     v.GPT4PromptResponse.Usage.PromptTokens,
+			TotalTokens:      v.GPT4PromptResponse.Usage.TotalTokens,
+		},
+		Cached:     v.Cached,
+		Inspection: v.Inspection,
+		MessageID:  v.MessageID,
+	}
+}
+
+func NewServicePromptErrorReply(correlationID string, err error) ServicePromptReply {
+	return ServicePromptReply{
+		CorrelationID: correlationID,
+		Status:        "error",
+		Error:         err.Error(),
+	}
+}
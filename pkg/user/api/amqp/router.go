@@ -2,7 +2,34 @@ package amqp
 
 import (
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
 )
 
+// RegisterPath Register async prompt handlers
 func RegisterPath(r *message.Router, pub message.Publisher, sub message.Subscriber, h *Handler) {
+	if h == nil {
+		panic("amqp handler cannot be nil")
+	}
+
+	poisonQueue, err := middleware.PoisonQueue(pub, h.config.AMQP.PoisonTopic)
+	if err != nil {
+		panic(err)
+	}
+
+	// poison queue catches what the retries could not handle, panics are turned into retriable errors
+	r.AddMiddleware(
+		middleware.CorrelationID,
+		poisonQueue,
+		h.RetryTransient,
+		middleware.Recoverer,
+	)
+
+	r.AddHandler(
+		"service_prompt",
+		h.config.AMQP.PromptTopic,
+		sub,
+		h.config.AMQP.ReplyTopic,
+		pub,
+		h.ServicePromptHandler,
+	)
 }
@@ -0,0 +1,260 @@
+package http
+
+import (
+	"net/http"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/pagination"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+const auditDefaultListLimit = 50
+
+// AdminSetUserRoleHandler overrides the role of a user
+func (h *Handler) AdminSetUserRoleHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminSetUserRole")
+	defer apm.EndTransaction(span)
+
+	req := new(request.AdminUserRole)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	err := h.service.SetUserRole(ctx, c.Param("email"), req.Role)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, common.NewValidationErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, common.NewDefaultSuccessResponse())
+}
+
+// AdminListUsersHandler lists known users with their last-seen time and current usage
+func (h *Handler) AdminListUsersHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminListUsers")
+	defer apm.EndTransaction(span)
+
+	req := new(request.AdminUserList)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Query"))
+	}
+	meta, err := pagination.Parse(req.Limit, req.Page)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(err.Error()))
+	}
+
+	users, count, err := h.service.ListUsers(ctx, meta.Offset, meta.Limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, common.NewValidationErrorResponse(err.Error()))
+	}
+	meta.Count = int(count)
+
+	return c.JSON(http.StatusOK, response.NewAdminUserListResponse(users, meta))
+}
+
+// AdminGetUserHandler returns a single user with their current usage
+func (h *Handler) AdminGetUserHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminGetUser")
+	defer apm.EndTransaction(span)
+
+	user, err := h.service.GetUserDetail(ctx, c.Param("email"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, common.NewNotFoundResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewAdminUserResponse(user))
+}
+
+// AdminResetQuotaHandler resets the token usage of a user
+func (h *Handler) AdminResetQuotaHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminResetQuota")
+	defer apm.EndTransaction(span)
+
+	err := h.service.ResetUserQuota(ctx, c.Param("email"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, common.NewValidationErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, common.DefaultResponse{Code: 200, Message: "Quota Reset"})
+}
+
+// AdminGrantQuotaHandler grants extra tokens to a user for the current usage window
+func (h *Handler) AdminGrantQuotaHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminGrantQuota")
+	defer apm.EndTransaction(span)
+
+	req := new(request.AdminGrantQuota)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	err := h.service.GrantUserQuota(ctx, c.Param("email"), req.Tokens)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, common.NewValidationErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, common.DefaultResponse{Code: 200, Message: "Quota Granted"})
+}
+
+// AdminClearContextHandler clears the context of a user
+func (h *Handler) AdminClearContextHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminClearContext")
+	defer apm.EndTransaction(span)
+
+	err := h.service.UserClearContext(ctx, c.Param("email"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, common.NewValidationErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, common.DefaultResponse{Code: 200, Message: "Context Cleared"})
+}
+
+// AdminBlockUserHandler blocks a user
+func (h *Handler) AdminBlockUserHandler(c echo.Context) error {
+	return h.setUserBlocked(c, true)
+}
+
+// AdminUnblockUserHandler unblocks a user
+func (h *Handler) AdminUnblockUserHandler(c echo.Context) error {
+	return h.setUserBlocked(c, false)
+}
+
+func (h *Handler) setUserBlocked(c echo.Context, blocked bool) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminSetUserBlocked")
+	defer apm.EndTransaction(span)
+
+	err := h.service.SetUserBlocked(ctx, c.Param("email"), blocked)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, common.NewValidationErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, common.NewDefaultSuccessResponse())
+}
+
+// AdminListAuditEntriesHandler lists the latest audit entries, optionally of a single action
+func (h *Handler) AdminListAuditEntriesHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminListAuditEntries")
+	defer apm.EndTransaction(span)
+
+	req := new(request.AdminAuditList)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Query"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+	if req.Limit == 0 {
+		req.Limit = auditDefaultListLimit
+	}
+
+	entries, err := h.service.ListAuditEntries(ctx, req.Action, req.Limit)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewAdminAuditListResponse(entries))
+}
+
+// AdminRotateServiceKeyHandler replaces the password of a service with a generated one, returned only once
+func (h *Handler) AdminRotateServiceKeyHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminRotateServiceKey")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	key, err := h.service.RotateServiceKey(ctx, c.Param("name"), jwtAtrr.Email)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewAdminServiceKeyResponse(key))
+}
+
+// AdminResetServiceKeyHandler drops the generated password of a service, its configured one applies again
+func (h *Handler) AdminResetServiceKeyHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminResetServiceKey")
+	defer apm.EndTransaction(span)
+
+	err := h.service.ResetServiceKey(ctx, c.Param("name"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, common.NewDefaultSuccessResponse())
+}
+
+// AdminGetRoleModelsHandler returns the models a role may use
+func (h *Handler) AdminGetRoleModelsHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminGetRoleModels")
+	defer apm.EndTransaction(span)
+
+	req := new(request.AdminRoleModels)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Role"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	models, err := h.service.GetRoleModels(ctx, req.Role)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewAdminRoleModelsResponse(models))
+}
+
+// AdminSetRoleModelsHandler replaces the models a role may use, an empty list allows every model
+func (h *Handler) AdminSetRoleModelsHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminSetRoleModels")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	req := new(request.AdminRoleModels)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	models, err := h.service.SetRoleModels(ctx, req.Role, req.AllowedModels, jwtAtrr.Email)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewAdminRoleModelsResponse(models))
+}
+
+// AdminResetRoleModelsHandler drops the models set for a role, its configured allow-list applies again
+func (h *Handler) AdminResetRoleModelsHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminResetRoleModels")
+	defer apm.EndTransaction(span)
+
+	req := new(request.AdminRoleModels)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Role"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	models, err := h.service.ResetRoleModels(ctx, req.Role)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewAdminRoleModelsResponse(models))
+}
@@ -1,10 +1,12 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/common/oauth/model"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
@@ -43,7 +45,7 @@ func (h *Handler) AuthHandler(c echo.Context) (err error) {
 
 // GoogleAuthCallback Receive Callback
 func (h *Handler) GoogleAuthCallback(c echo.Context) error {
-	_, span := apm.StartTransaction(c.Request().Context(), "Handler::GoogleCallback")
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::GoogleCallback")
 	defer apm.EndTransaction(span)
 
 	req := new(request.AuthCallback)
@@ -57,16 +59,27 @@ func (h *Handler) GoogleAuthCallback(c echo.Context) error {
 
 	token, err := h.googleOauth.ExchangeCodeForToken(req.Code)
 	if err != nil {
+		h.service.RecordLogin(ctx, "google", "", err)
 		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(err.Error()))
 	}
 
+	// the sign-in only counts once the guard would let the user in
+	claims, err := h.authGuard.SignIn(ctx, token.IDToken)
+	if errors.Is(err, authguard.ErrUserStateUnavailable) {
+		return c.JSON(http.StatusServiceUnavailable, common.NewServiceUnavailableResponse(err.Error()))
+	}
+	h.service.RecordLogin(ctx, "google", claims.Email, err)
+	if err != nil {
+		return c.JSON(http.StatusForbidden, common.NewForbiddenResponse(err.Error()))
+	}
+
 	// redirect to url
 	return c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s?access_token=%s&refresh_token=%s", h.config.UI.Host+UIPath, token.IDToken, token.RefreshToken))
 }
 
 // MicrosoftAuthCallback Receive Callback
 func (h *Handler) MicrosoftAuthCallback(c echo.Context) error {
-	_, span := apm.StartTransaction(c.Request().Context(), "Handler::MicrosoftCallback")
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::MicrosoftCallback")
 	defer apm.EndTransaction(span)
 
 	req := new(request.AuthCallback)
@@ -79,9 +92,20 @@ func (h *Handler) MicrosoftAuthCallback(c echo.Context) error {
 
 	token, err := h.microsoftOauth.ExchangeCodeForToken(req.Code)
 	if err != nil {
+		h.service.RecordLogin(ctx, "microsoft", "", err)
 		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(err.Error()))
 	}
 
+	// the sign-in only counts once the guard would let the user in
+	claims, err := h.authGuard.SignIn(ctx, token.IDToken)
+	if errors.Is(err, authguard.ErrUserStateUnavailable) {
+		return c.JSON(http.StatusServiceUnavailable, common.NewServiceUnavailableResponse(err.Error()))
+	}
+	h.service.RecordLogin(ctx, "microsoft", claims.Email, err)
+	if err != nil {
+		return c.JSON(http.StatusForbidden, common.NewForbiddenResponse(err.Error()))
+	}
+
 	// redirect to url with token
 	return c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s?access_token=%s&refresh_token=%s", h.config.UI.Host+UIPath, token.IDToken, token.RefreshToken))
 }
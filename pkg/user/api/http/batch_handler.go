@@ -0,0 +1,150 @@
+package http
+
+import (
+	"io"
+	"net/http"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+const batchDefaultListLimit = 20
+
+// UploadFileHandler uploads a JSONL batch input file
+func (h *Handler) UploadFileHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::UploadFile")
+	defer apm.EndTransaction(span)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("file is required"))
+	}
+	if fileHeader.Size > h.config.Batch.MaxFileBytes {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("file is too large"))
+	}
+	src, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(err.Error()))
+	}
+	defer src.Close()
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(err.Error()))
+	}
+
+	serviceName := c.Get("service").(string)
+	file, err := h.service.CreateFile(ctx, serviceName, c.FormValue("purpose"), fileHeader.Filename, content)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewFileResponse(file))
+}
+
+// GetFileHandler returns file metadata
+func (h *Handler) GetFileHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::GetFile")
+	defer apm.EndTransaction(span)
+
+	serviceName := c.Get("service").(string)
+	file, err := h.service.GetFile(ctx, serviceName, c.Param("id"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewFileResponse(file))
+}
+
+// GetFileContentHandler downloads file content
+func (h *Handler) GetFileContentHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::GetFileContent")
+	defer apm.EndTransaction(span)
+
+	serviceName := c.Get("service").(string)
+	content, err := h.service.GetFileContent(ctx, serviceName, c.Param("id"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.Blob(http.StatusOK, "application/jsonl", content)
+}
+
+// CreateBatchHandler creates a batch from an uploaded input file
+func (h *Handler) CreateBatchHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::CreateBatch")
+	defer apm.EndTransaction(span)
+
+	req := new(request.BatchCreate)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	serviceName := c.Get("service").(string)
+	batch, err := h.service.CreateBatch(ctx, serviceName, request.ToCoreBatchRequest(*req))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewBatchResponse(batch))
+}
+
+// ListBatchesHandler lists the latest batches of the service
+func (h *Handler) ListBatchesHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ListBatches")
+	defer apm.EndTransaction(span)
+
+	req := new(request.BatchList)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Query"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+	if req.Limit == 0 {
+		req.Limit = batchDefaultListLimit
+	}
+
+	serviceName := c.Get("service").(string)
+	batches, err := h.service.ListBatches(ctx, serviceName, req.Limit)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewBatchListResponse(batches, req.Limit))
+}
+
+// GetBatchHandler returns a batch
+func (h *Handler) GetBatchHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::GetBatch")
+	defer apm.EndTransaction(span)
+
+	serviceName := c.Get("service").(string)
+	batch, err := h.service.GetBatch(ctx, serviceName, c.Param("id"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewBatchResponse(batch))
+}
+
+// CancelBatchHandler cancels a batch
+func (h *Handler) CancelBatchHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::CancelBatch")
+	defer apm.EndTransaction(span)
+
+	serviceName := c.Get("service").(string)
+	batch, err := h.service.CancelBatch(ctx, serviceName, c.Param("id"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewBatchResponse(batch))
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+// GetConversationHandler returns the current conversation of the user with all its branches
+func (h *Handler) GetConversationHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::GetConversation")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	conversation, err := h.service.GetConversation(ctx, jwtAtrr.Email)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewConversationResponse(conversation))
+}
+
+// SwitchBranchHandler moves the user to another branch of the conversation
+func (h *Handler) SwitchBranchHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::SwitchBranch")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	req := new(request.ConversationBranch)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	conversation, err := h.service.SwitchBranch(ctx, jwtAtrr.Email, req.MessageID)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewConversationResponse(conversation))
+}
+
+// RegenerateHandler answers the last prompt of the conversation again
+func (h *Handler) RegenerateHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::Regenerate")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	req := new(request.UserRegenerateRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	longCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	res, err := h.service.RegenerateLastAnswer(longCtx, core.UserPromtGPTRequest{
+		UserID:         jwtAtrr.Email,
+		Role:           c.Get(authguard.RoleAttr).(string),
+		KnowledgeBases: req.KnowledgeBases,
+		Persona:        req.Persona,
+		Settings:       request.ToCoreRegenerateSettings(*req),
+	})
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewUserPromGPTResponse(res))
+}
+
+// EditMessageHandler replaces a past user message and answers it on a new branch
+func (h *Handler) EditMessageHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::EditMessage")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	req := new(request.UserPromptGPTRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	longCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	res, err := h.service.EditMessage(longCtx, c.Param("id"), core.UserPromtGPTRequest{
+		UserID:         jwtAtrr.Email,
+		Role:           c.Get(authguard.RoleAttr).(string),
+		Content:        request.ToCoreUserPromptGPTRequest(req.Content),
+		KnowledgeBases: req.KnowledgeBases,
+		Persona:        req.Persona,
+		Settings:       request.ToCoreModelSettings(*req),
+	})
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewUserPromGPTResponse(res))
+}
+
+// ExportConversationsHandler downloads one conversation of the user, or all of them without an id
+func (h *Handler) ExportConversationsHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ExportConversations")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+	return h.exportConversations(ctx, c, jwtAtrr.Email, c.Param("id"))
+}
+
+// AdminExportConversationsHandler downloads all conversations of a user, e.g. to curate training data
+func (h *Handler) AdminExportConversationsHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminExportConversations")
+	defer apm.EndTransaction(span)
+
+	return h.exportConversations(ctx, c, c.Param("email"), "")
+}
+
+// ImportConversationHandler restores a conversation exported as JSON, it becomes the current conversation
+func (h *Handler) ImportConversationHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ImportConversation")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	req := new(request.ConversationImport)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	conversation, err := h.service.ImportConversation(ctx, jwtAtrr.Email, c.Get(authguard.RoleAttr).(string), request.ToCoreConversationImport(*req))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewConversationResponse(conversation))
+}
+
+// exportConversations writes conversations as Markdown, JSON or fine-tuning JSONL. A single conversation
+// is exported as a JSON object that can be imported as it is, all conversations as an array.
+func (h *Handler) exportConversations(ctx context.Context, c echo.Context, userID string, conversationID string) error {
+	req := new(request.ConversationExport)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Query"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	conversations, err := h.service.ExportConversations(ctx, userID, conversationID)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	name := "conversations"
+	if conversationID != "" {
+		name = "conversation-" + conversationID
+	}
+	switch req.Format {
+	case "markdown":
+		c.Response().Header().Set(echo.HeaderContentType, "text/markdown")
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+".md"))
+		c.Response().WriteHeader(http.StatusOK)
+		return response.WriteConversationsMarkdown(c.Response(), conversations)
+	case "jsonl":
+		c.Response().Header().Set(echo.HeaderContentType, "application/jsonl")
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+".jsonl"))
+		c.Response().WriteHeader(http.StatusOK)
+		return response.WriteConversationsFineTuneJSONL(c.Response(), conversations)
+	default:
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+".json"))
+		if conversationID != "" {
+			return c.JSON(http.StatusOK, conversations[0])
+		}
+		return c.JSON(http.StatusOK, conversations)
+	}
+}
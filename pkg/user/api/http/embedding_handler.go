@@ -0,0 +1,48 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+// EmbeddingsHandler embeds the input for SSO users and backend services
+func (h *Handler) EmbeddingsHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::Embeddings")
+	defer apm.EndTransaction(span)
+
+	req := new(request.Embedding)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	payload := request.ToCoreEmbeddingRequest(*req)
+	payload.Role = c.Get(authguard.RoleAttr).(string)
+	if serviceName, ok := c.Get("service").(string); ok {
+		payload.ServiceName = serviceName
+	} else {
+		payload.UserID = c.Get(authguard.UserAttr).(authguard.JwtClaims).Email
+	}
+
+	longCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	res, err := h.service.Embeddings(longCtx, payload)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewEmbeddingListResponse(res, req.EncodingFormat))
+}
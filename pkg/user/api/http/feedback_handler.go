@@ -0,0 +1,89 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+// SubmitFeedbackHandler rates an answer with thumbs up or down and an optional comment
+func (h *Handler) SubmitFeedbackHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::SubmitFeedback")
+	defer apm.EndTransaction(span)
+
+	req := new(request.Feedback)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	feedback, err := h.service.SubmitFeedback(ctx, core.FeedbackRequest{
+		MessageID: c.Param("id"),
+		Rater:     caller(c),
+		Rating:    req.Rating,
+		Comment:   req.Comment,
+	})
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewFeedbackResponse(feedback))
+}
+
+// FeedbackReportHandler aggregates ratings by model, persona, template or service
+func (h *Handler) FeedbackReportHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::FeedbackReport")
+	defer apm.EndTransaction(span)
+
+	req := new(request.FeedbackReport)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Query"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	from, to := reportRange(req.From, req.To)
+	reports, err := h.service.FeedbackReport(ctx, req.GroupBy, from, to)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewFeedbackReportResponse(reports))
+}
+
+// ExportFeedbackHandler exports rated answers with their conversations as JSONL, for evaluation datasets
+func (h *Handler) ExportFeedbackHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ExportFeedback")
+	defer apm.EndTransaction(span)
+
+	req := new(request.FeedbackExport)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Query"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	from, to := reportRange(req.From, req.To)
+	exchanges, err := h.service.ExportFeedback(ctx, from, to, req.Rating)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	filename := fmt.Sprintf("feedback-%s-%s.jsonl", from.Format(reportDateLayout), to.AddDate(0, 0, -1).Format(reportDateLayout))
+	c.Response().Header().Set(echo.HeaderContentType, "application/jsonl")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+	return response.WriteRatedExchangesJSONL(c.Response(), exchanges)
+}
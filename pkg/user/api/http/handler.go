@@ -2,7 +2,9 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/abialemuel/AI-Proxy-Service/config"
@@ -19,20 +21,25 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// headerCache reports whether a service prompt was served from the response cache
+const headerCache = "X-Cache"
+
 type Handler struct {
 	service        business.UserService
 	googleOauth    oauthmanager.OAuth2Provider
 	microsoftOauth oauthmanager.OAuth2Provider
 	config         *config.MainConfig
+	authGuard      *authguard.AuthGuard
 }
 
 // NewHandler Construct user API handler
-func NewHandler(service business.UserService, google oauthmanager.OAuth2Provider, microsoft oauthmanager.OAuth2Provider, cfg *config.MainConfig) *Handler {
+func NewHandler(service business.UserService, google oauthmanager.OAuth2Provider, microsoft oauthmanager.OAuth2Provider, cfg *config.MainConfig, authGuard *authguard.AuthGuard) *Handler {
 	return &Handler{
 		service,
 		google,
 		microsoft,
 		cfg,
+		authGuard,
 	}
 }
 
@@ -42,10 +49,15 @@ func (h *Handler) GetUser(c echo.Context) error {
 	// get user id from token
 	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
 
+	role := c.Get(authguard.RoleAttr).(string)
+
+	// record last seen, the UI calls this endpoint right after login
+	go h.service.TouchUser(context.Background(), jwtAtrr.Email, jwtAtrr.Name)
+
 	tokenInfo := h.service.GetUserTokenUsage(ctx, jwtAtrr.Email)
 
 	// return 200 with user data from jwt
-	return c.JSON(http.StatusOK, response.NewUserMeResponse(jwtAtrr, tokenInfo))
+	return c.JSON(http.StatusOK, response.NewUserMeResponse(jwtAtrr, role, tokenInfo))
 }
 
 // GPT4Handler handler for GPT4
@@ -69,11 +81,15 @@ func (h *Handler) UserGPT4Handler(c echo.Context) error {
 	defer cancel()
 
 	res, err := h.service.UserPromtGPT(longCtx, core.UserPromtGPTRequest{
-		UserID:  userID,
-		Content: request.ToCoreUserPromptGPTRequest(req.Content),
+		UserID:         userID,
+		Role:           c.Get(authguard.RoleAttr).(string),
+		Content:        request.ToCoreUserPromptGPTRequest(req.Content),
+		KnowledgeBases: req.KnowledgeBases,
+		Persona:        req.Persona,
+		Settings:       request.ToCoreModelSettings(*req),
 	})
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, common.NewValidationErrorResponse(err.Error()))
+		return h.errorResponse(c, err)
 	}
 
 	return c.JSON(http.StatusOK, response.NewUserPromGPTResponse(res))
@@ -94,27 +110,63 @@ func (h *Handler) ServiceGPT4Handler(c echo.Context) error {
 
 	// get service from context
 	serviceName := c.Get("service").(string)
-
-	// Create a new context with a longer timeout or no timeout
-	longCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
-	defer cancel()
-
-	res, err := h.service.ServicePrompt(longCtx, core.ServicePromptRequest{
+	payload := core.ServicePromptRequest{
 		ServiceName: serviceName,
+		Role:        c.Get(authguard.RoleAttr).(string),
 		Model:       req.Model,
 		Temperature: req.Temperature,
 		MaxTokens:   req.MaxTokens,
 		TopP:        req.TopP,
 		Messages:    request.ToCoreMessage(req.Messages),
-	})
+
+		KnowledgeBases: req.KnowledgeBases,
+		Persona:        req.Persona,
+
+		Template:  req.Template,
+		Variables: req.Variables,
+	}
+	payload.NoCache, payload.NoStore = parseCacheControl(c.Request().Header.Get(echo.HeaderCacheControl))
+
+	// callers that can't wait get the result POSTed to their callback_url
+	if req.CallbackURL != "" {
+		delivery, err := h.service.ServicePromptAsync(ctx, payload, req.CallbackURL)
+		if err != nil {
+			return h.errorResponse(c, err)
+		}
+		return c.JSON(http.StatusAccepted, response.NewServicePromptAcceptedResponse(delivery))
+	}
+
+	// Create a new context with a longer timeout or no timeout
+	longCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	res, err := h.service.ServicePrompt(longCtx, payload)
 
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, common.NewValidationErrorResponse(err.Error()))
+		return h.errorResponse(c, err)
 	}
 
+	if res.Cached {
+		c.Response().Header().Set(headerCache, "HIT")
+	} else {
+		c.Response().Header().Set(headerCache, "MISS")
+	}
 	return c.JSON(http.StatusOK, response.NewServicePromGPTResponse(res))
 }
 
+// parseCacheControl reports whether the request opts out of reading from and writing to the response cache
+func parseCacheControl(header string) (noCache bool, noStore bool) {
+	for _, directive := range strings.Split(header, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-cache":
+			noCache = true
+		case "no-store":
+			noStore = true
+		}
+	}
+	return noCache, noStore
+}
+
 // UserClearContextHandler handler for clearing context
 func (h *Handler) UserClearContextHandler(c echo.Context) error {
 	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ClearContext")
@@ -131,3 +183,25 @@ func (h *Handler) UserClearContextHandler(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, common.DefaultResponse{Code: 200, Message: "Context Cleared"})
 }
+
+// errorResponse maps business errors to their HTTP status
+func (h *Handler) errorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, core.ErrInvalidRequest):
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(err.Error()))
+	case errors.Is(err, core.ErrSignInRequired):
+		return c.JSON(http.StatusUnauthorized, common.NewUnauthorizedResponse(err.Error()))
+	case errors.Is(err, core.ErrModelNotAllowed), errors.Is(err, core.ErrCallbackNotAllowed), errors.Is(err, core.ErrShareNotAllowed):
+		return c.JSON(http.StatusForbidden, common.NewForbiddenResponse(err.Error()))
+	case errors.Is(err, core.ErrNotFound):
+		return c.JSON(http.StatusNotFound, common.NewNotFoundResponse(err.Error()))
+	case errors.Is(err, core.ErrQuotaExceeded):
+		return c.JSON(http.StatusTooManyRequests, common.NewTooManyRequestResponse(err.Error()))
+	case errors.Is(err, core.ErrContentFiltered):
+		return c.JSON(http.StatusUnprocessableEntity, common.NewContentFilteredResponse(err.Error()))
+	case errors.Is(err, core.ErrNotConfigured):
+		return c.JSON(http.StatusServiceUnavailable, common.NewServiceUnavailableResponse(err.Error()))
+	default:
+		return c.JSON(http.StatusInternalServerError, common.NewValidationErrorResponse(err.Error()))
+	}
+}
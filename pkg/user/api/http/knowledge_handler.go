@@ -0,0 +1,137 @@
+package http
+
+import (
+	"io"
+	"net/http"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+// CreateKnowledgeBaseHandler creates a knowledge base of the caller
+func (h *Handler) CreateKnowledgeBaseHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::CreateKnowledgeBase")
+	defer apm.EndTransaction(span)
+
+	req := new(request.KnowledgeBaseCreate)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	kb, err := h.service.CreateKnowledgeBase(ctx, caller(c), req.Name, req.Description)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewKnowledgeBaseResponse(kb))
+}
+
+// ListKnowledgeBasesHandler lists the knowledge bases of the caller
+func (h *Handler) ListKnowledgeBasesHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ListKnowledgeBases")
+	defer apm.EndTransaction(span)
+
+	kbs, err := h.service.ListKnowledgeBases(ctx, caller(c))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewKnowledgeBaseListResponse(kbs))
+}
+
+// GetKnowledgeBaseHandler returns a knowledge base of the caller
+func (h *Handler) GetKnowledgeBaseHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::GetKnowledgeBase")
+	defer apm.EndTransaction(span)
+
+	kb, err := h.service.GetKnowledgeBase(ctx, caller(c), c.Param("name"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewKnowledgeBaseResponse(kb))
+}
+
+// DeleteKnowledgeBaseHandler deletes a knowledge base with all of its documents
+func (h *Handler) DeleteKnowledgeBaseHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::DeleteKnowledgeBase")
+	defer apm.EndTransaction(span)
+
+	if err := h.service.DeleteKnowledgeBase(ctx, caller(c), c.Param("name")); err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, common.DefaultResponse{Code: 200, Message: "Knowledge Base Deleted"})
+}
+
+// UploadDocumentHandler uploads a text, markdown, HTML or PDF document into a knowledge base
+func (h *Handler) UploadDocumentHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::UploadDocument")
+	defer apm.EndTransaction(span)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("file is required"))
+	}
+	if fileHeader.Size > h.config.RAG.MaxDocumentBytes {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("file is too large"))
+	}
+	src, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(err.Error()))
+	}
+	defer src.Close()
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(err.Error()))
+	}
+
+	doc, err := h.service.UploadDocument(ctx, caller(c), c.Param("name"), fileHeader.Filename, fileHeader.Header.Get(echo.HeaderContentType), content)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusAccepted, response.NewDocumentAcceptedResponse(doc))
+}
+
+// ListDocumentsHandler lists the documents of a knowledge base with their ingestion status
+func (h *Handler) ListDocumentsHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ListDocuments")
+	defer apm.EndTransaction(span)
+
+	docs, err := h.service.ListDocuments(ctx, caller(c), c.Param("name"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewDocumentListResponse(docs))
+}
+
+// DeleteDocumentHandler deletes a document from a knowledge base
+func (h *Handler) DeleteDocumentHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::DeleteDocument")
+	defer apm.EndTransaction(span)
+
+	if err := h.service.DeleteDocument(ctx, caller(c), c.Param("name"), c.Param("id")); err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, common.DefaultResponse{Code: 200, Message: "Document Deleted"})
+}
+
+// caller returns the backend service name or the SSO user email of the request
+func caller(c echo.Context) string {
+	if serviceName, ok := c.Get("service").(string); ok {
+		return serviceName
+	}
+	return c.Get(authguard.UserAttr).(authguard.JwtClaims).Email
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+// AdminCreatePersonaHandler creates a persona, or a new version of it when the name exists
+func (h *Handler) AdminCreatePersonaHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminCreatePersona")
+	defer apm.EndTransaction(span)
+
+	req := new(request.PersonaCreate)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	payload := request.ToCorePersonaRequest(*req)
+	payload.CreatedBy = c.Get(authguard.UserAttr).(authguard.JwtClaims).Email
+	persona, err := h.service.CreatePersona(ctx, payload)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewPersonaResponse(persona))
+}
+
+// AdminListPersonaVersionsHandler lists every version of a persona
+func (h *Handler) AdminListPersonaVersionsHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminListPersonaVersions")
+	defer apm.EndTransaction(span)
+
+	personas, err := h.service.ListPersonaVersions(ctx, c.Param("name"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewPersonaListResponse(personas))
+}
+
+// ListPersonasHandler lists the latest version of every persona
+func (h *Handler) ListPersonasHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ListPersonas")
+	defer apm.EndTransaction(span)
+
+	personas, err := h.service.ListPersonas(ctx)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewPersonaListResponse(personas))
+}
+
+// GetPersonaHandler returns a persona, the ref is either a name or name@vN
+func (h *Handler) GetPersonaHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::GetPersona")
+	defer apm.EndTransaction(span)
+
+	persona, err := h.service.GetPersona(ctx, c.Param("ref"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewPersonaResponse(persona))
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+// AdminCreatePromptTemplateHandler registers a prompt template, or a new version of it when the name exists
+func (h *Handler) AdminCreatePromptTemplateHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminCreatePromptTemplate")
+	defer apm.EndTransaction(span)
+
+	req := new(request.PromptTemplateCreate)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	payload := request.ToCorePromptTemplateRequest(*req)
+	payload.CreatedBy = c.Get(authguard.UserAttr).(authguard.JwtClaims).Email
+	tmpl, err := h.service.CreatePromptTemplate(ctx, payload)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewPromptTemplateResponse(tmpl))
+}
+
+// AdminListPromptTemplateVersionsHandler lists every version of a prompt template
+func (h *Handler) AdminListPromptTemplateVersionsHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminListPromptTemplateVersions")
+	defer apm.EndTransaction(span)
+
+	templates, err := h.service.ListPromptTemplateVersions(ctx, c.Param("name"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewPromptTemplateListResponse(templates))
+}
+
+// ListPromptTemplatesHandler lists the latest version of every prompt template
+func (h *Handler) ListPromptTemplatesHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ListPromptTemplates")
+	defer apm.EndTransaction(span)
+
+	templates, err := h.service.ListPromptTemplates(ctx)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewPromptTemplateListResponse(templates))
+}
+
+// GetPromptTemplateHandler returns a prompt template, the ref is either a name or name@vN
+func (h *Handler) GetPromptTemplateHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::GetPromptTemplate")
+	defer apm.EndTransaction(span)
+
+	tmpl, err := h.service.GetPromptTemplate(ctx, c.Param("ref"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewPromptTemplateResponse(tmpl))
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	reportDateLayout  = "2006-01-02"
+	reportDefaultDays = 30
+)
+
+// UsageReportHandler aggregates the usage ledger, as JSON or CSV export
+func (h *Handler) UsageReportHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::UsageReport")
+	defer apm.EndTransaction(span)
+
+	req := new(request.UsageReport)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Query"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	from, to := reportRange(req.From, req.To)
+	reports, err := h.service.UsageReport(ctx, req.GroupBy, from, to)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, common.NewValidationErrorResponse(err.Error()))
+	}
+
+	if req.Format == "csv" {
+		filename := fmt.Sprintf("usage-%s-%s-%s.csv", req.GroupBy, from.Format(reportDateLayout), to.AddDate(0, 0, -1).Format(reportDateLayout))
+		c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+		c.Response().WriteHeader(http.StatusOK)
+		return response.WriteUsageReportCSV(c.Response(), req.GroupBy, reports)
+	}
+
+	return c.JSON(http.StatusOK, response.NewUsageReportResponse(reports))
+}
+
+// reportRange returns the [from, to) range of validated report dates, it defaults to the last 30 days and `to` is inclusive
+func reportRange(fromDate string, toDate string) (time.Time, time.Time) {
+	today := time.Now().Truncate(24 * time.Hour)
+	to := today.AddDate(0, 0, 1)
+	if toDate != "" {
+		parsed, _ := time.Parse(reportDateLayout, toDate)
+		to = parsed.AddDate(0, 0, 1)
+	}
+	from := to.AddDate(0, 0, -reportDefaultDays)
+	if fromDate != "" {
+		from, _ = time.Parse(reportDateLayout, fromDate)
+	}
+	return from, to
+}
@@ -0,0 +1,24 @@
+package request
+
+type AdminUserRole struct {
+	Role string `json:"role" validate:"required,oneof=admin user service"`
+}
+
+type AdminUserList struct {
+	Limit string `query:"limit"`
+	Page  string `query:"page"`
+}
+
+type AdminGrantQuota struct {
+	Tokens int `json:"tokens" validate:"required,min=1"`
+}
+
+type AdminAuditList struct {
+	Action string `query:"action"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type AdminRoleModels struct {
+	Role          string   `param:"role" validate:"required,oneof=admin user service"`
+	AllowedModels []string `json:"allowed_models" validate:"dive,required"`
+}
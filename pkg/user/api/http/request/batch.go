@@ -0,0 +1,25 @@
+package request
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+type BatchCreate struct {
+	InputFileID      string            `json:"input_file_id" validate:"required"`
+	Endpoint         string            `json:"endpoint" validate:"required"`
+	CompletionWindow string            `json:"completion_window" validate:"required"`
+	Metadata         map[string]string `json:"metadata"`
+	CallbackURL      string            `json:"callback_url" validate:"omitempty,url"`
+}
+
+type BatchList struct {
+	Limit int `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func ToCoreBatchRequest(req BatchCreate) core.BatchRequest {
+	return core.BatchRequest{
+		InputFileID:      req.InputFileID,
+		Endpoint:         req.Endpoint,
+		CompletionWindow: req.CompletionWindow,
+		Metadata:         req.Metadata,
+		CallbackURL:      req.CallbackURL,
+	}
+}
@@ -0,0 +1,108 @@
+package request
+
+import (
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+)
+
+// UserRegenerateRequest asks for another answer to the last prompt, every field is optional
+type UserRegenerateRequest struct {
+	KnowledgeBases []string `json:"knowledge_bases" validate:"max=5,dive,required"`
+	Persona        string   `json:"persona" validate:"max=80"`
+
+	// optional model parameters, kept for the rest of the conversation
+	Model       string   `json:"model" validate:"max=64"`
+	Temperature *float64 `json:"temperature" validate:"omitempty,gte=0,lte=2"`
+	TopP        *float64 `json:"top_p" validate:"omitempty,gt=0,lte=1"`
+	MaxTokens   *int     `json:"max_tokens" validate:"omitempty,min=1"`
+	Stop        []string `json:"stop" validate:"max=4,dive,required,max=64"`
+	Seed        *int     `json:"seed"`
+}
+
+type ConversationBranch struct {
+	MessageID string `json:"message_id" validate:"required,len=24,hexadecimal"`
+}
+
+func ToCoreRegenerateSettings(req UserRegenerateRequest) core.ModelSettings {
+	return core.ModelSettings{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		MaxTokens:   req.MaxTokens,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
+	}
+}
+
+type ConversationExport struct {
+	Format string `query:"format" validate:"omitempty,oneof=markdown json jsonl"`
+}
+
+// ConversationImport is a conversation in the JSON export format
+type ConversationImport struct {
+	Persona    string                `json:"persona" validate:"max=80"`
+	Settings   *ConversationSettings `json:"settings"`
+	ActiveLeaf string                `json:"active_leaf" validate:"max=64"`
+	Summaries  []ImportedSummary     `json:"summaries" validate:"max=100,dive"`
+	Messages   []ImportedMessage     `json:"messages" validate:"required,max=1000,dive"`
+	CreatedAt  time.Time             `json:"created_at"`
+}
+
+type ConversationSettings struct {
+	Model       string   `json:"model" validate:"max=64"`
+	Temperature *float64 `json:"temperature" validate:"omitempty,gte=0,lte=2"`
+	TopP        *float64 `json:"top_p" validate:"omitempty,gt=0,lte=1"`
+	MaxTokens   *int     `json:"max_tokens" validate:"omitempty,min=1"`
+	Stop        []string `json:"stop" validate:"max=4,dive,required,max=64"`
+	Seed        *int     `json:"seed"`
+}
+
+type ImportedMessage struct {
+	ID        string    `json:"id" validate:"max=64"`
+	ParentID  string    `json:"parent_id" validate:"max=64"`
+	Role      string    `json:"role" validate:"required,oneof=user assistant"`
+	Content   []Content `json:"content" validate:"required,dive"`
+	Model     string    `json:"model" validate:"max=64"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ImportedSummary struct {
+	Content []Content `json:"content" validate:"required,dive"`
+	UpTo    string    `json:"up_to" validate:"required,max=64"`
+}
+
+func ToCoreConversationImport(req ConversationImport) core.ConversationExport {
+	res := core.ConversationExport{
+		Persona:    req.Persona,
+		ActiveLeaf: req.ActiveLeaf,
+		CreatedAt:  req.CreatedAt,
+	}
+	if req.Settings != nil {
+		res.Settings = &core.ModelSettings{
+			Model:       req.Settings.Model,
+			Temperature: req.Settings.Temperature,
+			TopP:        req.Settings.TopP,
+			MaxTokens:   req.Settings.MaxTokens,
+			Stop:        req.Settings.Stop,
+			Seed:        req.Settings.Seed,
+		}
+	}
+	for _, v := range req.Summaries {
+		res.Summaries = append(res.Summaries, core.ExportedSummary{
+			Content: ToCoreUserPromptGPTRequest(v.Content),
+			UpTo:    v.UpTo,
+		})
+	}
+	for _, v := range req.Messages {
+		res.Messages = append(res.Messages, core.ExportedMessage{
+			ID:        v.ID,
+			ParentID:  v.ParentID,
+			Role:      v.Role,
+			Content:   ToCoreUserPromptGPTRequest(v.Content),
+			Model:     v.Model,
+			CreatedAt: v.CreatedAt,
+		})
+	}
+	return res
+}
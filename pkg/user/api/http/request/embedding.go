@@ -0,0 +1,42 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+)
+
+type Embedding struct {
+	Model          string         `json:"model"`
+	Input          EmbeddingInput `json:"input" validate:"required,min=1,max=2048,dive,required"`
+	Dimensions     *int           `json:"dimensions" validate:"omitempty,min=1"`
+	EncodingFormat string         `json:"encoding_format" validate:"omitempty,oneof=float base64"`
+}
+
+// EmbeddingInput accepts both a single string and an array of strings
+type EmbeddingInput []string
+
+func (e *EmbeddingInput) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*e = EmbeddingInput{text}
+		return nil
+	}
+
+	var texts []string
+	if err := json.Unmarshal(data, &texts); err != nil {
+		return errors.New("input must be a string or an array of strings")
+	}
+	*e = texts
+	return nil
+}
+
+func ToCoreEmbeddingRequest(req Embedding) core.EmbeddingRequest {
+	return core.EmbeddingRequest{
+		Model:          req.Model,
+		Input:          req.Input,
+		Dimensions:     req.Dimensions,
+		EncodingFormat: req.EncodingFormat,
+	}
+}
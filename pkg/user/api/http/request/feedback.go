@@ -0,0 +1,18 @@
+package request
+
+type Feedback struct {
+	Rating  string `json:"rating" validate:"required,oneof=up down"`
+	Comment string `json:"comment" validate:"max=2000"`
+}
+
+type FeedbackReport struct {
+	GroupBy string `query:"group_by" validate:"required,oneof=model persona template service"`
+	From    string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To      string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type FeedbackExport struct {
+	Rating string `query:"rating" validate:"omitempty,oneof=up down"`
+	From   string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To     string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
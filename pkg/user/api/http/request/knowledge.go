@@ -0,0 +1,6 @@
+package request
+
+type KnowledgeBaseCreate struct {
+	Name        string `json:"name" validate:"required,max=64,excludesall=/ "`
+	Description string `json:"description" validate:"max=1024"`
+}
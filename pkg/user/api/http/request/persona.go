@@ -0,0 +1,25 @@
+package request
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+type PersonaCreate struct {
+	Name         string   `json:"name" validate:"required,max=64,excludesall=@/ "`
+	Description  string   `json:"description" validate:"max=1024"`
+	SystemPrompt string   `json:"system_prompt" validate:"required"`
+	Model        string   `json:"model"`
+	Temperature  *float64 `json:"temperature" validate:"omitempty,gte=0,lte=2"`
+	TopP         *float64 `json:"top_p" validate:"omitempty,gt=0,lte=1"`
+	MaxTokens    *int     `json:"max_tokens" validate:"omitempty,min=1"`
+}
+
+func ToCorePersonaRequest(req PersonaCreate) core.PersonaRequest {
+	return core.PersonaRequest{
+		Name:         req.Name,
+		Description:  req.Description,
+		SystemPrompt: req.SystemPrompt,
+		Model:        req.Model,
+		Temperature:  req.Temperature,
+		TopP:         req.TopP,
+		MaxTokens:    req.MaxTokens,
+	}
+}
@@ -0,0 +1,43 @@
+package request
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+type PromptTemplateCreate struct {
+	Name        string             `json:"name" validate:"required,max=64,excludesall=@/ "`
+	Description string             `json:"description" validate:"max=1024"`
+	Messages    []TemplateMessage  `json:"messages" validate:"required,min=1,dive"`
+	Variables   []TemplateVariable `json:"variables" validate:"dive"`
+}
+
+type TemplateMessage struct {
+	Role     string `json:"role" validate:"required,oneof=system user assistant"`
+	Template string `json:"template" validate:"required"`
+}
+
+type TemplateVariable struct {
+	Name        string      `json:"name" validate:"required,max=64"`
+	Type        string      `json:"type" validate:"required,oneof=string number integer boolean array object"`
+	Required    bool        `json:"required"`
+	Default     interface{} `json:"default"`
+	Description string      `json:"description" validate:"max=1024"`
+}
+
+func ToCorePromptTemplateRequest(req PromptTemplateCreate) core.PromptTemplateRequest {
+	res := core.PromptTemplateRequest{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	for _, m := range req.Messages {
+		res.Messages = append(res.Messages, core.TemplateMessage{Role: m.Role, Template: m.Template})
+	}
+	for _, v := range req.Variables {
+		res.Variables = append(res.Variables, core.TemplateVariable{
+			Name:        v.Name,
+			Type:        v.Type,
+			Required:    v.Required,
+			Default:     v.Default,
+			Description: v.Description,
+		})
+	}
+	return res
+}
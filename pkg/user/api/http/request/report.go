@@ -0,0 +1,8 @@
+package request
+
+type UsageReport struct {
+	GroupBy string `query:"group_by" validate:"required,oneof=day user tribe service model"`
+	From    string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To      string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+	Format  string `query:"format" validate:"omitempty,oneof=json csv"`
+}
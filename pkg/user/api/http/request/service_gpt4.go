@@ -3,16 +3,23 @@ package request
 import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
 
 type ServicePromptGPTRequest struct {
-	Model       string    `json:"model" validate:"required"`
-	Temperature float64   `json:"temperature" validate:"required"`
-	MaxTokens   int       `json:"max_tokens" validate:"required"`
-	TopP        float64   `json:"top_p" validate:"required"`
-	Messages    []Message `json:"messages" validate:"required"`
+	Model       string    `json:"model"` // may be left to the persona
+	Temperature *float64  `json:"temperature" validate:"omitempty,gte=0,lte=2"`
+	MaxTokens   int       `json:"max_tokens" validate:"omitempty,min=1"`
+	TopP        float64   `json:"top_p" validate:"omitempty,gt=0,lte=1"`
+	Messages    []Message `json:"messages" validate:"required_without=Template,dive"`
+	CallbackURL string    `json:"callback_url" validate:"omitempty,url"`
+
+	KnowledgeBases []string `json:"knowledge_bases" validate:"max=5,dive,required"`
+	Persona        string   `json:"persona" validate:"max=80"`
+
+	Template  string                 `json:"template" validate:"max=80"`
+	Variables map[string]interface{} `json:"variables"`
 }
 
 type Message struct {
 	Role    string    `json:"role" validate:"required"`
-	Content []Content `json:"content" validate:"required"`
+	Content []Content `json:"content" validate:"required,dive"`
 }
 
 func ToCoreMessage(req []Message) (res []core.MessageRequest) {
@@ -34,7 +41,8 @@ func ToCoreContent(req []Content) (res []core.Content) {
 		}
 		if v.ImageURL != nil {
 			content.ImageURL = &core.ImageURL{
-				URL: v.ImageURL.URL,
+				URL:    v.ImageURL.URL,
+				Detail: v.ImageURL.Detail,
 			}
 		}
 		res = append(res, content)
@@ -0,0 +1,6 @@
+package request
+
+type ShareCreate struct {
+	ExpiresIn  int  `json:"expires_in" validate:"omitempty,min=60,max=31536000"` // seconds, unset for a share that doesn't expire
+	SameDomain bool `json:"same_domain"`
+}
@@ -14,7 +14,17 @@ import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
 //	                        "url": "data:image/jpeg;base64,/9j/4AAQSkZJRgABAQAAAQABAAD/4gHYS"
 //						}}
 type UserPromptGPTRequest struct {
-	Content []Content `json:"content" validate:"required"`
+	Content        []Content `json:"content" validate:"required,dive"`
+	KnowledgeBases []string  `json:"knowledge_bases" validate:"max=5,dive,required"`
+	Persona        string    `json:"persona" validate:"max=80"`
+
+	// optional model parameters, kept for the rest of the conversation
+	Model       string   `json:"model" validate:"max=64"`
+	Temperature *float64 `json:"temperature" validate:"omitempty,gte=0,lte=2"`
+	TopP        *float64 `json:"top_p" validate:"omitempty,gt=0,lte=1"`
+	MaxTokens   *int     `json:"max_tokens" validate:"omitempty,min=1"`
+	Stop        []string `json:"stop" validate:"max=4,dive,required,max=64"`
+	Seed        *int     `json:"seed"`
 }
 
 type Content struct {
@@ -24,7 +34,8 @@ type Content struct {
 }
 
 type ImageURL struct {
-	URL string `json:"url"`
+	URL    string `json:"url" validate:"required"`
+	Detail string `json:"detail" validate:"omitempty,oneof=auto low high"`
 }
 
 func ToCoreUserPromptGPTRequest(req []Content) (res []core.Content) {
@@ -36,7 +47,8 @@ func ToCoreUserPromptGPTRequest(req []Content) (res []core.Content) {
 		}
 		if v.ImageURL != nil {
 			content.ImageURL = &core.ImageURL{
-				URL: v.ImageURL.URL,
+				URL:    v.ImageURL.URL,
+				Detail: v.ImageURL.Detail,
 			}
 		}
 		res = append(res, content)
@@ -44,3 +56,14 @@ func ToCoreUserPromptGPTRequest(req []Content) (res []core.Content) {
 	return res
 
 }
+
+func ToCoreModelSettings(req UserPromptGPTRequest) core.ModelSettings {
+	return core.ModelSettings{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		MaxTokens:   req.MaxTokens,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
+	}
+}
@@ -0,0 +1,5 @@
+package request
+
+type WebhookDeliveryList struct {
+	Limit int `query:"limit" validate:"omitempty,min=1,max=100"`
+}
@@ -0,0 +1,109 @@
+package response
+
+import (
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/pagination"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+)
+
+type AdminUser struct {
+	Email      string    `json:"email"`
+	Name       string    `json:"name"`
+	Role       string    `json:"role"`
+	Blocked    bool      `json:"blocked"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	TokenLimit int       `json:"token_limit"`
+	TokenUsage int       `json:"token_usage"`
+	Warning    bool      `json:"warning"`
+}
+
+type AdminUserResponse struct {
+	Code    int       `json:"code"`
+	Message string    `json:"message"`
+	Payload AdminUser `json:"payload"`
+}
+
+type AdminUserListResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Payload []AdminUser     `json:"payload"`
+	Meta    pagination.Meta `json:"meta"`
+}
+
+func toAdminUser(v core.UserDetail) AdminUser {
+	return AdminUser{
+		Email:      v.Email,
+		Name:       v.Name,
+		Role:       v.Role,
+		Blocked:    v.Blocked,
+		LastSeenAt: v.LastSeenAt,
+		TokenLimit: v.Usage.TokenLimit,
+		TokenUsage: v.Usage.TokenUsage,
+		Warning:    v.Usage.Warning,
+	}
+}
+
+func NewAdminUserResponse(v core.UserDetail) *AdminUserResponse {
+	var ResultResponse AdminUserResponse
+	ResultResponse.Code = 200
+	ResultResponse.Message = "Success"
+	ResultResponse.Payload = toAdminUser(v)
+	return &ResultResponse
+}
+
+func NewAdminUserListResponse(v []core.UserDetail, meta pagination.Meta) *AdminUserListResponse {
+	var ResultResponse AdminUserListResponse
+	payload := []AdminUser{}
+	for _, user := range v {
+		payload = append(payload, toAdminUser(user))
+	}
+
+	ResultResponse.Code = 200
+	ResultResponse.Message = "Success"
+	ResultResponse.Payload = payload
+	ResultResponse.Meta = meta
+	return &ResultResponse
+}
+
+type AdminAuditListResponse struct {
+	Code    int               `json:"code"`
+	Message string            `json:"message"`
+	Payload []core.AuditEntry `json:"payload"`
+}
+
+func NewAdminAuditListResponse(v []core.AuditEntry) *AdminAuditListResponse {
+	return &AdminAuditListResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+type AdminServiceKeyResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Payload core.ServiceKey `json:"payload"`
+}
+
+func NewAdminServiceKeyResponse(v core.ServiceKey) *AdminServiceKeyResponse {
+	return &AdminServiceKeyResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+type AdminRoleModelsResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Payload core.RoleModels `json:"payload"`
+}
+
+func NewAdminRoleModelsResponse(v core.RoleModels) *AdminRoleModelsResponse {
+	return &AdminRoleModelsResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
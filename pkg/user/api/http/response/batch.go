@@ -0,0 +1,137 @@
+package response
+
+import (
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+)
+
+// File and Batch follow the OpenAI object format so OpenAI clients can be pointed at the proxy
+
+type File struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+}
+
+type BatchErrors struct {
+	Object string       `json:"object"`
+	Data   []BatchError `json:"data"`
+}
+
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type Batch struct {
+	ID               string             `json:"id"`
+	Object           string             `json:"object"`
+	Endpoint         string             `json:"endpoint"`
+	Errors           *BatchErrors       `json:"errors"`
+	InputFileID      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	OutputFileID     *string            `json:"output_file_id"`
+	ErrorFileID      *string            `json:"error_file_id"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     *int64             `json:"in_progress_at"`
+	CompletedAt      *int64             `json:"completed_at"`
+	FailedAt         *int64             `json:"failed_at"`
+	ExpiredAt        *int64             `json:"expired_at"`
+	CancellingAt     *int64             `json:"cancelling_at"`
+	CancelledAt      *int64             `json:"cancelled_at"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata"`
+	CallbackURL      *string            `json:"callback_url,omitempty"`
+}
+
+type BatchList struct {
+	Object  string  `json:"object"`
+	Data    []Batch `json:"data"`
+	FirstID *string `json:"first_id"`
+	LastID  *string `json:"last_id"`
+	HasMore bool    `json:"has_more"`
+}
+
+func NewFileResponse(v core.File) *File {
+	return &File{
+		ID:        v.ID,
+		Object:    "file",
+		Bytes:     v.Bytes,
+		CreatedAt: v.CreatedAt.Unix(),
+		Filename:  v.Filename,
+		Purpose:   v.Purpose,
+	}
+}
+
+func NewBatchResponse(v core.Batch) *Batch {
+	res := Batch{
+		ID:               v.ID,
+		Object:           "batch",
+		Endpoint:         v.Endpoint,
+		InputFileID:      v.InputFileID,
+		CompletionWindow: v.CompletionWindow,
+		Status:           v.Status,
+		OutputFileID:     optionalString(v.OutputFileID),
+		ErrorFileID:      optionalString(v.ErrorFileID),
+		CreatedAt:        v.CreatedAt.Unix(),
+		InProgressAt:     optionalUnix(v.InProgressAt),
+		CompletedAt:      optionalUnix(v.CompletedAt),
+		FailedAt:         optionalUnix(v.FailedAt),
+		ExpiredAt:        optionalUnix(v.ExpiredAt),
+		CancellingAt:     optionalUnix(v.CancellingAt),
+		CancelledAt:      optionalUnix(v.CancelledAt),
+		RequestCounts: BatchRequestCounts{
+			Total:     v.RequestCounts.Total,
+			Completed: v.RequestCounts.Completed,
+			Failed:    v.RequestCounts.Failed,
+		},
+		Metadata:    v.Metadata,
+		CallbackURL: optionalString(v.CallbackURL),
+	}
+	if len(v.Errors) > 0 {
+		res.Errors = &BatchErrors{Object: "list"}
+		for _, msg := range v.Errors {
+			res.Errors.Data = append(res.Errors.Data, BatchError{Code: "invalid_request", Message: msg})
+		}
+	}
+	return &res
+}
+
+func NewBatchListResponse(v []core.Batch, limit int) *BatchList {
+	res := BatchList{Object: "list", Data: []Batch{}}
+	for _, batch := range v {
+		res.Data = append(res.Data, *NewBatchResponse(batch))
+	}
+	if len(res.Data) > 0 {
+		res.FirstID = &res.Data[0].ID
+		res.LastID = &res.Data[len(res.Data)-1].ID
+	}
+	res.HasMore = len(res.Data) == limit
+	return &res
+}
+
+func optionalString(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func optionalUnix(v *time.Time) *int64 {
+	if v == nil {
+		return nil
+	}
+	unix := v.Unix()
+	return &unix
+}
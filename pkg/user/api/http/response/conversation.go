@@ -0,0 +1,106 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+)
+
+type ConversationResponse struct {
+	Code    int                   `json:"code"`
+	Message string                `json:"message"`
+	Payload core.ConversationTree `json:"payload"`
+}
+
+func NewConversationResponse(v core.ConversationTree) *ConversationResponse {
+	return &ConversationResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+// WriteConversationsMarkdown writes the current branch of every conversation as a Markdown transcript
+func WriteConversationsMarkdown(w io.Writer, v []core.ConversationExport) error {
+	var b strings.Builder
+	for i, conversation := range v {
+		if i > 0 {
+			b.WriteString("\n---\n\n")
+		}
+		fmt.Fprintf(&b, "# Conversation %s\n\n", conversation.ID)
+		fmt.Fprintf(&b, "_Started %s", conversation.CreatedAt.Format(time.RFC3339))
+		if conversation.Persona != "" {
+			fmt.Fprintf(&b, " with persona %s", conversation.Persona)
+		}
+		b.WriteString("_\n")
+
+		for _, message := range conversation.ActivePath() {
+			fmt.Fprintf(&b, "\n## %s\n\n", markdownRole(message.Role))
+			images := 0
+			for _, c := range message.Content {
+				switch {
+				case c.Text != nil:
+					fmt.Fprintf(&b, "%s\n", *c.Text)
+				case c.ImageURL != nil:
+					images++
+					fmt.Fprintf(&b, "![image %d](%s)\n", images, c.ImageURL.URL)
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownRole(role string) string {
+	if role == "assistant" {
+		return "Assistant"
+	}
+	return "User"
+}
+
+// fineTuneMessage is a message of the OpenAI chat fine-tuning format, content is a string unless it holds images
+type fineTuneMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type fineTuneContent struct {
+	Type     string         `json:"type"`
+	Text     *string        `json:"text,omitempty"`
+	ImageURL *core.ImageURL `json:"image_url,omitempty"`
+}
+
+// WriteConversationsFineTuneJSONL writes the current branch of every conversation as one line of `messages`
+func WriteConversationsFineTuneJSONL(w io.Writer, v []core.ConversationExport) error {
+	encoder := json.NewEncoder(w)
+	for _, conversation := range v {
+		messages := []fineTuneMessage{}
+		for _, message := range conversation.ActivePath() {
+			messages = append(messages, fineTuneMessage{Role: message.Role, Content: fineTuneMessageContent(message.Content)})
+		}
+		if err := encoder.Encode(map[string]interface{}{"messages": messages}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fineTuneMessageContent(content []core.Content) interface{} {
+	var texts []string
+	parts := []fineTuneContent{}
+	for _, c := range content {
+		if c.Text != nil {
+			texts = append(texts, *c.Text)
+		}
+		parts = append(parts, fineTuneContent{Type: c.Type, Text: c.Text, ImageURL: c.ImageURL})
+	}
+	if len(texts) == len(content) {
+		return strings.Join(texts, "\n")
+	}
+	return parts
+}
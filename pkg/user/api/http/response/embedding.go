@@ -0,0 +1,42 @@
+package response
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+// EmbeddingList follows the OpenAI embeddings format so OpenAI clients can be pointed at the proxy
+type EmbeddingList struct {
+	Object string         `json:"object"`
+	Data   []Embedding    `json:"data"`
+	Model  string         `json:"model"`
+	Usage  EmbeddingUsage `json:"usage"`
+}
+
+type Embedding struct {
+	Object    string      `json:"object"`
+	Index     int         `json:"index"`
+	Embedding interface{} `json:"embedding"` // []float32, or a string for the base64 encoding format
+}
+
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+func NewEmbeddingListResponse(v core.EmbeddingResponse, encodingFormat string) *EmbeddingList {
+	res := EmbeddingList{
+		Object: "list",
+		Data:   []Embedding{},
+		Model:  v.Model,
+		Usage: EmbeddingUsage{
+			PromptTokens: v.Usage.PromptTokens,
+			TotalTokens:  v.Usage.TotalTokens,
+		},
+	}
+	for _, e := range v.Data {
+		embedding := Embedding{Object: "embedding", Index: e.Index, Embedding: e.Vector}
+		if encodingFormat == core.EmbeddingEncodingBase64 {
+			embedding.Embedding = e.Base64
+		}
+		res.Data = append(res.Data, embedding)
+	}
+	return &res
+}
@@ -0,0 +1,47 @@
+package response
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+)
+
+type FeedbackResponse struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Payload core.Feedback `json:"payload"`
+}
+
+func NewFeedbackResponse(v core.Feedback) *FeedbackResponse {
+	return &FeedbackResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+type FeedbackReportResponse struct {
+	Code    int                   `json:"code"`
+	Message string                `json:"message"`
+	Payload []core.FeedbackReport `json:"payload"`
+}
+
+func NewFeedbackReportResponse(v []core.FeedbackReport) *FeedbackReportResponse {
+	return &FeedbackReportResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+// WriteRatedExchangesJSONL writes one rated exchange per line
+func WriteRatedExchangesJSONL(w io.Writer, v []core.RatedExchange) error {
+	encoder := json.NewEncoder(w)
+	for _, row := range v {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
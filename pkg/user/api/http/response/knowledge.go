@@ -0,0 +1,60 @@
+package response
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+type KnowledgeBaseResponse struct {
+	Code    int                `json:"code"`
+	Message string             `json:"message"`
+	Payload core.KnowledgeBase `json:"payload"`
+}
+
+func NewKnowledgeBaseResponse(v core.KnowledgeBase) *KnowledgeBaseResponse {
+	return &KnowledgeBaseResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+type KnowledgeBaseListResponse struct {
+	Code    int                  `json:"code"`
+	Message string               `json:"message"`
+	Payload []core.KnowledgeBase `json:"payload"`
+}
+
+func NewKnowledgeBaseListResponse(v []core.KnowledgeBase) *KnowledgeBaseListResponse {
+	return &KnowledgeBaseListResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+type DocumentResponse struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Payload core.Document `json:"payload"`
+}
+
+// NewDocumentAcceptedResponse is returned on upload, the document is embedded in the background
+func NewDocumentAcceptedResponse(v core.Document) *DocumentResponse {
+	return &DocumentResponse{
+		Code:    202,
+		Message: "Accepted",
+		Payload: v,
+	}
+}
+
+type DocumentListResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Payload []core.Document `json:"payload"`
+}
+
+func NewDocumentListResponse(v []core.Document) *DocumentListResponse {
+	return &DocumentListResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
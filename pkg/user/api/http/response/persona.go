@@ -0,0 +1,31 @@
+package response
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+type PersonaResponse struct {
+	Code    int          `json:"code"`
+	Message string       `json:"message"`
+	Payload core.Persona `json:"payload"`
+}
+
+func NewPersonaResponse(v core.Persona) *PersonaResponse {
+	return &PersonaResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+type PersonaListResponse struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	Payload []core.Persona `json:"payload"`
+}
+
+func NewPersonaListResponse(v []core.Persona) *PersonaListResponse {
+	return &PersonaListResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
@@ -0,0 +1,31 @@
+package response
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+type PromptTemplateResponse struct {
+	Code    int                 `json:"code"`
+	Message string              `json:"message"`
+	Payload core.PromptTemplate `json:"payload"`
+}
+
+func NewPromptTemplateResponse(v core.PromptTemplate) *PromptTemplateResponse {
+	return &PromptTemplateResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+type PromptTemplateListResponse struct {
+	Code    int                   `json:"code"`
+	Message string                `json:"message"`
+	Payload []core.PromptTemplate `json:"payload"`
+}
+
+func NewPromptTemplateListResponse(v []core.PromptTemplate) *PromptTemplateListResponse {
+	return &PromptTemplateListResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
@@ -0,0 +1,43 @@
+package response
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+)
+
+type UsageReportResponse struct {
+	Code    int                `json:"code"`
+	Message string             `json:"message"`
+	Payload []core.UsageReport `json:"payload"`
+}
+
+func NewUsageReportResponse(v []core.UsageReport) *UsageReportResponse {
+	var ResultResponse UsageReportResponse
+	ResultResponse.Code = 200
+	ResultResponse.Message = "Success"
+	ResultResponse.Payload = v
+	return &ResultResponse
+}
+
+// WriteUsageReportCSV writes the report as CSV with a header row
+func WriteUsageReportCSV(w io.Writer, groupBy string, v []core.UsageReport) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{groupBy, "requests", "errors", "prompt_tokens", "completion_tokens", "total_tokens", "cost", "avg_latency_ms"})
+	for _, row := range v {
+		writer.Write([]string{
+			row.Key,
+			strconv.Itoa(row.Requests),
+			strconv.Itoa(row.Errors),
+			strconv.Itoa(row.PromptTokens),
+			strconv.Itoa(row.CompletionTokens),
+			strconv.Itoa(row.TotalTokens),
+			strconv.FormatFloat(row.Cost, 'f', 6, 64),
+			strconv.FormatFloat(row.AvgLatencyMs, 'f', 0, 64),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,17 @@
+package response
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+type ErasureResponse struct {
+	Code    int                `json:"code"`
+	Message string             `json:"message"`
+	Payload core.ErasureReport `json:"payload"`
+}
+
+func NewErasureResponse(v core.ErasureReport) *ErasureResponse {
+	return &ErasureResponse{
+		Code:    200,
+		Message: "Data Erased",
+		Payload: v,
+	}
+}
@@ -3,10 +3,17 @@ package response
 import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
 
 type ServicePromGPT struct {
-	Model       string  `json:"model"`
-	Temperature float64 `json:"temperature"`
-	Usage       Usage   `json:"usage"`
-	Content     string  `json:"content"`
+	Model       string          `json:"model"`
+	Temperature float64         `json:"temperature"`
+	Usage       Usage           `json:"usage"`
+	Content     string          `json:"content"`
+	Cached      bool            `json:"cached"`
+	Similarity  float64         `json:"similarity,omitempty"`
+	Citations   []core.Citation `json:"citations,omitempty"`
+	Redactions  []string        `json:"redactions,omitempty"`
+
+	Inspection *core.PromptInspection `json:"inspection,omitempty"`
+	MessageID  string                 `json:"message_id,omitempty"`
 }
 
 type Usage struct {
@@ -30,6 +37,12 @@ func NewServicePromGPTResponse(v core.ServicePromGPTResponse) *ServicePromGPTRes
 			PromptTokens:     v.GPT4PromptResponse.Usage.PromptTokens,
 			TotalTokens:      v.GPT4PromptResponse.Usage.TotalTokens,
 		},
+		Cached:     v.Cached,
+		Similarity: v.Similarity,
+		Citations:  v.Citations,
+		Redactions: v.Redactions,
+		Inspection: v.Inspection,
+		MessageID:  v.MessageID,
 	}
 
 	ResultResponse.Code = 200
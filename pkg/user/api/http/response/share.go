@@ -0,0 +1,45 @@
+package response
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+type ShareResponse struct {
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Payload core.Share `json:"payload"`
+}
+
+func NewShareResponse(v core.Share) *ShareResponse {
+	return &ShareResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+type ShareListResponse struct {
+	Code    int          `json:"code"`
+	Message string       `json:"message"`
+	Payload []core.Share `json:"payload"`
+}
+
+func NewShareListResponse(v []core.Share) *ShareListResponse {
+	return &ShareListResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+type SharedConversationResponse struct {
+	Code    int                     `json:"code"`
+	Message string                  `json:"message"`
+	Payload core.SharedConversation `json:"payload"`
+}
+
+func NewSharedConversationResponse(v core.SharedConversation) *SharedConversationResponse {
+	return &SharedConversationResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
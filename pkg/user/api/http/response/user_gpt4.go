@@ -8,7 +8,15 @@ import (
 )
 
 type UserPromGPT struct {
-	Content string `json:"content"`
+	Model      string          `json:"model"`
+	Content    string          `json:"content"`
+	Cached     bool            `json:"cached"`
+	Similarity float64         `json:"similarity,omitempty"`
+	Citations  []core.Citation `json:"citations,omitempty"`
+	Redactions []string        `json:"redactions,omitempty"`
+
+	PromptMessageID string `json:"prompt_message_id,omitempty"`
+	MessageID       string `json:"message_id,omitempty"`
 }
 
 type Token struct {
@@ -50,13 +58,14 @@ type UserMe struct {
 	Email      string    `json:"email"`
 	Name       string    `json:"name"`
 	Picture    string    `json:"picture"`
+	Role       string    `json:"role"`
 	ExpiresAt  time.Time `json:"expires_at"`
 	TokenLimit int       `json:"token_limit"`
 	TokenUsage int       `json:"token_usage"`
 	Warning    bool      `json:"warning"`
 }
 
-func NewUserMeResponse(v authguard.JwtClaims, tokenInfo core.UserTokenUsage) *UserMeResponse {
+func NewUserMeResponse(v authguard.JwtClaims, role string, tokenInfo core.UserTokenUsage) *UserMeResponse {
 	var ResultResponse UserMeResponse
 	payload := UserMe{
 		Issuer:     v.Issuer,
@@ -64,6 +73,7 @@ func NewUserMeResponse(v authguard.JwtClaims, tokenInfo core.UserTokenUsage) *Us
 		Email:      v.Email,
 		Name:       v.Name,
 		Picture:    v.Picture,
+		Role:       role,
 		ExpiresAt:  time.Unix(v.ExpiresAt.Unix(), 0),
 		TokenLimit: tokenInfo.TokenLimit,
 		TokenUsage: tokenInfo.TokenUsage,
@@ -79,7 +89,15 @@ func NewUserMeResponse(v authguard.JwtClaims, tokenInfo core.UserTokenUsage) *Us
 func NewUserPromGPTResponse(v core.UserPromGPTResponse) *UserPromGPTResponse {
 	var ResultResponse UserPromGPTResponse
 	payload := UserPromGPT{
-		Content: v.GPT4PromptResponse.Choices[0].Message.Content,
+		Model:      v.GPT4PromptResponse.Model,
+		Content:    v.GPT4PromptResponse.Choices[0].Message.Content,
+		Cached:     v.Cached,
+		Similarity: v.Similarity,
+		Citations:  v.Citations,
+		Redactions: v.Redactions,
+
+		PromptMessageID: v.PromptMessageID,
+		MessageID:       v.MessageID,
 	}
 
 	ResultResponse.Code = 200
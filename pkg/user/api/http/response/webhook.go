@@ -0,0 +1,55 @@
+package response
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+type ServicePromptAccepted struct {
+	DeliveryID    string `json:"delivery_id"`
+	CorrelationID string `json:"correlation_id"`
+	Status        string `json:"status"`
+}
+
+type ServicePromptAcceptedResponse struct {
+	Code    int                   `json:"code"`
+	Message string                `json:"message"`
+	Payload ServicePromptAccepted `json:"payload"`
+}
+
+func NewServicePromptAcceptedResponse(v core.WebhookDelivery) *ServicePromptAcceptedResponse {
+	return &ServicePromptAcceptedResponse{
+		Code:    202,
+		Message: "Accepted",
+		Payload: ServicePromptAccepted{
+			DeliveryID:    v.ID,
+			CorrelationID: v.CorrelationID,
+			Status:        v.Status,
+		},
+	}
+}
+
+type WebhookDeliveryResponse struct {
+	Code    int                  `json:"code"`
+	Message string               `json:"message"`
+	Payload core.WebhookDelivery `json:"payload"`
+}
+
+func NewWebhookDeliveryResponse(v core.WebhookDelivery) *WebhookDeliveryResponse {
+	return &WebhookDeliveryResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
+
+type WebhookDeliveryListResponse struct {
+	Code    int                    `json:"code"`
+	Message string                 `json:"message"`
+	Payload []core.WebhookDelivery `json:"payload"`
+}
+
+func NewWebhookDeliveryListResponse(v []core.WebhookDelivery) *WebhookDeliveryListResponse {
+	return &WebhookDeliveryListResponse{
+		Code:    200,
+		Message: "Success",
+		Payload: v,
+	}
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+// EraseMyDataHandler deletes everything kept about the user, usage is only kept under a pseudonym
+func (h *Handler) EraseMyDataHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::EraseMyData")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	report, err := h.service.EraseUserData(ctx, jwtAtrr.Email, "")
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewErasureResponse(report))
+}
+
+// AdminEraseUserDataHandler deletes everything kept about a user on their behalf
+func (h *Handler) AdminEraseUserDataHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::AdminEraseUserData")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	report, err := h.service.EraseUserData(ctx, c.Param("email"), jwtAtrr.Email)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewErasureResponse(report))
+}
@@ -19,9 +19,90 @@ func RegisterPath(e *echo.Echo, h *Handler, authGuard *authguard.AuthGuard) {
 	e.GET("v1/auth/refresh", h.RefreshTokenHandler)
 
 	e.GET("v1/users/me", h.GetUser, authGuard.Bearer)
+	e.DELETE("v1/users/me/data", h.EraseMyDataHandler, authGuard.Bearer)
 	e.POST("v1/prompt", h.UserGPT4Handler, authGuard.Bearer)
 	e.POST("v1/prompt/new", h.UserClearContextHandler, authGuard.Bearer)
+	e.POST("v1/prompt/regenerate", h.RegenerateHandler, authGuard.Bearer)
+	e.POST("v1/prompt/messages/:id/edit", h.EditMessageHandler, authGuard.Bearer)
+
+	// Ratings of answers, given by the user or service that was answered
+	e.POST("v1/messages/:id/feedback", h.SubmitFeedbackHandler, authGuard.BearerOrBasic)
+
+	// Conversation branches created by edits and regenerations
+	e.GET("v1/conversation", h.GetConversationHandler, authGuard.Bearer)
+	e.POST("v1/conversation/branch", h.SwitchBranchHandler, authGuard.Bearer)
+
+	// Download and restore of conversations
+	e.GET("v1/conversations/export", h.ExportConversationsHandler, authGuard.Bearer)
+	e.GET("v1/conversations/:id/export", h.ExportConversationsHandler, authGuard.Bearer)
+	e.POST("v1/conversations/import", h.ImportConversationHandler, authGuard.Bearer)
+
+	// Read-only conversation snapshots shared through a token
+	e.POST("v1/conversations/:id/shares", h.CreateShareHandler, authGuard.Bearer)
+	e.GET("v1/shares", h.ListSharesHandler, authGuard.Bearer)
+	e.DELETE("v1/shares/:id", h.RevokeShareHandler, authGuard.Bearer)
+	e.GET("v1/shared/:token", h.ViewShareHandler, authGuard.OptionalBearer)
+	e.POST("v1/shared/:token/fork", h.ForkShareHandler, authGuard.Bearer)
 
 	// Internal service for GPT4
 	e.POST("v1/prompt/internal", h.ServiceGPT4Handler, authGuard.Basic)
+
+	// Embeddings for users and internal services, OpenAI compatible
+	e.POST("v1/embeddings", h.EmbeddingsHandler, authGuard.BearerOrBasic)
+
+	// Personas selectable per conversation or request
+	e.GET("v1/personas", h.ListPersonasHandler, authGuard.BearerOrBasic)
+	e.GET("v1/personas/:ref", h.GetPersonaHandler, authGuard.BearerOrBasic)
+
+	// Prompt templates usable from v1/prompt/internal
+	e.GET("v1/templates", h.ListPromptTemplatesHandler, authGuard.BearerOrBasic)
+	e.GET("v1/templates/:ref", h.GetPromptTemplateHandler, authGuard.BearerOrBasic)
+
+	// Knowledge bases for retrieval-augmented prompts
+	e.POST("v1/knowledge-bases", h.CreateKnowledgeBaseHandler, authGuard.BearerOrBasic)
+	e.GET("v1/knowledge-bases", h.ListKnowledgeBasesHandler, authGuard.BearerOrBasic)
+	e.GET("v1/knowledge-bases/:name", h.GetKnowledgeBaseHandler, authGuard.BearerOrBasic)
+	e.DELETE("v1/knowledge-bases/:name", h.DeleteKnowledgeBaseHandler, authGuard.BearerOrBasic)
+	e.POST("v1/knowledge-bases/:name/documents", h.UploadDocumentHandler, authGuard.BearerOrBasic)
+	e.GET("v1/knowledge-bases/:name/documents", h.ListDocumentsHandler, authGuard.BearerOrBasic)
+	e.DELETE("v1/knowledge-bases/:name/documents/:id", h.DeleteDocumentHandler, authGuard.BearerOrBasic)
+
+	// Batch API for internal services, OpenAI compatible
+	e.POST("v1/files", h.UploadFileHandler, authGuard.Basic)
+	e.GET("v1/files/:id", h.GetFileHandler, authGuard.Basic)
+	e.GET("v1/files/:id/content", h.GetFileContentHandler, authGuard.Basic)
+	e.POST("v1/batches", h.CreateBatchHandler, authGuard.Basic)
+	e.GET("v1/batches", h.ListBatchesHandler, authGuard.Basic)
+	e.GET("v1/batches/:id", h.GetBatchHandler, authGuard.Basic)
+	e.POST("v1/batches/:id/cancel", h.CancelBatchHandler, authGuard.Basic)
+
+	// Webhook deliveries of async prompts and batches
+	e.GET("v1/webhooks/deliveries", h.ListWebhookDeliveriesHandler, authGuard.Basic)
+	e.GET("v1/webhooks/deliveries/:id", h.GetWebhookDeliveryHandler, authGuard.Basic)
+
+	// Admin
+	admin := e.Group("v1/admin", authGuard.Bearer, authGuard.RequireRoles(authguard.RoleAdmin))
+	admin.GET("/users", h.AdminListUsersHandler)
+	admin.GET("/users/:email", h.AdminGetUserHandler)
+	admin.PUT("/users/:email/role", h.AdminSetUserRoleHandler)
+	admin.POST("/users/:email/quota/reset", h.AdminResetQuotaHandler)
+	admin.POST("/users/:email/quota/grant", h.AdminGrantQuotaHandler)
+	admin.POST("/users/:email/context/clear", h.AdminClearContextHandler)
+	admin.GET("/users/:email/conversations/export", h.AdminExportConversationsHandler)
+	admin.DELETE("/users/:email/data", h.AdminEraseUserDataHandler)
+	admin.POST("/users/:email/block", h.AdminBlockUserHandler)
+	admin.POST("/users/:email/unblock", h.AdminUnblockUserHandler)
+	admin.POST("/services/:name/key", h.AdminRotateServiceKeyHandler)
+	admin.DELETE("/services/:name/key", h.AdminResetServiceKeyHandler)
+	admin.GET("/roles/:role/models", h.AdminGetRoleModelsHandler)
+	admin.PUT("/roles/:role/models", h.AdminSetRoleModelsHandler)
+	admin.DELETE("/roles/:role/models", h.AdminResetRoleModelsHandler)
+	admin.GET("/reports/usage", h.UsageReportHandler)
+	admin.GET("/reports/feedback", h.FeedbackReportHandler)
+	admin.GET("/feedback/export", h.ExportFeedbackHandler)
+	admin.GET("/audit", h.AdminListAuditEntriesHandler)
+	admin.POST("/personas", h.AdminCreatePersonaHandler)
+	admin.GET("/personas/:name/versions", h.AdminListPersonaVersionsHandler)
+	admin.POST("/templates", h.AdminCreatePromptTemplateHandler)
+	admin.GET("/templates/:name/versions", h.AdminListPromptTemplateVersionsHandler)
 }
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+// CreateShareHandler shares a read-only snapshot of a conversation, the token is only returned here
+func (h *Handler) CreateShareHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::CreateShare")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	req := new(request.ShareCreate)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Body"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+
+	share, err := h.service.CreateShare(ctx, core.ShareRequest{
+		ConversationID: c.Param("id"),
+		Owner:          jwtAtrr.Email,
+		ExpiresIn:      time.Duration(req.ExpiresIn) * time.Second,
+		SameDomain:     req.SameDomain,
+	})
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewShareResponse(share))
+}
+
+// ListSharesHandler lists the shares created by the user
+func (h *Handler) ListSharesHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ListShares")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	shares, err := h.service.ListShares(ctx, jwtAtrr.Email)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewShareListResponse(shares))
+}
+
+// RevokeShareHandler deletes a share of the user
+func (h *Handler) RevokeShareHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::RevokeShare")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	if err := h.service.RevokeShare(ctx, jwtAtrr.Email, c.Param("id")); err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, common.DefaultResponse{Code: 200, Message: "Share Revoked"})
+}
+
+// ViewShareHandler shows a shared conversation, signing in is only needed for shares restricted to a domain
+func (h *Handler) ViewShareHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ViewShare")
+	defer apm.EndTransaction(span)
+
+	var viewer string
+	if jwtAtrr, ok := c.Get(authguard.UserAttr).(authguard.JwtClaims); ok {
+		viewer = jwtAtrr.Email
+	}
+
+	shared, err := h.service.ViewShare(ctx, c.Param("token"), viewer)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSharedConversationResponse(shared))
+}
+
+// ForkShareHandler copies a shared conversation into the conversations of the user
+func (h *Handler) ForkShareHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ForkShare")
+	defer apm.EndTransaction(span)
+
+	jwtAtrr := c.Get(authguard.UserAttr).(authguard.JwtClaims)
+
+	conversation, err := h.service.ForkShare(ctx, c.Param("token"), jwtAtrr.Email, c.Get(authguard.RoleAttr).(string))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewConversationResponse(conversation))
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"net/http"
+
+	common "github.com/abialemuel/AI-Proxy-Service/pkg/common/http"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/validator"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/request"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/api/http/response"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"github.com/labstack/echo/v4"
+)
+
+const webhookDefaultListLimit = 20
+
+// ListWebhookDeliveriesHandler lists the latest webhook deliveries of the service
+func (h *Handler) ListWebhookDeliveriesHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::ListWebhookDeliveries")
+	defer apm.EndTransaction(span)
+
+	req := new(request.WebhookDeliveryList)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse("Invalid Query"))
+	}
+	if msg, check := validator.Validation(req); !check {
+		return c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(msg))
+	}
+	if req.Limit == 0 {
+		req.Limit = webhookDefaultListLimit
+	}
+
+	serviceName := c.Get("service").(string)
+	deliveries, err := h.service.ListWebhookDeliveries(ctx, serviceName, req.Limit)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewWebhookDeliveryListResponse(deliveries))
+}
+
+// GetWebhookDeliveryHandler returns a webhook delivery with all of its attempts
+func (h *Handler) GetWebhookDeliveryHandler(c echo.Context) error {
+	ctx, span := apm.StartTransaction(c.Request().Context(), "Handler::GetWebhookDelivery")
+	defer apm.EndTransaction(span)
+
+	serviceName := c.Get("service").(string)
+	delivery, err := h.service.GetWebhookDelivery(ctx, serviceName, c.Param("id"))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewWebhookDeliveryResponse(delivery))
+}
@@ -0,0 +1,114 @@
+package business
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// serviceKeyBytes is the entropy of a generated service password
+const serviceKeyBytes = 32
+
+// ResolveServiceKey returns the password hash set for a service, it satisfies authguard.ServiceKeyResolver
+func (u UserService) ResolveServiceKey(ctx context.Context, service string) (authguard.StoredServiceKey, error) {
+	key, err := u.repo.GetServiceKey(ctx, service)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return authguard.StoredServiceKey{}, nil
+	}
+	if err != nil {
+		return authguard.StoredServiceKey{}, err
+	}
+	return authguard.StoredServiceKey{PasswordHash: key.PasswordHash}, nil
+}
+
+// RotateServiceKey replaces the password of a configured service with a generated one.
+// The configured password stops working, the new one is returned and only its hash is kept.
+func (u UserService) RotateServiceKey(ctx context.Context, service string, actor string) (core.ServiceKey, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::RotateServiceKey")
+	defer apm.EndTransaction(span)
+
+	configured, ok := u.backendService(service)
+	if !ok {
+		return core.ServiceKey{}, fmt.Errorf("%w: service %s", core.ErrNotFound, service)
+	}
+
+	b := make([]byte, serviceKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return core.ServiceKey{}, err
+	}
+	password := base64.RawURLEncoding.EncodeToString(b)
+	sum := sha256.Sum256([]byte(password))
+	err := u.repo.UpsertServiceKey(ctx, repository.ServiceKey{
+		Service:      service,
+		PasswordHash: hex.EncodeToString(sum[:]),
+		CreatedBy:    actor,
+	})
+	if err != nil {
+		return core.ServiceKey{}, err
+	}
+	return core.ServiceKey{Service: service, Username: configured.Username, Password: password}, nil
+}
+
+// ResetServiceKey drops the password set for a service, it authenticates with the configured one again
+func (u UserService) ResetServiceKey(ctx context.Context, service string) error {
+	ctx, span := apm.StartTransaction(ctx, "Service::ResetServiceKey")
+	defer apm.EndTransaction(span)
+
+	if _, ok := u.backendService(service); !ok {
+		return fmt.Errorf("%w: service %s", core.ErrNotFound, service)
+	}
+	return u.repo.DeleteServiceKey(ctx, service)
+}
+
+// GetRoleModels returns the model allow-list of a role, the one set by an admin wins over the configured one
+func (u UserService) GetRoleModels(ctx context.Context, role string) (core.RoleModels, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::GetRoleModels")
+	defer apm.EndTransaction(span)
+
+	stored, err := u.repo.GetRoleModels(ctx, role)
+	if err == nil {
+		return core.RoleModels{Role: role, AllowedModels: stored.AllowedModels, Source: core.SourceAdmin}, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return core.RoleModels{}, err
+	}
+
+	allowed := u.cfg.Roles[role].AllowedModels
+	if allowed == nil {
+		allowed = []string{}
+	}
+	return core.RoleModels{Role: role, AllowedModels: allowed, Source: core.SourceConfig}, nil
+}
+
+// SetRoleModels replaces the model allow-list of a role, an empty list allows every model
+func (u UserService) SetRoleModels(ctx context.Context, role string, models []string, actor string) (core.RoleModels, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::SetRoleModels")
+	defer apm.EndTransaction(span)
+
+	err := u.repo.UpsertRoleModels(ctx, repository.RoleModels{Role: role, AllowedModels: models, UpdatedBy: actor})
+	if err != nil {
+		return core.RoleModels{}, err
+	}
+	return u.GetRoleModels(ctx, role)
+}
+
+// ResetRoleModels drops the model allow-list set for a role, the configured one applies again
+func (u UserService) ResetRoleModels(ctx context.Context, role string) (core.RoleModels, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ResetRoleModels")
+	defer apm.EndTransaction(span)
+
+	if err := u.repo.DeleteRoleModels(ctx, role); err != nil {
+		return core.RoleModels{}, err
+	}
+	return u.GetRoleModels(ctx, role)
+}
@@ -0,0 +1,97 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+)
+
+// TouchUser records the user's last activity
+func (u UserService) TouchUser(ctx context.Context, email string, name string) error {
+	return u.repo.TouchUser(ctx, email, name)
+}
+
+// ListUsers returns known users with their current token usage
+func (u UserService) ListUsers(ctx context.Context, offset int, limit int) ([]core.UserDetail, int64, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListUsers")
+	defer apm.EndTransaction(span)
+
+	users, count, err := u.repo.ListUsers(ctx, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res := []core.UserDetail{}
+	for _, user := range users {
+		res = append(res, core.ToCoreUserDetail(user, u.GetUserTokenUsage(ctx, user.Email)))
+	}
+	return res, count, nil
+}
+
+// GetUserDetail returns a known user with their current token usage
+func (u UserService) GetUserDetail(ctx context.Context, email string) (core.UserDetail, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::GetUserDetail")
+	defer apm.EndTransaction(span)
+
+	user, err := u.repo.GetUser(ctx, email)
+	if err != nil {
+		return core.UserDetail{}, fmt.Errorf("user %s not found: %v", email, err)
+	}
+	return core.ToCoreUserDetail(user, u.GetUserTokenUsage(ctx, email)), nil
+}
+
+// SetUserBlocked blocks or unblocks a user, a blocked user also loses their context
+func (u UserService) SetUserBlocked(ctx context.Context, email string, blocked bool) error {
+	ctx, span := apm.StartTransaction(ctx, "Service::SetUserBlocked")
+	defer apm.EndTransaction(span)
+
+	err := u.repo.SetUserBlocked(ctx, email, blocked)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return u.UserClearContext(ctx, email)
+	}
+	return nil
+}
+
+// ResetUserQuota clears the token usage and any granted tokens of a user
+func (u UserService) ResetUserQuota(ctx context.Context, email string) error {
+	u.cache.Delete(ctx, fmt.Sprintf(redisKeyTokenUsage, email))
+	u.cache.Delete(ctx, fmt.Sprintf(redisKeyTokenGrant, email))
+	return nil
+}
+
+// GrantUserQuota adds extra tokens on top of the configured limit until the current usage window ends
+func (u UserService) GrantUserQuota(ctx context.Context, email string, tokens int) error {
+	grantKey := fmt.Sprintf(redisKeyTokenGrant, email)
+
+	granted := 0
+	grantData, success := u.cache.Get(ctx, grantKey)
+	if success {
+		granted, _ = strconv.Atoi(grantData.(string))
+	}
+
+	// the grant lives as long as the usage window it extends
+	duration, _ := u.cache.TTL(ctx, fmt.Sprintf(redisKeyTokenUsage, email))
+	if duration <= 0 {
+		duration = time.Second * time.Duration(u.cfg.OpenAI.TokenLifetime)
+	}
+
+	return u.cache.Set(ctx, grantKey, granted+tokens, duration)
+}
+
+// tokenLimit returns the configured token limit plus any tokens granted to the user
+func (u UserService) tokenLimit(ctx context.Context, userID string) int {
+	limit := u.cfg.OpenAI.TokenLimit
+	grantData, success := u.cache.Get(ctx, fmt.Sprintf(redisKeyTokenGrant, userID))
+	if success {
+		granted, _ := strconv.Atoi(grantData.(string))
+		limit += granted
+	}
+	return limit
+}
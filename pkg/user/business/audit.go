@@ -0,0 +1,40 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+)
+
+// audit records an action against a principal in the audit trail, without blocking the caller
+func (u UserService) audit(p principal, action string, detail map[string]string) {
+	entry := repository.AuditEntry{
+		Action:        action,
+		Principal:     p.Name,
+		PrincipalType: p.Type,
+		Tribe:         p.Tribe,
+		Detail:        detail,
+		CreatedAt:     time.Now(),
+	}
+	go func() {
+		if err := u.repo.InsertAuditEntry(context.Background(), entry); err != nil {
+			fmt.Println("Error inserting audit entry:", err)
+		}
+	}()
+}
+
+// ListAuditEntries returns the latest audit entries, optionally of a single action
+func (u UserService) ListAuditEntries(ctx context.Context, action string, limit int) ([]core.AuditEntry, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListAuditEntries")
+	defer apm.EndTransaction(span)
+
+	entries, err := u.repo.ListAuditEntries(ctx, action, limit)
+	if err != nil {
+		return nil, err
+	}
+	return core.ToCoreAuditEntries(entries), nil
+}
@@ -1 +1,51 @@
 package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ResolveUser returns the role and block state stored for a user, it satisfies authguard.UserResolver
+func (u UserService) ResolveUser(ctx context.Context, email string) (authguard.StoredUser, error) {
+	user, err := u.repo.GetUser(ctx, email)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return authguard.StoredUser{}, nil
+	}
+	if err != nil {
+		return authguard.StoredUser{}, err
+	}
+	return authguard.StoredUser{Role: user.Role, Blocked: user.Blocked}, nil
+}
+
+// SetUserRole stores the role of a user, overriding the role derived from IdP claims
+func (u UserService) SetUserRole(ctx context.Context, email string, role string) error {
+	ctx, span := apm.StartTransaction(ctx, "Service::SetUserRole")
+	defer apm.EndTransaction(span)
+
+	return u.repo.UpsertUserRole(ctx, email, role)
+}
+
+// validateModel checks if a role is allowed to use the given model
+func (u UserService) validateModel(ctx context.Context, role string, model string) error {
+	policy, err := u.GetRoleModels(ctx, role)
+	if err != nil {
+		return err
+	}
+	if len(policy.AllowedModels) == 0 {
+		return nil
+	}
+
+	for _, allowed := range policy.AllowedModels {
+		if strings.EqualFold(allowed, model) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not allowed for role %s", core.ErrModelNotAllowed, model, role)
+}
@@ -0,0 +1,505 @@
+package business
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/http/middleware/authguard"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	batchDefaultTemperature = 1.0
+	batchDefaultTop         = 1.0
+	batchCompletionWindow   = 24 * time.Hour
+	batchRequestTimeout     = 1 * time.Minute
+	batchFlushEvery         = 50              // persist request counts every n requests
+	batchPollInterval       = 5 * time.Second // how often other instances' cancellations are picked up
+	batchStaleAfter         = 1 * time.Minute // unfinished batches untouched for longer lost their instance
+)
+
+// batchRunner bounds upstream concurrency across all batches and tracks running batches for cancellation
+type batchRunner struct {
+	slots   chan struct{}
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func newBatchRunner(concurrency int) *batchRunner {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &batchRunner{
+		slots:   make(chan struct{}, concurrency),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+func (r *batchRunner) register(id string, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancels[id] = cancel
+}
+
+func (r *batchRunner) unregister(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cancels, id)
+}
+
+func (r *batchRunner) cancel(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cancel, ok := r.cancels[id]; ok {
+		cancel()
+	}
+}
+
+// CreateFile stores an uploaded file
+func (u UserService) CreateFile(ctx context.Context, owner string, purpose string, filename string, content []byte) (core.File, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::CreateFile")
+	defer apm.EndTransaction(span)
+
+	if purpose != core.FilePurposeBatch {
+		return core.File{}, fmt.Errorf("%w: unsupported purpose %s", core.ErrInvalidRequest, purpose)
+	}
+	if int64(len(content)) > u.cfg.Batch.MaxFileBytes {
+		return core.File{}, fmt.Errorf("%w: file exceeds %d bytes", core.ErrInvalidRequest, u.cfg.Batch.MaxFileBytes)
+	}
+
+	file, err := u.repo.InsertFile(ctx, repository.File{Owner: owner, Purpose: purpose, Filename: filename}, content)
+	if err != nil {
+		return core.File{}, err
+	}
+	return core.ToCoreFile(file), nil
+}
+
+// GetFile returns a file owned by the caller
+func (u UserService) GetFile(ctx context.Context, owner string, id string) (core.File, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::GetFile")
+	defer apm.EndTransaction(span)
+
+	file, err := u.repo.GetFile(ctx, id)
+	if err != nil || file.Owner != owner {
+		return core.File{}, fmt.Errorf("%w: file %s", core.ErrNotFound, id)
+	}
+	return core.ToCoreFile(file), nil
+}
+
+// GetFileContent returns the content of a file owned by the caller
+func (u UserService) GetFileContent(ctx context.Context, owner string, id string) ([]byte, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::GetFileContent")
+	defer apm.EndTransaction(span)
+
+	file, err := u.repo.GetFile(ctx, id)
+	if err != nil || file.Owner != owner {
+		return nil, fmt.Errorf("%w: file %s", core.ErrNotFound, id)
+	}
+	return u.repo.GetFileContent(ctx, id)
+}
+
+// CreateBatch validates the batch request, stores it and starts processing in the background
+func (u UserService) CreateBatch(ctx context.Context, owner string, req core.BatchRequest) (core.Batch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::CreateBatch")
+	defer apm.EndTransaction(span)
+
+	if req.Endpoint != core.BatchEndpointChatCompletions {
+		return core.Batch{}, fmt.Errorf("%w: unsupported endpoint %s", core.ErrInvalidRequest, req.Endpoint)
+	}
+	if req.CompletionWindow != core.BatchCompletionWindow {
+		return core.Batch{}, fmt.Errorf("%w: unsupported completion window %s", core.ErrInvalidRequest, req.CompletionWindow)
+	}
+
+	if err := u.validateCallbackURL(owner, req.CallbackURL); err != nil {
+		return core.Batch{}, err
+	}
+
+	file, err := u.repo.GetFile(ctx, req.InputFileID)
+	if err != nil || file.Owner != owner {
+		return core.Batch{}, fmt.Errorf("%w: file %s", core.ErrNotFound, req.InputFileID)
+	}
+	if file.Purpose != core.FilePurposeBatch {
+		return core.Batch{}, fmt.Errorf("%w: file %s is not a batch input file", core.ErrInvalidRequest, req.InputFileID)
+	}
+
+	batch, err := u.repo.InsertBatch(ctx, repository.Batch{
+		Owner:            owner,
+		Endpoint:         req.Endpoint,
+		InputFileID:      req.InputFileID,
+		CompletionWindow: req.CompletionWindow,
+		Status:           core.BatchStatusValidating,
+		Errors:           []string{},
+		Metadata:         req.Metadata,
+		CallbackURL:      req.CallbackURL,
+	})
+	if err != nil {
+		return core.Batch{}, err
+	}
+
+	go u.runBatch(batch)
+
+	return core.ToCoreBatch(batch), nil
+}
+
+// GetBatch returns a batch owned by the caller
+func (u UserService) GetBatch(ctx context.Context, owner string, id string) (core.Batch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::GetBatch")
+	defer apm.EndTransaction(span)
+
+	batch, err := u.repo.GetBatch(ctx, id)
+	if err != nil || batch.Owner != owner {
+		return core.Batch{}, fmt.Errorf("%w: batch %s", core.ErrNotFound, id)
+	}
+	return core.ToCoreBatch(batch), nil
+}
+
+// ListBatches returns the latest batches of the caller
+func (u UserService) ListBatches(ctx context.Context, owner string, limit int) ([]core.Batch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListBatches")
+	defer apm.EndTransaction(span)
+
+	batches, err := u.repo.ListBatches(ctx, owner, limit)
+	if err != nil {
+		return nil, err
+	}
+	return core.ToCoreBatches(batches), nil
+}
+
+// CancelBatch stops a running batch, requests already sent upstream still complete
+func (u UserService) CancelBatch(ctx context.Context, owner string, id string) (core.Batch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::CancelBatch")
+	defer apm.EndTransaction(span)
+
+	batch, err := u.repo.GetBatch(ctx, id)
+	if err != nil || batch.Owner != owner {
+		return core.Batch{}, fmt.Errorf("%w: batch %s", core.ErrNotFound, id)
+	}
+
+	batch, err = u.repo.CancelBatch(ctx, id)
+	if err != nil {
+		return core.Batch{}, fmt.Errorf("%w: batch %s can no longer be cancelled", core.ErrInvalidRequest, id)
+	}
+	u.batches.cancel(id)
+
+	return core.ToCoreBatch(batch), nil
+}
+
+// runBatch validates the input file, sends every request upstream and writes the output and error files
+func (u UserService) runBatch(batch repository.Batch) {
+	id := batch.ID.Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), batchCompletionWindow)
+	defer cancel()
+	u.batches.register(id, cancel)
+	defer u.batches.unregister(id)
+
+	content, err := u.repo.GetFileContent(ctx, batch.InputFileID)
+	if err != nil {
+		u.failBatch(batch, []string{fmt.Sprintf("failed to read input file: %v", err)})
+		return
+	}
+	lines, errs := u.parseBatchInput(content)
+	if len(errs) > 0 {
+		u.failBatch(batch, errs)
+		return
+	}
+
+	batch.RequestCounts.Total = len(lines)
+	if err := u.repo.StartBatch(ctx, batch.ID, len(lines)); err != nil {
+		// cancelled while the input file was validated
+		now := time.Now()
+		batch.Status = core.BatchStatusCancelled
+		batch.CancelledAt = &now
+		u.finishBatch(batch, core.BatchStatusCancelling)
+		return
+	}
+
+	// pick up cancellations made through other instances
+	go u.watchBatchCancellation(ctx, batch.ID, cancel)
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		outputs   bytes.Buffer
+		errorsBuf bytes.Buffer
+	)
+	for i, line := range lines {
+		acquired := false
+		select {
+		case u.batches.slots <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			if acquired {
+				<-u.batches.slots
+			}
+			// remaining requests were never sent
+			mu.Lock()
+			for _, skipped := range lines[i:] {
+				writeBatchLine(&errorsBuf, batchErrorLine(skipped.CustomID, "batch_cancelled", "batch was cancelled or expired before the request was sent"))
+				batch.RequestCounts.Failed++
+			}
+			mu.Unlock()
+			break
+		}
+
+		wg.Add(1)
+		go func(line core.BatchInputLine) {
+			defer wg.Done()
+			defer func() { <-u.batches.slots }()
+
+			output, ok := u.runBatchLine(ctx, batch.Owner, line)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				writeBatchLine(&outputs, output)
+				batch.RequestCounts.Completed++
+			} else {
+				writeBatchLine(&errorsBuf, output)
+				batch.RequestCounts.Failed++
+			}
+			if (batch.RequestCounts.Completed+batch.RequestCounts.Failed)%batchFlushEvery == 0 {
+				if err := u.repo.UpdateBatchCounts(context.Background(), batch.ID, batch.RequestCounts); err != nil {
+					fmt.Println("Error updating batch:", err)
+				}
+			}
+		}(line)
+	}
+	wg.Wait()
+
+	// write results even when cancelled, completed requests are still billed
+	if outputs.Len() > 0 {
+		file, err := u.repo.InsertFile(context.Background(), repository.File{Owner: batch.Owner, Purpose: core.FilePurposeBatchOutput, Filename: fmt.Sprintf("batch_%s_output.jsonl", id)}, outputs.Bytes())
+		if err == nil {
+			batch.OutputFileID = file.ID.Hex()
+		}
+	}
+	if errorsBuf.Len() > 0 {
+		file, err := u.repo.InsertFile(context.Background(), repository.File{Owner: batch.Owner, Purpose: core.FilePurposeBatchOutput, Filename: fmt.Sprintf("batch_%s_error.jsonl", id)}, errorsBuf.Bytes())
+		if err == nil {
+			batch.ErrorFileID = file.ID.Hex()
+		}
+	}
+
+	now := time.Now()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		batch.Status = core.BatchStatusExpired
+		batch.ExpiredAt = &now
+		u.finishBatch(batch, core.BatchStatusInProgress)
+	case errors.Is(ctx.Err(), context.Canceled):
+		batch.Status = core.BatchStatusCancelled
+		batch.CancelledAt = &now
+		u.finishBatch(batch, core.BatchStatusCancelling)
+	default:
+		batch.Status = core.BatchStatusCompleted
+		batch.CompletedAt = &now
+		u.finishBatch(batch, core.BatchStatusInProgress)
+	}
+}
+
+// runBatchLine sends a single batch request, it returns the output line and whether it succeeded
+func (u UserService) runBatchLine(ctx context.Context, owner string, line core.BatchInputLine) (core.BatchOutputLine, bool) {
+	ctx, cancel := context.WithTimeout(ctx, batchRequestTimeout)
+	defer cancel()
+
+	req := core.ServicePromptRequest{
+		ServiceName: owner,
+		Role:        authguard.RoleService,
+		Model:       line.Body.Model,
+		Temperature: line.Body.Temperature,
+		MaxTokens:   userDefaultMaxTokens,
+		TopP:        batchDefaultTop,
+		Messages:    core.ToCoreMessageRequests(line.Body.Messages),
+	}
+	if req.Temperature == nil {
+		temperature := batchDefaultTemperature
+		req.Temperature = &temperature
+	}
+	if line.Body.MaxTokens != nil {
+		req.MaxTokens = *line.Body.MaxTokens
+	}
+	if line.Body.TopP != nil {
+		req.TopP = *line.Body.TopP
+	}
+
+	res, err := u.ServicePrompt(ctx, req)
+	if err != nil {
+		return batchErrorLine(line.CustomID, promptErrorCode(err), err.Error()), false
+	}
+
+	return core.BatchOutputLine{
+		ID:       "batch_req_" + primitive.NewObjectID().Hex(),
+		CustomID: line.CustomID,
+		Response: &core.BatchOutputResponse{
+			StatusCode: 200,
+			RequestID:  res.GPT4PromptResponse.ID,
+			Body:       res.GPT4PromptResponse,
+		},
+	}, true
+}
+
+// parseBatchInput parses and validates every line of a batch input file
+func (u UserService) parseBatchInput(content []byte) ([]core.BatchInputLine, []string) {
+	var lines []core.BatchInputLine
+	var errs []string
+	customIDs := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var line core.BatchInputLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: invalid json: %v", lineNo, err))
+			continue
+		}
+		switch {
+		case line.CustomID == "":
+			errs = append(errs, fmt.Sprintf("line %d: custom_id is required", lineNo))
+		case customIDs[line.CustomID]:
+			errs = append(errs, fmt.Sprintf("line %d: duplicate custom_id %s", lineNo, line.CustomID))
+		case line.Method != "POST":
+			errs = append(errs, fmt.Sprintf("line %d: unsupported method %s", lineNo, line.Method))
+		case line.URL != core.BatchEndpointChatCompletions:
+			errs = append(errs, fmt.Sprintf("line %d: unsupported url %s", lineNo, line.URL))
+		case line.Body.Model == "" || len(line.Body.Messages) == 0:
+			errs = append(errs, fmt.Sprintf("line %d: body.model and body.messages are required", lineNo))
+		}
+		customIDs[line.CustomID] = true
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(lines) == 0 && len(errs) == 0 {
+		errs = append(errs, "input file has no requests")
+	}
+	if len(lines) > u.cfg.Batch.MaxRequests {
+		errs = append(errs, fmt.Sprintf("input file has %d requests, at most %d are allowed", len(lines), u.cfg.Batch.MaxRequests))
+	}
+	return lines, errs
+}
+
+// watchBatchCancellation keeps the batch marked as alive and cancels it once its stored status becomes cancelling
+func (u UserService) watchBatchCancellation(ctx context.Context, id primitive.ObjectID, cancel context.CancelFunc) {
+	ticker := time.NewTicker(batchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			batch, err := u.repo.TouchBatch(ctx, id)
+			if err == nil && batch.Status == core.BatchStatusCancelling {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// failBatch marks a batch as failed validation
+func (u UserService) failBatch(batch repository.Batch, errs []string) {
+	now := time.Now()
+	batch.Status = core.BatchStatusFailed
+	batch.FailedAt = &now
+	batch.Errors = errs
+	u.finishBatch(batch, core.BatchStatusValidating)
+}
+
+// finishBatch stores the final state of a batch whose status is one of from and notifies its callback.
+// A cancellation that landed meanwhile wins, the batch then ends as cancelled with the work it completed.
+func (u UserService) finishBatch(batch repository.Batch, from ...string) {
+	ctx := context.Background()
+	err := u.repo.FinishBatch(ctx, batch, from...)
+	if errors.Is(err, mongo.ErrNoDocuments) && batch.Status != core.BatchStatusCancelled {
+		now := time.Now()
+		batch.Status = core.BatchStatusCancelled
+		batch.CancelledAt = &now
+		batch.CompletedAt, batch.FailedAt, batch.ExpiredAt = nil, nil, nil
+		err = u.repo.FinishBatch(ctx, batch, core.BatchStatusCancelling)
+	}
+	if err != nil {
+		fmt.Println("Error updating batch:", err)
+		return
+	}
+
+	// the stored batch also holds what other writers set, e.g. cancelling_at
+	if stored, err := u.repo.GetBatch(ctx, batch.ID.Hex()); err == nil {
+		batch = stored
+	}
+	u.notifyBatch(batch)
+}
+
+// RunBatchRecovery ends the batches whose instance went away, every batchStaleAfter until ctx is done.
+// Their progress is only known from the last flush, so they end as failed and have to be resubmitted.
+func (u UserService) RunBatchRecovery(ctx context.Context) {
+	ticker := time.NewTicker(batchStaleAfter)
+	defer ticker.Stop()
+	for {
+		u.recoverBatches(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u UserService) recoverBatches(ctx context.Context) {
+	stale, err := u.repo.ListStaleBatches(ctx, time.Now().Add(-batchStaleAfter))
+	if err != nil {
+		fmt.Println("Error listing stale batches:", err)
+		return
+	}
+	for _, batch := range stale {
+		from := batch.Status
+		now := time.Now()
+		if from == core.BatchStatusCancelling {
+			batch.Status = core.BatchStatusCancelled
+			batch.CancelledAt = &now
+		} else {
+			batch.Status = core.BatchStatusFailed
+			batch.FailedAt = &now
+			batch.Errors = append(batch.Errors, "batch was interrupted by a restart before it finished, resubmit it")
+		}
+		// the conditional write lets a single instance recover each batch
+		if err := u.repo.FinishBatch(ctx, batch, from); err != nil {
+			continue
+		}
+		u.notifyBatch(batch)
+	}
+}
+
+func batchErrorLine(customID string, code string, message string) core.BatchOutputLine {
+	return core.BatchOutputLine{
+		ID:       "batch_req_" + primitive.NewObjectID().Hex(),
+		CustomID: customID,
+		Error:    &core.BatchOutputError{Code: code, Message: message},
+	}
+}
+
+func writeBatchLine(buf *bytes.Buffer, line core.BatchOutputLine) {
+	data, _ := json.Marshal(line)
+	buf.Write(data)
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,245 @@
+package business
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetConversation returns the current conversation of a user with all its branches
+func (u UserService) GetConversation(ctx context.Context, userID string) (core.ConversationTree, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::GetConversation")
+	defer apm.EndTransaction(span)
+
+	conversation, messages, err := u.conversationMessages(ctx, userID)
+	if err != nil {
+		return core.ConversationTree{}, err
+	}
+
+	active := map[primitive.ObjectID]bool{}
+	for _, v := range conversationPath(messages, conversation.ActiveLeaf) {
+		active[v.ID] = true
+	}
+	return core.ToCoreConversationTree(conversation, messages, active), nil
+}
+
+// RegenerateLastAnswer answers the last prompt of the active branch again, the new answer becomes a sibling of the old one
+func (u UserService) RegenerateLastAnswer(ctx context.Context, payload core.UserPromtGPTRequest) (core.UserPromGPTResponse, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::RegenerateLastAnswer")
+	defer apm.EndTransaction(span)
+
+	conversation, messages, err := u.conversationMessages(ctx, payload.UserID)
+	if err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+
+	path := conversationPath(messages, conversation.ActiveLeaf)
+	if len(path) < 2 || path[len(path)-1].Role != "assistant" || path[len(path)-2].Role != userDefaultRole {
+		return core.UserPromGPTResponse{}, fmt.Errorf("%w: there is no answer to regenerate", core.ErrInvalidRequest)
+	}
+	prompt := path[len(path)-2]
+
+	payload.Content = core.ToCoreContent(prompt.Content)
+	payload.PromptMessageID = prompt.ID.Hex()
+	return u.promptOnBranch(ctx, payload, conversation, messages, path[:len(path)-2], prompt.ID)
+}
+
+// EditMessage replaces a past user message, the conversation continues on a new branch starting before that message
+func (u UserService) EditMessage(ctx context.Context, messageID string, payload core.UserPromtGPTRequest) (core.UserPromGPTResponse, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::EditMessage")
+	defer apm.EndTransaction(span)
+
+	conversation, messages, err := u.conversationMessages(ctx, payload.UserID)
+	if err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+
+	message, found := findMessage(messages, messageID)
+	if !found {
+		return core.UserPromGPTResponse{}, fmt.Errorf("%w: message %s", core.ErrNotFound, messageID)
+	}
+	if message.Role != userDefaultRole {
+		return core.UserPromGPTResponse{}, fmt.Errorf("%w: only user messages can be edited", core.ErrInvalidRequest)
+	}
+
+	return u.promptOnBranch(ctx, payload, conversation, messages, conversationPath(messages, message.ParentID), message.ParentID)
+}
+
+// SwitchBranch moves the user to the branch going through a message, following its most recent continuation
+func (u UserService) SwitchBranch(ctx context.Context, userID string, messageID string) (core.ConversationTree, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::SwitchBranch")
+	defer apm.EndTransaction(span)
+
+	conversation, messages, err := u.conversationMessages(ctx, userID)
+	if err != nil {
+		return core.ConversationTree{}, err
+	}
+
+	message, found := findMessage(messages, messageID)
+	if !found {
+		return core.ConversationTree{}, fmt.Errorf("%w: message %s", core.ErrNotFound, messageID)
+	}
+	leaf := latestLeaf(messages, message.ID)
+
+	if err = u.switchBranch(ctx, userID, conversation, conversationPath(messages, leaf), leaf); err != nil {
+		return core.ConversationTree{}, err
+	}
+	return u.GetConversation(ctx, userID)
+}
+
+// conversationMessages returns the current conversation of a user and all of its messages
+func (u UserService) conversationMessages(ctx context.Context, userID string) (repository.Conversation, []repository.Message, error) {
+	conversation, err := u.repo.GetLatestConversation(ctx, userID)
+	if err != nil {
+		return repository.Conversation{}, nil, fmt.Errorf("%w: conversation", core.ErrNotFound)
+	}
+	messages, err := u.repo.ListConversationMessages(ctx, conversation.ID)
+	if err != nil {
+		return repository.Conversation{}, nil, err
+	}
+	return conversation, messages, nil
+}
+
+// activeConversation returns the latest conversation of a user, or the zero conversation when they have none yet
+func (u UserService) activeConversation(ctx context.Context, userID string) (repository.Conversation, error) {
+	conversation, err := u.repo.GetLatestConversation(ctx, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return repository.Conversation{}, nil
+	}
+	return conversation, err
+}
+
+// promptOnBranch prompts on the branch ending at leaf. When the prompt fails the user is put back on the
+// branch they were on, so a failed regenerate or edit leaves the conversation as it was.
+func (u UserService) promptOnBranch(ctx context.Context, payload core.UserPromtGPTRequest, conversation repository.Conversation, messages []repository.Message, path []repository.Message, leaf primitive.ObjectID) (core.UserPromGPTResponse, error) {
+	if err := u.switchBranch(ctx, payload.UserID, conversation, path, leaf); err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+
+	res, err := u.UserPromtGPT(ctx, payload)
+	if err != nil {
+		// the request may have been cancelled, the restore must still happen
+		restoreCtx := context.WithoutCancel(ctx)
+		previous := conversation.ActiveLeaf
+		if err := u.switchBranch(restoreCtx, payload.UserID, conversation, conversationPath(messages, previous), previous); err != nil {
+			fmt.Println("Error restoring branch:", err)
+		}
+		return core.UserPromGPTResponse{}, err
+	}
+	return res, nil
+}
+
+// switchBranch makes leaf the active message of the conversation and rebuilds the context from the path leading to it
+func (u UserService) switchBranch(ctx context.Context, userID string, conversation repository.Conversation, path []repository.Message, leaf primitive.ObjectID) error {
+	if err := u.rebuildContext(ctx, userID, conversation.Summaries, path); err != nil {
+		return err
+	}
+	return u.repo.SetActiveLeaf(ctx, conversation.ID, leaf)
+}
+
+// rebuildContext writes the summaries and context of a branch to the cache. Summaries are kept while the
+// messages they cover are on the branch, the context holds the messages after the last of them.
+func (u UserService) rebuildContext(ctx context.Context, userID string, summaries []repository.Summary, path []repository.Message) error {
+	position := map[primitive.ObjectID]int{}
+	for i, v := range path {
+		position[v.ID] = i
+	}
+
+	var summaryMsgs []gpt4_webservice.MessageReq
+	start := 0
+	for _, v := range summaries {
+		i, onPath := position[v.UpTo]
+		if !onPath {
+			continue
+		}
+		summaryMsgs = append(summaryMsgs, gpt4_webservice.MessageReq{
+			Role:    v.Role,
+			Content: core.ToWebServiceUserPromtGPTContentRequest(core.ToCoreContent(v.Content)),
+		})
+		start = i + 1
+	}
+
+	var contextMsgs []gpt4_webservice.MessageReq
+	for _, v := range path[start:] {
+		contextMsgs = append(contextMsgs, gpt4_webservice.MessageReq{
+			Role:    v.Role,
+			Content: core.ToWebServiceUserPromtGPTContentRequest(core.ToCoreContent(v.Content)),
+		})
+	}
+
+	if err := u.setOrDelete(ctx, fmt.Sprintf(redisKeySummary, userID), summaryMsgs); err != nil {
+		return err
+	}
+	return u.setOrDelete(ctx, fmt.Sprintf(redisKeyContext, userID), contextMsgs)
+}
+
+// setOrDelete caches messages under key, or removes the key when there are none
+func (u UserService) setOrDelete(ctx context.Context, key string, messages []gpt4_webservice.MessageReq) error {
+	if len(messages) == 0 {
+		u.cache.Delete(ctx, key)
+		return nil
+	}
+	jsonData, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+	return u.cache.Set(ctx, key, jsonData, u.contextTTL())
+}
+
+// conversationPath returns the messages from the root of the conversation down to leaf
+func conversationPath(messages []repository.Message, leaf primitive.ObjectID) []repository.Message {
+	byID := map[primitive.ObjectID]repository.Message{}
+	for _, v := range messages {
+		byID[v.ID] = v
+	}
+
+	var path []repository.Message
+	for id := leaf; !id.IsZero(); {
+		message, found := byID[id]
+		if !found {
+			break
+		}
+		path = append([]repository.Message{message}, path...)
+		id = message.ParentID
+	}
+	return path
+}
+
+// latestLeaf follows the most recent continuation of a message down to the end of its branch
+func latestLeaf(messages []repository.Message, id primitive.ObjectID) primitive.ObjectID {
+	latest := map[primitive.ObjectID]primitive.ObjectID{}
+	for _, v := range messages {
+		// messages are ordered oldest first, the last child seen wins
+		if !v.ParentID.IsZero() {
+			latest[v.ParentID] = v.ID
+		}
+	}
+	for {
+		child, found := latest[id]
+		if !found {
+			return id
+		}
+		id = child
+	}
+}
+
+func findMessage(messages []repository.Message, id string) (repository.Message, bool) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repository.Message{}, false
+	}
+	for _, v := range messages {
+		if v.ID == objectID {
+			return v, true
+		}
+	}
+	return repository.Message{}, false
+}
@@ -0,0 +1,14 @@
+package contract
+
+import "context"
+
+// BlobStore keeps binary objects such as images, referenced from conversations by key
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type ImageFetcher interface {
+	Fetch(ctx context.Context, url string, maxBytes int64) ([]byte, error)
+}
@@ -0,0 +1,11 @@
+package contract
+
+import (
+	"context"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/embedding_webservice"
+)
+
+type EmbeddingWebService interface {
+	Embed(ctx context.Context, payload embedding_webservice.EmbeddingRequestDao) (embedding_webservice.EmbeddingResponseDao, error)
+}
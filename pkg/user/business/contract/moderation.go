@@ -0,0 +1,11 @@
+package contract
+
+import (
+	"context"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/moderation_webservice"
+)
+
+type ModerationWebService interface {
+	Moderate(ctx context.Context, payload moderation_webservice.ModerationRequestDao) (moderation_webservice.ModerationResponseDao, error)
+}
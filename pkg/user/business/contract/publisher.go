@@ -1,5 +1,7 @@
 package contract
 
+import "context"
+
 type Publisher interface {
-	Publish(topic string, msg interface{}) error
+	Publish(ctx context.Context, topic string, msg interface{}) error
 }
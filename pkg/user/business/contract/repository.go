@@ -2,11 +2,114 @@ package contract
 
 import (
 	"context"
+	"time"
 
 	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Repository interface {
 	// Conversations Repository
-	UpsertConversation(ctx context.Context, userID string, message []repository.Message, summary *repository.Summary) error
+	UpsertConversation(ctx context.Context, userID string, conversationID primitive.ObjectID, parent primitive.ObjectID, message []repository.Message, summary *repository.Summary) error
+	SetConversationPersona(ctx context.Context, userID string, persona string) error
+	SetConversationSettings(ctx context.Context, userID string, settings repository.ModelSettings) error
+	StartConversation(ctx context.Context, userID string) error
+	GetLatestConversation(ctx context.Context, userID string) (repository.Conversation, error)
+	ListConversationMessages(ctx context.Context, conversationID primitive.ObjectID) ([]repository.Message, error)
+	SetActiveLeaf(ctx context.Context, conversationID primitive.ObjectID, leaf primitive.ObjectID) error
+	GetConversation(ctx context.Context, id primitive.ObjectID) (repository.Conversation, error)
+	ListConversations(ctx context.Context, userID string) ([]repository.Conversation, error)
+	InsertConversation(ctx context.Context, conversation repository.Conversation, messages []repository.Message) error
+
+	// Feedback Repository
+	InsertMessages(ctx context.Context, messages []repository.Message) error
+	GetMessage(ctx context.Context, id primitive.ObjectID) (repository.Message, error)
+	SetMessageFeedback(ctx context.Context, id primitive.ObjectID, feedback repository.Feedback) error
+	AggregateFeedback(ctx context.Context, groupBy string, from time.Time, to time.Time) ([]repository.FeedbackAggregate, error)
+	ListRatedMessages(ctx context.Context, from time.Time, to time.Time, rating string) ([]repository.Message, error)
+
+	// Share Repository
+	InsertShare(ctx context.Context, share repository.Share) (repository.Share, error)
+	GetShareByTokenHash(ctx context.Context, tokenHash string) (repository.Share, error)
+	ListShares(ctx context.Context, owner string) ([]repository.Share, error)
+	DeleteShare(ctx context.Context, owner string, id primitive.ObjectID) error
+
+	// Retention Repository
+	PurgeConversations(ctx context.Context, domains []string, excludeDomains []string, before time.Time) (int64, []string, error)
+	PurgeServiceMessages(ctx context.Context, service string, before time.Time) (int64, []string, error)
+	DeleteUserConversations(ctx context.Context, userID string) (int64, error)
+	DeleteShares(ctx context.Context, owner string) error
+	ListConversationImages(ctx context.Context, userID string) ([]string, error)
+	ImageReferenced(ctx context.Context, url string) (bool, error)
+	PseudonymizeUsage(ctx context.Context, principal string, pseudonym string) (int64, error)
+	PseudonymizeAuditEntries(ctx context.Context, principal string, pseudonym string) (int64, error)
+	DeleteUser(ctx context.Context, email string) error
+
+	// Users Repository
+	GetUser(ctx context.Context, email string) (repository.User, error)
+	UpsertUserRole(ctx context.Context, email string, role string) error
+	TouchUser(ctx context.Context, email string, name string) error
+	SetUserBlocked(ctx context.Context, email string, blocked bool) error
+	ListUsers(ctx context.Context, offset int, limit int) ([]repository.User, int64, error)
+
+	// Access Repository
+	GetServiceKey(ctx context.Context, service string) (repository.ServiceKey, error)
+	UpsertServiceKey(ctx context.Context, key repository.ServiceKey) error
+	DeleteServiceKey(ctx context.Context, service string) error
+	GetRoleModels(ctx context.Context, role string) (repository.RoleModels, error)
+	UpsertRoleModels(ctx context.Context, models repository.RoleModels) error
+	DeleteRoleModels(ctx context.Context, role string) error
+
+	// Usage Repository
+	InsertUsage(ctx context.Context, usage repository.Usage) error
+	AggregateUsage(ctx context.Context, groupBy string, from time.Time, to time.Time) ([]repository.UsageAggregate, error)
+
+	// Audit Repository
+	InsertAuditEntry(ctx context.Context, entry repository.AuditEntry) error
+	ListAuditEntries(ctx context.Context, action string, limit int) ([]repository.AuditEntry, error)
+
+	// Batch Repository
+	InsertFile(ctx context.Context, file repository.File, content []byte) (repository.File, error)
+	GetFile(ctx context.Context, id string) (repository.File, error)
+	GetFileContent(ctx context.Context, id string) ([]byte, error)
+	InsertBatch(ctx context.Context, batch repository.Batch) (repository.Batch, error)
+	GetBatch(ctx context.Context, id string) (repository.Batch, error)
+	ListBatches(ctx context.Context, owner string, limit int) ([]repository.Batch, error)
+	StartBatch(ctx context.Context, id primitive.ObjectID, total int) error
+	UpdateBatchCounts(ctx context.Context, id primitive.ObjectID, counts repository.BatchRequestCounts) error
+	TouchBatch(ctx context.Context, id primitive.ObjectID) (repository.Batch, error)
+	FinishBatch(ctx context.Context, batch repository.Batch, from ...string) error
+	ListStaleBatches(ctx context.Context, before time.Time) ([]repository.Batch, error)
+	CancelBatch(ctx context.Context, id string) (repository.Batch, error)
+
+	// Webhook Repository
+	InsertWebhookDelivery(ctx context.Context, delivery repository.WebhookDelivery) (repository.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery repository.WebhookDelivery) error
+	ClaimDueWebhookDelivery(ctx context.Context, lease time.Duration) (repository.WebhookDelivery, error)
+	ClaimStalePendingWebhookDelivery(ctx context.Context, before time.Time) (repository.WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id string) (repository.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, owner string, limit int) ([]repository.WebhookDelivery, error)
+
+	// Persona Repository
+	InsertPersona(ctx context.Context, persona repository.Persona) (repository.Persona, error)
+	GetPersona(ctx context.Context, name string, version int) (repository.Persona, error)
+	ListPersonas(ctx context.Context) ([]repository.Persona, error)
+	ListPersonaVersions(ctx context.Context, name string) ([]repository.Persona, error)
+
+	// Prompt Template Repository
+	InsertPromptTemplate(ctx context.Context, tmpl repository.PromptTemplate) (repository.PromptTemplate, error)
+	GetPromptTemplate(ctx context.Context, name string, version int) (repository.PromptTemplate, error)
+	ListPromptTemplates(ctx context.Context) ([]repository.PromptTemplate, error)
+	ListPromptTemplateVersions(ctx context.Context, name string) ([]repository.PromptTemplate, error)
+
+	// Knowledge Base Repository
+	InsertKnowledgeBase(ctx context.Context, kb repository.KnowledgeBase) (repository.KnowledgeBase, error)
+	GetKnowledgeBase(ctx context.Context, owner string, name string) (repository.KnowledgeBase, error)
+	ListKnowledgeBases(ctx context.Context, owner string) ([]repository.KnowledgeBase, error)
+	DeleteKnowledgeBase(ctx context.Context, id primitive.ObjectID) error
+	InsertDocument(ctx context.Context, doc repository.Document) (repository.Document, error)
+	UpdateDocument(ctx context.Context, doc repository.Document) error
+	GetDocument(ctx context.Context, id string) (repository.Document, error)
+	ListDocuments(ctx context.Context, knowledgeBaseID primitive.ObjectID) ([]repository.Document, error)
+	DeleteDocument(ctx context.Context, id primitive.ObjectID) error
 }
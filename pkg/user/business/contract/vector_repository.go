@@ -0,0 +1,16 @@
+package contract
+
+import (
+	"context"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VectorRepository stores embedded document chunks and searches them by similarity
+type VectorRepository interface {
+	InsertChunks(ctx context.Context, chunks []repository.Chunk) error
+	SearchChunks(ctx context.Context, knowledgeBaseIDs []primitive.ObjectID, vector []float32, topK int, minScore float64) ([]repository.ChunkMatch, error)
+	DeleteDocumentChunks(ctx context.Context, documentID primitive.ObjectID) error
+	DeleteKnowledgeBaseChunks(ctx context.Context, knowledgeBaseID primitive.ObjectID) error
+}
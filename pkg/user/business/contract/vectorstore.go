@@ -0,0 +1,13 @@
+package contract
+
+import (
+	"context"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/vectorstore"
+)
+
+type VectorStore interface {
+	Search(ctx context.Context, namespace string, vector []float32, minScore float64) (vectorstore.Match, bool)
+	Add(ctx context.Context, namespace string, entry vectorstore.Entry) error
+	DeleteOwner(ctx context.Context, owner string) (int, error)
+}
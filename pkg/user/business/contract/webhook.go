@@ -0,0 +1,7 @@
+package contract
+
+import "context"
+
+type WebhookSender interface {
+	Send(ctx context.Context, url string, headers map[string]string, body []byte) (statusCode int, err error)
+}
@@ -0,0 +1,21 @@
+package core
+
+// Sources of a setting that can be changed through the admin API
+const (
+	SourceConfig = "config"
+	SourceAdmin  = "admin"
+)
+
+// ServiceKey is a newly generated service password, it is only ever returned once
+type ServiceKey struct {
+	Service  string `json:"service"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// RoleModels is the model allow-list of a role, empty allows every model
+type RoleModels struct {
+	Role          string   `json:"role"`
+	AllowedModels []string `json:"allowed_models"`
+	Source        string   `json:"source"` // config or admin
+}
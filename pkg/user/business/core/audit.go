@@ -0,0 +1,43 @@
+package core
+
+import (
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+)
+
+// Audit actions
+const (
+	AuditActionContentFiltered = "content_filtered"
+	AuditActionDataErased      = "data_erased"
+)
+
+type AuditEntry struct {
+	ID            string            `json:"id"`
+	Action        string            `json:"action"`
+	Principal     string            `json:"principal"`
+	PrincipalType string            `json:"principal_type"`
+	Tribe         string            `json:"tribe,omitempty"`
+	Detail        map[string]string `json:"detail,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+func ToCoreAuditEntry(e repository.AuditEntry) AuditEntry {
+	return AuditEntry{
+		ID:            e.ID.Hex(),
+		Action:        e.Action,
+		Principal:     e.Principal,
+		PrincipalType: e.PrincipalType,
+		Tribe:         e.Tribe,
+		Detail:        e.Detail,
+		CreatedAt:     e.CreatedAt,
+	}
+}
+
+func ToCoreAuditEntries(entries []repository.AuditEntry) []AuditEntry {
+	res := []AuditEntry{}
+	for _, e := range entries {
+		res = append(res, ToCoreAuditEntry(e))
+	}
+	return res
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+)
+
+const (
+	FilePurposeBatch       = "batch"
+	FilePurposeBatchOutput = "batch_output"
+
+	BatchEndpointChatCompletions = "/v1/chat/completions"
+	BatchCompletionWindow        = "24h"
+
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+type File struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	Purpose   string    `json:"purpose"`
+	Filename  string    `json:"filename"`
+	Bytes     int64     `json:"bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BatchRequest struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata"`
+	CallbackURL      string            `json:"callback_url"`
+}
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type Batch struct {
+	ID               string             `json:"id"`
+	Owner            string             `json:"owner"`
+	Endpoint         string             `json:"endpoint"`
+	InputFileID      string             `json:"input_file_id"`
+	OutputFileID     string             `json:"output_file_id"`
+	ErrorFileID      string             `json:"error_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	Errors           []string           `json:"errors"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata"`
+	CallbackURL      string             `json:"callback_url,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	InProgressAt     *time.Time         `json:"in_progress_at"`
+	CompletedAt      *time.Time         `json:"completed_at"`
+	FailedAt         *time.Time         `json:"failed_at"`
+	ExpiredAt        *time.Time         `json:"expired_at"`
+	CancellingAt     *time.Time         `json:"cancelling_at"`
+	CancelledAt      *time.Time         `json:"cancelled_at"`
+}
+
+// BatchInputLine is a single line of a batch input file, in the OpenAI batch format
+type BatchInputLine struct {
+	CustomID string         `json:"custom_id"`
+	Method   string         `json:"method"`
+	URL      string         `json:"url"`
+	Body     BatchInputBody `json:"body"`
+}
+
+type BatchInputBody struct {
+	Model       string              `json:"model"`
+	Messages    []BatchInputMessage `json:"messages"`
+	Temperature *float64            `json:"temperature"`
+	MaxTokens   *int                `json:"max_tokens"`
+	TopP        *float64            `json:"top_p"`
+}
+
+type BatchInputMessage struct {
+	Role    string       `json:"role"`
+	Content BatchContent `json:"content"`
+}
+
+// BatchContent accepts both the plain string and the content parts form of a message
+type BatchContent []Content
+
+func (b *BatchContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = BatchContent{{Type: "text", Text: &text}}
+		return nil
+	}
+
+	var parts []Content
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	*b = parts
+	return nil
+}
+
+// BatchOutputLine is a single line of a batch output or error file, in the OpenAI batch format
+type BatchOutputLine struct {
+	ID       string               `json:"id"`
+	CustomID string               `json:"custom_id"`
+	Response *BatchOutputResponse `json:"response"`
+	Error    *BatchOutputError    `json:"error"`
+}
+
+type BatchOutputResponse struct {
+	StatusCode int                `json:"status_code"`
+	RequestID  string             `json:"request_id"`
+	Body       GPT4PromptResponse `json:"body"`
+}
+
+type BatchOutputError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func ToCoreMessageRequests(messages []BatchInputMessage) (res []MessageRequest) {
+	for _, v := range messages {
+		res = append(res, MessageRequest{
+			Role:    v.Role,
+			Content: v.Content,
+		})
+	}
+	return res
+}
+
+func ToCoreFile(f repository.File) File {
+	return File{
+		ID:        f.ID.Hex(),
+		Owner:     f.Owner,
+		Purpose:   f.Purpose,
+		Filename:  f.Filename,
+		Bytes:     f.Bytes,
+		CreatedAt: f.CreatedAt,
+	}
+}
+
+func ToCoreBatch(b repository.Batch) Batch {
+	return Batch{
+		ID:               b.ID.Hex(),
+		Owner:            b.Owner,
+		Endpoint:         b.Endpoint,
+		InputFileID:      b.InputFileID,
+		OutputFileID:     b.OutputFileID,
+		ErrorFileID:      b.ErrorFileID,
+		CompletionWindow: b.CompletionWindow,
+		Status:           b.Status,
+		Errors:           b.Errors,
+		RequestCounts: BatchRequestCounts{
+			Total:     b.RequestCounts.Total,
+			Completed: b.RequestCounts.Completed,
+			Failed:    b.RequestCounts.Failed,
+		},
+		Metadata:     b.Metadata,
+		CallbackURL:  b.CallbackURL,
+		CreatedAt:    b.CreatedAt,
+		InProgressAt: b.InProgressAt,
+		CompletedAt:  b.CompletedAt,
+		FailedAt:     b.FailedAt,
+		ExpiredAt:    b.ExpiredAt,
+		CancellingAt: b.CancellingAt,
+		CancelledAt:  b.CancelledAt,
+	}
+}
+
+func ToCoreBatches(batches []repository.Batch) []Batch {
+	res := []Batch{}
+	for _, b := range batches {
+		res = append(res, ToCoreBatch(b))
+	}
+	return res
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConversationTree holds every message of a conversation, each edit or regeneration opens a branch
+type ConversationTree struct {
+	ID         string                `json:"id"`
+	ActiveLeaf string                `json:"active_leaf,omitempty"`
+	Messages   []ConversationMessage `json:"messages"`
+}
+
+type ConversationMessage struct {
+	ID        string    `json:"id"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	Role      string    `json:"role"`
+	Content   []Content `json:"content"`
+	Model     string    `json:"model,omitempty"`
+	Feedback  *Feedback `json:"feedback,omitempty"`
+	Children  []string  `json:"children,omitempty"` // alternative continuations, oldest first
+	Active    bool      `json:"active"`             // on the branch the user is on
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ToCoreConversationTree(conversation repository.Conversation, messages []repository.Message, active map[primitive.ObjectID]bool) ConversationTree {
+	tree := ConversationTree{
+		ID:       conversation.ID.Hex(),
+		Messages: []ConversationMessage{},
+	}
+	if !conversation.ActiveLeaf.IsZero() {
+		tree.ActiveLeaf = conversation.ActiveLeaf.Hex()
+	}
+
+	children := map[primitive.ObjectID][]string{}
+	for _, v := range messages {
+		if !v.ParentID.IsZero() {
+			children[v.ParentID] = append(children[v.ParentID], v.ID.Hex())
+		}
+	}
+	for _, v := range messages {
+		message := ConversationMessage{
+			ID:        v.ID.Hex(),
+			Role:      v.Role,
+			Content:   ToCoreContent(v.Content),
+			Model:     v.Model,
+			Feedback:  ToCoreFeedback(v.Feedback),
+			Children:  children[v.ID],
+			Active:    active[v.ID],
+			CreatedAt: v.Timestamp,
+		}
+		if !v.ParentID.IsZero() {
+			message.ParentID = v.ParentID.Hex()
+		}
+		tree.Messages = append(tree.Messages, message)
+	}
+	return tree
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/embedding_webservice"
+)
+
+const (
+	EmbeddingEncodingFloat  = "float"
+	EmbeddingEncodingBase64 = "base64"
+)
+
+type EmbeddingRequest struct {
+	UserID         string   `json:"user_id"`      // set for SSO users
+	ServiceName    string   `json:"service_name"` // set for backend services
+	Role           string   `json:"role"`
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     *int     `json:"dimensions"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+type EmbeddingResponse struct {
+	Data  []Embedding    `json:"data"`
+	Model string         `json:"model"`
+	Usage EmbeddingUsage `json:"usage"`
+}
+
+// Embedding holds either Vector or, for the base64 encoding format, Base64
+type Embedding struct {
+	Index  int       `json:"index"`
+	Vector []float32 `json:"vector,omitempty"`
+	Base64 string    `json:"base64,omitempty"`
+}
+
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+func ToCoreEmbeddingResponse(res embedding_webservice.EmbeddingResponseDao, encodingFormat string) EmbeddingResponse {
+	data := []Embedding{}
+	for _, v := range res.Data {
+		embedding := Embedding{Index: v.Index}
+		if encodingFormat == EmbeddingEncodingBase64 {
+			embedding.Base64 = encodeEmbedding(v.Embedding)
+		} else {
+			embedding.Vector = v.Embedding
+		}
+		data = append(data, embedding)
+	}
+	return EmbeddingResponse{
+		Data:  data,
+		Model: res.Model,
+		Usage: EmbeddingUsage{
+			PromptTokens: res.Usage.PromptTokens,
+			TotalTokens:  res.Usage.TotalTokens,
+		},
+	}
+}
+
+// encodeEmbedding encodes a vector as base64 little-endian float32, as OpenAI does
+func encodeEmbedding(vector []float32) string {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
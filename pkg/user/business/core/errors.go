@@ -0,0 +1,56 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrModelNotAllowed is returned when the caller's role may not use the requested model
+	ErrModelNotAllowed = errors.New("model not allowed")
+	// ErrQuotaExceeded is returned when the caller has used up their token limit
+	ErrQuotaExceeded = errors.New("token usage limit reached")
+	// ErrNotFound is returned when a resource does not exist or belongs to someone else
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidRequest is returned when a request is well-formed but cannot be accepted
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrCallbackNotAllowed is returned when a callback_url points outside the service's allow-list
+	ErrCallbackNotAllowed = errors.New("callback url not allowed")
+	// ErrSignInRequired is returned when an endpoint open to anonymous callers needs a signed in user for a resource
+	ErrSignInRequired = errors.New("sign in required")
+	// ErrShareNotAllowed is returned when a shared conversation is restricted to viewers of another email domain
+	ErrShareNotAllowed = errors.New("share not allowed")
+	// ErrNotConfigured is returned when a feature needs configuration the deployment does not have
+	ErrNotConfigured = errors.New("not configured")
+	// ErrContentFiltered is returned, wrapped in a ContentFilteredError, when a guardrail rejects a prompt or its answer
+	ErrContentFiltered = errors.New("content_filtered")
+)
+
+// Guardrail stages and the rule reported for the upstream's own content filter
+const (
+	GuardrailStageInput     = "input"
+	GuardrailStageOutput    = "output"
+	GuardrailStageImport    = "import"
+	GuardrailRuleUpstream   = "upstream"
+	GuardrailRuleModeration = "moderation"
+	GuardrailRuleInjection  = "injection"
+	GuardrailRuleLeak       = "leak"
+)
+
+// ContentFilteredError tells which guardrail rejected a prompt or its answer
+type ContentFilteredError struct {
+	Stage  string `json:"stage"`  // input, output or import
+	Rule   string `json:"rule"`   // blocklist, topic, length, moderation, injection, leak or upstream
+	Detail string `json:"detail"` // the pattern, topic or categories that matched
+}
+
+func (e *ContentFilteredError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%s: %s rejected by the %s rule", ErrContentFiltered, e.Stage, e.Rule)
+	}
+	return fmt.Sprintf("%s: %s rejected by the %s rule (%s)", ErrContentFiltered, e.Stage, e.Rule, e.Detail)
+}
+
+func (e *ContentFilteredError) Unwrap() error {
+	return ErrContentFiltered
+}
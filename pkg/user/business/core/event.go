@@ -0,0 +1,67 @@
+package core
+
+import "time"
+
+// Event types, the topic of an event is the configured prefix followed by its type
+const (
+	EventPromptCompleted = "prompt.completed"
+	EventQuotaExceeded   = "quota.exceeded"
+	EventContextCleared  = "context.cleared"
+	EventSummaryCreated  = "summary.created"
+	EventLoginSucceeded  = "login.succeeded"
+	EventLoginFailed     = "login.failed"
+
+	// EventSchemaVersion is bumped on breaking changes of any event data
+	EventSchemaVersion = "1"
+)
+
+// Event is the envelope of every published domain event
+type Event struct {
+	ID            string      `json:"id"`
+	Type          string      `json:"type"`
+	SchemaVersion string      `json:"schema_version"`
+	Source        string      `json:"source"`
+	CorrelationID string      `json:"correlation_id"`
+	OccurredAt    time.Time   `json:"occurred_at"`
+	Data          interface{} `json:"data"`
+}
+
+type PromptCompletedEvent struct {
+	Principal        string  `json:"principal"`
+	PrincipalType    string  `json:"principal_type"`
+	Tribe            string  `json:"tribe,omitempty"`
+	Kind             string  `json:"kind"`
+	Model            string  `json:"model"`
+	Upstream         string  `json:"upstream"`
+	Status           string  `json:"status"`
+	Error            string  `json:"error,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	LatencyMs        int64   `json:"latency_ms"`
+	Template         string  `json:"template,omitempty"` // name@vN of the prompt template, if any
+}
+
+type QuotaExceededEvent struct {
+	Principal     string `json:"principal"`
+	PrincipalType string `json:"principal_type"`
+	TokenUsage    int    `json:"token_usage"`
+	TokenLimit    int    `json:"token_limit"`
+}
+
+type ContextClearedEvent struct {
+	UserID string `json:"user_id"`
+}
+
+type SummaryCreatedEvent struct {
+	UserID      string `json:"user_id"`
+	Model       string `json:"model"`
+	TotalTokens int    `json:"total_tokens"`
+}
+
+type LoginEvent struct {
+	Provider string `json:"provider"`
+	Email    string `json:"email,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
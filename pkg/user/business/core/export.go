@@ -0,0 +1,173 @@
+package core
+
+import (
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConversationExport is a conversation with all its branches, the JSON export format and the import payload
+type ConversationExport struct {
+	ID         string            `json:"id,omitempty"`
+	Persona    string            `json:"persona,omitempty"`
+	Settings   *ModelSettings    `json:"settings,omitempty"`
+	ActiveLeaf string            `json:"active_leaf,omitempty"` // last message of the current branch, defaults to the last message
+	Summaries  []ExportedSummary `json:"summaries,omitempty"`
+	Messages   []ExportedMessage `json:"messages"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+type ExportedMessage struct {
+	ID        string    `json:"id"`
+	ParentID  string    `json:"parent_id,omitempty"` // unset for a first message, on import a message without id follows the previous one
+	Role      string    `json:"role"`
+	Content   []Content `json:"content"`
+	Model     string    `json:"model,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportedSummary struct {
+	Content []Content `json:"content"`
+	UpTo    string    `json:"up_to"` // last message covered by the summary
+}
+
+func ToCoreConversationExport(conversation repository.Conversation, messages []repository.Message) ConversationExport {
+	export := ConversationExport{
+		ID:        conversation.ID.Hex(),
+		Persona:   conversation.Persona,
+		Messages:  ToCoreExportedMessages(messages),
+		CreatedAt: conversation.CreatedAt,
+		UpdatedAt: conversation.UpdatedAt,
+	}
+	if conversation.Settings != nil {
+		settings := ToCoreModelSettings(*conversation.Settings)
+		export.Settings = &settings
+	}
+	if !conversation.ActiveLeaf.IsZero() {
+		export.ActiveLeaf = conversation.ActiveLeaf.Hex()
+	}
+	for _, v := range conversation.Summaries {
+		if v.UpTo.IsZero() {
+			continue
+		}
+		export.Summaries = append(export.Summaries, ExportedSummary{
+			Content: ToCoreContent(v.Content),
+			UpTo:    v.UpTo.Hex(),
+		})
+	}
+	return export
+}
+
+func ToCoreExportedMessages(messages []repository.Message) []ExportedMessage {
+	res := []ExportedMessage{}
+	for _, v := range messages {
+		message := ExportedMessage{
+			ID:        v.ID.Hex(),
+			Role:      v.Role,
+			Content:   ToCoreContent(v.Content),
+			Model:     v.Model,
+			CreatedAt: v.Timestamp,
+		}
+		if !v.ParentID.IsZero() {
+			message.ParentID = v.ParentID.Hex()
+		}
+		res = append(res, message)
+	}
+	return res
+}
+
+func ToCoreModelSettings(v repository.ModelSettings) ModelSettings {
+	return ModelSettings{
+		Model:       v.Model,
+		Temperature: v.Temperature,
+		TopP:        v.TopP,
+		MaxTokens:   v.MaxTokens,
+		Stop:        v.Stop,
+		Seed:        v.Seed,
+	}
+}
+
+// ActivePath returns the messages of the current branch, from the first message down to the active leaf
+func (v ConversationExport) ActivePath() []ExportedMessage {
+	byID := map[string]ExportedMessage{}
+	for _, m := range v.Messages {
+		byID[m.ID] = m
+	}
+	leaf := v.ActiveLeaf
+	if leaf == "" && len(v.Messages) > 0 {
+		leaf = v.Messages[len(v.Messages)-1].ID
+	}
+
+	var path []ExportedMessage
+	for id := leaf; id != ""; {
+		message, found := byID[id]
+		if !found {
+			break
+		}
+		path = append([]ExportedMessage{message}, path...)
+		id = message.ParentID
+	}
+	return path
+}
+
+// ToRepoConversation converts an imported conversation, the messages get new IDs and their parents are mapped to them
+func ToRepoConversation(userID string, v ConversationExport) (repository.Conversation, []repository.Message) {
+	now := time.Now()
+	conversation := repository.Conversation{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Summaries: []repository.Summary{},
+		Persona:   v.Persona,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: now,
+	}
+	if conversation.CreatedAt.IsZero() {
+		conversation.CreatedAt = now
+	}
+	if v.Settings != nil {
+		settings := ToRepoModelSettings(*v.Settings)
+		conversation.Settings = &settings
+	}
+
+	ids := map[string]primitive.ObjectID{}
+	var messages []repository.Message
+	var previous primitive.ObjectID
+	for _, m := range v.Messages {
+		message := repository.Message{
+			ID:             primitive.NewObjectID(),
+			ConversationID: conversation.ID,
+			ParentID:       previous,
+			Role:           m.Role,
+			Content:        ToContentRepo(m.Content),
+			Model:          m.Model,
+			Timestamp:      m.CreatedAt,
+		}
+		if m.ID != "" || m.ParentID != "" {
+			message.ParentID = ids[m.ParentID]
+		}
+		if message.Timestamp.IsZero() {
+			message.Timestamp = now
+		}
+		if m.ID != "" {
+			ids[m.ID] = message.ID
+		}
+		previous = message.ID
+		messages = append(messages, message)
+	}
+
+	conversation.ActiveLeaf = previous
+	if v.ActiveLeaf != "" {
+		conversation.ActiveLeaf = ids[v.ActiveLeaf]
+	}
+	// only summaries written by the proxy speak as the system, an imported one could carry instructions
+	for _, s := range v.Summaries {
+		conversation.Summaries = append(conversation.Summaries, repository.Summary{
+			Role:    "assistant",
+			Content: ToContentRepo(s.Content),
+			UpTo:    ids[s.UpTo],
+		})
+	}
+	return conversation, messages
+}
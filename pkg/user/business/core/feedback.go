@@ -0,0 +1,102 @@
+package core
+
+import (
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+)
+
+// Feedback ratings
+const (
+	FeedbackRatingUp   = "up"
+	FeedbackRatingDown = "down"
+)
+
+type FeedbackRequest struct {
+	MessageID string `json:"message_id"`
+	Rater     string `json:"rater"` // user email or service name, only the one who was answered may rate
+	Rating    string `json:"rating"`
+	Comment   string `json:"comment"`
+}
+
+type Feedback struct {
+	Rating    string    `json:"rating"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FeedbackReport struct {
+	Key      string  `json:"key"`
+	Ratings  int     `json:"ratings"`
+	Up       int     `json:"up"`
+	Down     int     `json:"down"`
+	Comments int     `json:"comments"`
+	Score    float64 `json:"score"` // share of thumbs up
+}
+
+// RatedExchange is a rated answer with the messages that led to it, one line of a feedback export
+type RatedExchange struct {
+	MessageID string           `json:"message_id"`
+	Rating    string           `json:"rating"`
+	Comment   string           `json:"comment,omitempty"`
+	Model     string           `json:"model,omitempty"`
+	Persona   string           `json:"persona,omitempty"`
+	Template  string           `json:"template,omitempty"`
+	Service   string           `json:"service,omitempty"`
+	Messages  []MessageRequest `json:"messages"`
+	RatedAt   time.Time        `json:"rated_at"`
+}
+
+func ToCoreFeedback(v *repository.Feedback) *Feedback {
+	if v == nil {
+		return nil
+	}
+	return &Feedback{
+		Rating:    v.Rating,
+		Comment:   v.Comment,
+		CreatedAt: v.CreatedAt,
+	}
+}
+
+func ToCoreFeedbackReports(aggregates []repository.FeedbackAggregate) []FeedbackReport {
+	res := []FeedbackReport{}
+	for _, v := range aggregates {
+		report := FeedbackReport{
+			Key:      v.Key,
+			Ratings:  v.Ratings,
+			Up:       v.Up,
+			Down:     v.Down,
+			Comments: v.Comments,
+		}
+		if v.Ratings > 0 {
+			report.Score = float64(v.Up) / float64(v.Ratings)
+		}
+		res = append(res, report)
+	}
+	return res
+}
+
+// ToCoreRatedExchange converts a rated message and the path leading to it, the rated message last
+func ToCoreRatedExchange(path []repository.Message) RatedExchange {
+	rated := path[len(path)-1]
+	exchange := RatedExchange{
+		MessageID: rated.ID.Hex(),
+		Model:     rated.Model,
+		Persona:   rated.Persona,
+		Template:  rated.Template,
+		Service:   rated.Service,
+		Messages:  []MessageRequest{},
+	}
+	if rated.Feedback != nil {
+		exchange.Rating = rated.Feedback.Rating
+		exchange.Comment = rated.Feedback.Comment
+		exchange.RatedAt = rated.Feedback.CreatedAt
+	}
+	for _, v := range path {
+		exchange.Messages = append(exchange.Messages, MessageRequest{
+			Role:    v.Role,
+			Content: ToCoreContent(v.Content),
+		})
+	}
+	return exchange
+}
@@ -25,8 +25,9 @@ type Message struct {
 }
 
 type Choices struct {
-	Index   int     `json:"index"`
-	Message Message `json:"message"`
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason,omitempty"`
 }
 
 type Usage struct {
@@ -55,6 +56,7 @@ func ToCoreChoices(c []gpt4_webservice.Choices) []Choices {
 				Content: choice.Message.Content,
 				Role:    choice.Message.Role,
 			},
+			FinishReason: choice.FinishReason,
 		})
 	}
 	return coreChoices
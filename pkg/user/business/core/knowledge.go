@@ -0,0 +1,86 @@
+package core
+
+import (
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+)
+
+const (
+	DocumentStatusProcessing = "processing"
+	DocumentStatusReady      = "ready"
+	DocumentStatusFailed     = "failed"
+)
+
+type KnowledgeBase struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Document struct {
+	ID              string    `json:"id"`
+	KnowledgeBaseID string    `json:"knowledge_base_id"`
+	Filename        string    `json:"filename"`
+	MimeType        string    `json:"mime_type"`
+	Bytes           int64     `json:"bytes"`
+	Chunks          int       `json:"chunks"`
+	Status          string    `json:"status"`
+	Error           string    `json:"error,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Citation is a knowledge base chunk that was injected into a prompt, Index matches the [n] marker in the context
+type Citation struct {
+	Index         int     `json:"index"`
+	KnowledgeBase string  `json:"knowledge_base"`
+	DocumentID    string  `json:"document_id"`
+	Filename      string  `json:"filename"`
+	Chunk         int     `json:"chunk"`
+	Score         float64 `json:"score"`
+	Snippet       string  `json:"snippet"`
+}
+
+func ToCoreKnowledgeBase(kb repository.KnowledgeBase) KnowledgeBase {
+	return KnowledgeBase{
+		ID:          kb.ID.Hex(),
+		Name:        kb.Name,
+		Description: kb.Description,
+		CreatedAt:   kb.CreatedAt,
+		UpdatedAt:   kb.UpdatedAt,
+	}
+}
+
+func ToCoreKnowledgeBases(kbs []repository.KnowledgeBase) []KnowledgeBase {
+	res := []KnowledgeBase{}
+	for _, kb := range kbs {
+		res = append(res, ToCoreKnowledgeBase(kb))
+	}
+	return res
+}
+
+func ToCoreDocument(doc repository.Document) Document {
+	return Document{
+		ID:              doc.ID.Hex(),
+		KnowledgeBaseID: doc.KnowledgeBaseID.Hex(),
+		Filename:        doc.Filename,
+		MimeType:        doc.MimeType,
+		Bytes:           doc.Bytes,
+		Chunks:          doc.Chunks,
+		Status:          doc.Status,
+		Error:           doc.Error,
+		CreatedAt:       doc.CreatedAt,
+		UpdatedAt:       doc.UpdatedAt,
+	}
+}
+
+func ToCoreDocuments(docs []repository.Document) []Document {
+	res := []Document{}
+	for _, doc := range docs {
+		res = append(res, ToCoreDocument(doc))
+	}
+	return res
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+)
+
+type PersonaRequest struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	SystemPrompt string   `json:"system_prompt"`
+	Model        string   `json:"model"`
+	Temperature  *float64 `json:"temperature"`
+	TopP         *float64 `json:"top_p"`
+	MaxTokens    *int     `json:"max_tokens"`
+	CreatedBy    string   `json:"created_by"`
+}
+
+type Persona struct {
+	Name         string    `json:"name"`
+	Version      int       `json:"version"`
+	Ref          string    `json:"ref"` // name@vN, pins this version
+	Description  string    `json:"description"`
+	SystemPrompt string    `json:"system_prompt"`
+	Model        string    `json:"model,omitempty"`
+	Temperature  *float64  `json:"temperature,omitempty"`
+	TopP         *float64  `json:"top_p,omitempty"`
+	MaxTokens    *int      `json:"max_tokens,omitempty"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// VersionedRef returns the name@vN reference pinning a version of a persona or prompt template
+func VersionedRef(name string, version int) string {
+	return fmt.Sprintf("%s@v%d", name, version)
+}
+
+func ToCorePersona(p repository.Persona) Persona {
+	return Persona{
+		Name:         p.Name,
+		Version:      p.Version,
+		Ref:          VersionedRef(p.Name, p.Version),
+		Description:  p.Description,
+		SystemPrompt: p.SystemPrompt,
+		Model:        p.Model,
+		Temperature:  p.Temperature,
+		TopP:         p.TopP,
+		MaxTokens:    p.MaxTokens,
+		CreatedBy:    p.CreatedBy,
+		CreatedAt:    p.CreatedAt,
+	}
+}
+
+func ToCorePersonas(personas []repository.Persona) []Persona {
+	res := []Persona{}
+	for _, p := range personas {
+		res = append(res, ToCorePersona(p))
+	}
+	return res
+}
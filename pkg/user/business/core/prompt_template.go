@@ -0,0 +1,74 @@
+package core
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+)
+
+type PromptTemplateRequest struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Messages    []TemplateMessage  `json:"messages"`
+	Variables   []TemplateVariable `json:"variables"`
+	CreatedBy   string             `json:"created_by"`
+}
+
+type PromptTemplate struct {
+	Name        string             `json:"name"`
+	Version     int                `json:"version"`
+	Ref         string             `json:"ref"` // name@vN, pins this version
+	Description string             `json:"description"`
+	Messages    []TemplateMessage  `json:"messages"`
+	Variables   []TemplateVariable `json:"variables"`
+	CreatedBy   string             `json:"created_by"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+type TemplateMessage struct {
+	Role     string `json:"role"`
+	Template string `json:"template"`
+}
+
+type TemplateVariable struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Required    bool        `json:"required"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+func ToCorePromptTemplate(t repository.PromptTemplate) PromptTemplate {
+	messages := []TemplateMessage{}
+	for _, m := range t.Messages {
+		messages = append(messages, TemplateMessage{Role: m.Role, Template: m.Template})
+	}
+	variables := []TemplateVariable{}
+	for _, v := range t.Variables {
+		variable := TemplateVariable{Name: v.Name, Type: v.Type, Required: v.Required, Description: v.Description}
+		if v.Default != "" {
+			json.Unmarshal([]byte(v.Default), &variable.Default)
+		}
+		variables = append(variables, variable)
+	}
+
+	return PromptTemplate{
+		Name:        t.Name,
+		Version:     t.Version,
+		Ref:         VersionedRef(t.Name, t.Version),
+		Description: t.Description,
+		Messages:    messages,
+		Variables:   variables,
+		CreatedBy:   t.CreatedBy,
+		CreatedAt:   t.CreatedAt,
+	}
+}
+
+func ToCorePromptTemplates(templates []repository.PromptTemplate) []PromptTemplate {
+	res := []PromptTemplate{}
+	for _, t := range templates {
+		res = append(res, ToCorePromptTemplate(t))
+	}
+	return res
+}
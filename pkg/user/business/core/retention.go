@@ -0,0 +1,11 @@
+package core
+
+// ErasureReport counts what was removed, or pseudonymized, when the data of a user was erased
+type ErasureReport struct {
+	Conversations  int64 `json:"conversations"`
+	KnowledgeBases int   `json:"knowledge_bases"`
+	Images         int   `json:"images"`
+	CacheEntries   int   `json:"cache_entries"` // semantic cache entries of their prompts
+	UsageRecords   int64 `json:"usage_records"` // pseudonymized, kept for chargeback
+	AuditEntries   int64 `json:"audit_entries"` // pseudonymized, kept for review
+}
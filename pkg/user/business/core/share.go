@@ -0,0 +1,58 @@
+package core
+
+import (
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+)
+
+type ShareRequest struct {
+	ConversationID string        `json:"conversation_id"`
+	Owner          string        `json:"owner"`
+	ExpiresIn      time.Duration `json:"expires_in"`  // zero for a share that doesn't expire
+	SameDomain     bool          `json:"same_domain"` // only users signed in with the owner's email domain may view it
+}
+
+type Share struct {
+	ID             string     `json:"id"`
+	Token          string     `json:"token,omitempty"` // only returned when the share is created
+	ConversationID string     `json:"conversation_id"`
+	Domain         string     `json:"domain,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// SharedConversation is what viewers of a share see, the owner isn't disclosed
+type SharedConversation struct {
+	Persona   string            `json:"persona,omitempty"`
+	Messages  []ExportedMessage `json:"messages"`
+	SharedAt  time.Time         `json:"shared_at"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+}
+
+func ToCoreShare(v repository.Share) Share {
+	return Share{
+		ID:             v.ID.Hex(),
+		ConversationID: v.ConversationID.Hex(),
+		Domain:         v.Domain,
+		ExpiresAt:      v.ExpiresAt,
+		CreatedAt:      v.CreatedAt,
+	}
+}
+
+func ToCoreShares(shares []repository.Share) []Share {
+	res := []Share{}
+	for _, v := range shares {
+		res = append(res, ToCoreShare(v))
+	}
+	return res
+}
+
+func ToCoreSharedConversation(v repository.Share) SharedConversation {
+	return SharedConversation{
+		Persona:   v.Persona,
+		Messages:  ToCoreExportedMessages(v.Messages),
+		SharedAt:  v.CreatedAt,
+		ExpiresAt: v.ExpiresAt,
+	}
+}
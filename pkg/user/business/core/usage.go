@@ -0,0 +1,31 @@
+package core
+
+import "github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+
+type UsageReport struct {
+	Key              string  `json:"key"`
+	Requests         int     `json:"requests"`
+	Errors           int     `json:"errors"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+}
+
+func ToCoreUsageReports(aggregates []repository.UsageAggregate) []UsageReport {
+	res := []UsageReport{}
+	for _, v := range aggregates {
+		res = append(res, UsageReport{
+			Key:              v.Key,
+			Requests:         v.Requests,
+			Errors:           v.Errors,
+			PromptTokens:     v.PromptTokens,
+			CompletionTokens: v.CompletionTokens,
+			TotalTokens:      v.TotalTokens,
+			Cost:             v.Cost,
+			AvgLatencyMs:     v.AvgLatencyMs,
+		})
+	}
+	return res
+}
@@ -1,18 +1,54 @@
 package core
 
 import (
+	"time"
+
 	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
 )
 
 type UserPromtGPTRequest struct {
-	Content []Content `json:"content"`
-	UserID  string    `json:"user_id"`
+	Content        []Content     `json:"content"`
+	UserID         string        `json:"user_id"`
+	Role           string        `json:"role"`
+	KnowledgeBases []string      `json:"knowledge_bases"` // knowledge bases searched for context
+	Persona        string        `json:"persona"`         // name or name@vN, replaces the persona of the conversation
+	Settings       ModelSettings `json:"settings"`        // merged into the settings of the conversation
+
+	PromptMessageID string `json:"prompt_message_id,omitempty"` // set when answering a prompt already in the conversation, e.g. on regeneration
+}
+
+// ModelSettings are model parameters chosen by a user, unset fields keep their current value
+type ModelSettings struct {
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+}
+
+func ToRepoModelSettings(v ModelSettings) repository.ModelSettings {
+	return repository.ModelSettings{
+		Model:       v.Model,
+		Temperature: v.Temperature,
+		TopP:        v.TopP,
+		MaxTokens:   v.MaxTokens,
+		Stop:        v.Stop,
+		Seed:        v.Seed,
+	}
 }
 
 type UserPromGPTResponse struct {
 	GPT4PromptResponse
-	UserID string `json:"user_id"`
+	UserID     string     `json:"user_id"`
+	Cached     bool       `json:"cached"`
+	Similarity float64    `json:"similarity,omitempty"` // set on semantic cache hits
+	Citations  []Citation `json:"citations,omitempty"`  // knowledge base chunks injected into the prompt
+	Redactions []string   `json:"redactions,omitempty"` // detectors that redacted parts of the prompt
+
+	PromptMessageID string `json:"prompt_message_id,omitempty"` // stored user message, edits are made on it
+	MessageID       string `json:"message_id,omitempty"`        // stored assistant message
 }
 
 type UserTokenUsage struct {
@@ -21,6 +57,15 @@ type UserTokenUsage struct {
 	Warning    bool `json:"warning"`
 }
 
+type UserDetail struct {
+	Email      string         `json:"email"`
+	Name       string         `json:"name"`
+	Role       string         `json:"role"`
+	Blocked    bool           `json:"blocked"`
+	LastSeenAt time.Time      `json:"last_seen_at"`
+	Usage      UserTokenUsage `json:"usage"`
+}
+
 type Content struct {
 	Type     string    `json:"type" validate:"required"`
 	Text     *string   `json:"text,omitempty"`
@@ -28,7 +73,8 @@ type Content struct {
 }
 
 type ImageURL struct {
-	URL string `json:"url"`
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"` // auto, low or high
 }
 
 func ToWebServiceUserPromtGPTContentRequest(req []Content) (res []gpt4_webservice.Content) {
@@ -40,7 +86,8 @@ func ToWebServiceUserPromtGPTContentRequest(req []Content) (res []gpt4_webservic
 		}
 		if v.ImageURL != nil {
 			content.ImageURL = &gpt4_webservice.ImageURL{
-				URL: v.ImageURL.URL,
+				URL:    v.ImageURL.URL,
+				Detail: v.ImageURL.Detail,
 			}
 		}
 		res = append(res, content)
@@ -57,8 +104,36 @@ func ToContentRepo(req []Content) (res []repository.Content) {
 		}
 		if v.ImageURL != nil {
 			content.ImageURL = v.ImageURL.URL
+			content.ImageDetail = v.ImageURL.Detail
+		}
+		res = append(res, content)
+	}
+	return res
+}
+
+func ToCoreContent(req []repository.Content) (res []Content) {
+	for _, v := range req {
+		var content Content
+		content.Type = v.Type
+		content.Text = v.Text
+		if v.ImageURL != "" {
+			content.ImageURL = &ImageURL{
+				URL:    v.ImageURL,
+				Detail: v.ImageDetail,
+			}
 		}
 		res = append(res, content)
 	}
 	return res
 }
+
+func ToCoreUserDetail(user repository.User, usage UserTokenUsage) UserDetail {
+	return UserDetail{
+		Email:      user.Email,
+		Name:       user.Name,
+		Role:       user.Role,
+		Blocked:    user.Blocked,
+		LastSeenAt: user.LastSeenAt,
+		Usage:      usage,
+	}
+}
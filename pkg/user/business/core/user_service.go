@@ -5,10 +5,19 @@ import "github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
 type ServicePromptRequest struct {
 	Model       string           `json:"model"`
 	ServiceName string           `json:"service_name"`
-	Temperature float64          `json:"temperature"`
+	Role        string           `json:"role"`
+	Temperature *float64         `json:"temperature"` // nil leaves it to the persona
 	MaxTokens   int              `json:"max_tokens"`
 	TopP        float64          `json:"top_p"`
 	Messages    []MessageRequest `json:"messages"`
+	NoCache     bool             `json:"no_cache"` // skip the response cache lookup
+	NoStore     bool             `json:"no_store"` // don't store the response in the response cache
+
+	KnowledgeBases []string `json:"knowledge_bases"` // knowledge bases searched for context
+	Persona        string   `json:"persona"`         // name or name@vN, overrides the persona bound to the service
+
+	Template  string                 `json:"template"`  // name or name@vN, its rendered messages lead Messages
+	Variables map[string]interface{} `json:"variables"` // values of the template variables
 }
 
 type MessageRequest struct {
@@ -28,5 +37,27 @@ func ToWebServicePromtGPTMsgRequest(req []MessageRequest) (res []gpt4_webservice
 
 type ServicePromGPTResponse struct {
 	GPT4PromptResponse
-	UserID string `json:"user_id"`
+	UserID     string     `json:"user_id"`
+	Cached     bool       `json:"cached"`
+	Similarity float64    `json:"similarity,omitempty"` // set on semantic cache hits
+	Citations  []Citation `json:"citations,omitempty"`  // knowledge base chunks injected into the prompt
+	Redactions []string   `json:"redactions,omitempty"` // detectors that redacted parts of the prompt
+
+	Inspection *PromptInspection `json:"inspection,omitempty"` // injection and leak findings
+	MessageID  string            `json:"message_id,omitempty"` // stored answer, feedback is given on it
+}
+
+// Leaks reported in a PromptInspection
+const (
+	LeakSystemPrompt = "system_prompt"
+	LeakSecret       = "secret"
+)
+
+// PromptInspection reports what the injection and leak detectors found in a service prompt and its answer
+type PromptInspection struct {
+	InjectionScore   float64  `json:"injection_score"`
+	InjectionSignals []string `json:"injection_signals,omitempty"`
+	Stripped         bool     `json:"stripped,omitempty"`       // injected instructions were removed before the prompt was sent
+	Leaks            []string `json:"leaks,omitempty"`          // system_prompt or secret
+	LeaksStripped    bool     `json:"leaks_stripped,omitempty"` // leaked text was removed from the answer
 }
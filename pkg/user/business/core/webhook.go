@@ -0,0 +1,91 @@
+package core
+
+import (
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+)
+
+const (
+	WebhookEventServicePrompt = "service_prompt.completed"
+	WebhookEventBatch         = "batch.completed"
+
+	WebhookStatusPending    = "pending"
+	WebhookStatusDelivering = "delivering"
+	WebhookStatusSucceeded  = "succeeded"
+	WebhookStatusFailed     = "failed"
+
+	WebhookHeaderSignature  = "X-Signature-256"
+	WebhookHeaderTimestamp  = "X-Signature-Timestamp"
+	WebhookHeaderDeliveryID = "X-Delivery-ID"
+	WebhookHeaderEvent      = "X-Webhook-Event"
+)
+
+type WebhookAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+type WebhookDelivery struct {
+	ID            string           `json:"id"`
+	Event         string           `json:"event"`
+	CallbackURL   string           `json:"callback_url"`
+	CorrelationID string           `json:"correlation_id"`
+	Status        string           `json:"status"`
+	Attempts      []WebhookAttempt `json:"attempts"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+// ServicePromptCallback is the body POSTed to the callback_url of an async service prompt
+type ServicePromptCallback struct {
+	DeliveryID    string                  `json:"delivery_id"`
+	CorrelationID string                  `json:"correlation_id"`
+	Status        string                  `json:"status"` // completed or failed
+	Result        *ServicePromGPTResponse `json:"result,omitempty"`
+	Error         *CallbackError          `json:"error,omitempty"`
+}
+
+// BatchCallback is the body POSTed to the callback_url of a batch once it ends
+type BatchCallback struct {
+	DeliveryID    string `json:"delivery_id"`
+	CorrelationID string `json:"correlation_id"`
+	Batch         Batch  `json:"batch"`
+}
+
+type CallbackError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func ToCoreWebhookDelivery(d repository.WebhookDelivery) WebhookDelivery {
+	attempts := []WebhookAttempt{}
+	for _, a := range d.Attempts {
+		attempts = append(attempts, WebhookAttempt{
+			At:         a.At,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			DurationMs: a.DurationMs,
+		})
+	}
+	return WebhookDelivery{
+		ID:            d.ID.Hex(),
+		Event:         d.Event,
+		CallbackURL:   d.CallbackURL,
+		CorrelationID: d.CorrelationID,
+		Status:        d.Status,
+		Attempts:      attempts,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+	}
+}
+
+func ToCoreWebhookDeliveries(deliveries []repository.WebhookDelivery) []WebhookDelivery {
+	res := []WebhookDelivery{}
+	for _, d := range deliveries {
+		res = append(res, ToCoreWebhookDelivery(d))
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+package business
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/redact"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/embedding_webservice"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Embeddings embeds a batch of inputs, the tokens count against the caller's quota like chat prompts
+func (u UserService) Embeddings(ctx context.Context, payload core.EmbeddingRequest) (res core.EmbeddingResponse, err error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::Embeddings")
+	// defer add metadata error to span
+	defer func() {
+		if err != nil {
+			apm.AddEvent(ctx, "Error",
+				attribute.String("error", err.Error()),
+			)
+		}
+	}()
+	defer apm.EndTransaction(span)
+
+	// the configured embeddings model is always allowed, others follow the role's allow-list
+	if payload.Model == "" {
+		payload.Model = u.cfg.Embeddings.Model
+	}
+	if payload.Model != u.cfg.Embeddings.Model {
+		if err = u.validateModel(ctx, payload.Role, payload.Model); err != nil {
+			return core.EmbeddingResponse{}, err
+		}
+	}
+
+	var p principal
+	if payload.ServiceName != "" {
+		p = u.servicePrincipal(payload.ServiceName)
+		err = u.validateServiceTokenUsage(ctx, payload.ServiceName)
+	} else {
+		p = u.userPrincipal(payload.UserID)
+		err = u.validateUserQuota(ctx, payload.UserID)
+	}
+	if err != nil {
+		return core.EmbeddingResponse{}, err
+	}
+
+	// inputs leave the network like prompts do, so the same redaction policy applies
+	payload.Input = redactTexts(u.principalRedactor(p), payload.Input, redact.Vault{})
+
+	embedResponse, err := u.embed(ctx, p, usageKindEmbedding, embedding_webservice.EmbeddingRequestDao{
+		Model:      payload.Model,
+		Input:      payload.Input,
+		Dimensions: payload.Dimensions,
+	})
+	if err != nil {
+		return core.EmbeddingResponse{}, fmt.Errorf("error in embeddings: %v", err)
+	}
+	res = core.ToCoreEmbeddingResponse(embedResponse, payload.EncodingFormat)
+
+	// add metadata token usage to span
+	apm.AddEvent(ctx, "TokenUsage",
+		attribute.String("user_id", p.Name),
+		attribute.Int("token_usage", res.Usage.TotalTokens),
+	)
+
+	if payload.ServiceName != "" {
+		err = u.addServiceTokenUsage(ctx, payload.ServiceName, res.Usage.TotalTokens)
+	} else {
+		err = u.addUserTokenUsage(ctx, payload.UserID, res.Usage.TotalTokens)
+	}
+	if err != nil {
+		return core.EmbeddingResponse{}, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,49 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/correlation"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// publish emits a domain event in the background, nothing is published when events are disabled
+func (u UserService) publish(ctx context.Context, eventType string, data interface{}) {
+	if u.publisher == nil || !u.cfg.Events.Enabled {
+		return
+	}
+
+	event := core.Event{
+		ID:            primitive.NewObjectID().Hex(),
+		Type:          eventType,
+		SchemaVersion: core.EventSchemaVersion,
+		Source:        u.cfg.App.Name,
+		CorrelationID: correlation.FromContext(ctx),
+		OccurredAt:    time.Now(),
+		Data:          data,
+	}
+	if event.CorrelationID == "" {
+		event.CorrelationID = event.ID
+	}
+	topic := fmt.Sprintf("%s.%s", u.cfg.Events.TopicPrefix, eventType)
+
+	// the request context may be cancelled before the event is out
+	go func() {
+		err := u.publisher.Publish(correlation.WithID(context.Background(), event.CorrelationID), topic, event)
+		if err != nil {
+			fmt.Println("Error publishing event:", err)
+		}
+	}()
+}
+
+// RecordLogin publishes the outcome of an SSO login
+func (u UserService) RecordLogin(ctx context.Context, provider string, email string, err error) {
+	if err != nil {
+		u.publish(ctx, core.EventLoginFailed, core.LoginEvent{Provider: provider, Email: email, Reason: err.Error()})
+		return
+	}
+	u.publish(ctx, core.EventLoginSucceeded, core.LoginEvent{Provider: provider, Email: email})
+}
@@ -0,0 +1,222 @@
+package business
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/redact"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// importMaxPath is the longest active branch an import may have, it becomes the cached context of the user
+const importMaxPath = 50
+
+// ExportConversations returns one conversation of a user, or all of them when conversationID is empty.
+// Images are inlined as data URIs so the export stands on its own.
+func (u UserService) ExportConversations(ctx context.Context, userID string, conversationID string) ([]core.ConversationExport, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ExportConversations")
+	defer apm.EndTransaction(span)
+
+	var conversations []repository.Conversation
+	if conversationID != "" {
+		id, err := primitive.ObjectIDFromHex(conversationID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: conversation %s", core.ErrNotFound, conversationID)
+		}
+		conversation, err := u.repo.GetConversation(ctx, id)
+		if err != nil || conversation.UserID != userID {
+			return nil, fmt.Errorf("%w: conversation %s", core.ErrNotFound, conversationID)
+		}
+		conversations = []repository.Conversation{conversation}
+	} else {
+		var err error
+		conversations, err = u.repo.ListConversations(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := []core.ConversationExport{}
+	for _, conversation := range conversations {
+		messages, err := u.repo.ListConversationMessages(ctx, conversation.ID)
+		if err != nil {
+			return nil, err
+		}
+		// conversations opened by clearing the context stay empty until the next prompt
+		if len(messages) == 0 && conversationID == "" {
+			continue
+		}
+
+		export := core.ToCoreConversationExport(conversation, messages)
+		for i := range export.Messages {
+			export.Messages[i].Content = u.inlineImages(ctx, export.Messages[i].Content)
+		}
+		res = append(res, export)
+	}
+	return res, nil
+}
+
+// ImportConversation restores an exported conversation as the current conversation of a user
+func (u UserService) ImportConversation(ctx context.Context, userID string, role string, v core.ConversationExport) (core.ConversationTree, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ImportConversation")
+	defer apm.EndTransaction(span)
+
+	if err := validateConversationImport(v); err != nil {
+		return core.ConversationTree{}, err
+	}
+	// imported text reaches the model as context, it is screened like the prompts it stands in for
+	contents := [][]core.Content{}
+	for _, m := range v.Messages {
+		contents = append(contents, m.Content)
+	}
+	for _, s := range v.Summaries {
+		contents = append(contents, s.Content)
+	}
+	if err := u.checkImport(ctx, u.userPrincipal(userID), u.cfg.Guardrails.Users, contents); err != nil {
+		return core.ConversationTree{}, err
+	}
+	if v.Persona != "" {
+		persona, err := u.resolvePersona(ctx, v.Persona)
+		if err != nil {
+			return core.ConversationTree{}, err
+		}
+		v.Persona = core.VersionedRef(persona.Name, persona.Version)
+	}
+	if v.Settings != nil {
+		if err := u.validateModelSettings(ctx, role, *v.Settings); err != nil {
+			return core.ConversationTree{}, err
+		}
+	}
+
+	// imported content is held to the same rules as prompts, images go to the blob store and PII is redacted
+	vault := redact.Vault{}
+	redactor := u.redactor(u.cfg.Redaction.Users)
+	var err error
+	for i := range v.Messages {
+		v.Messages[i].Content, err = u.prepareImages(ctx, v.Messages[i].Content)
+		if err != nil {
+			return core.ConversationTree{}, err
+		}
+		v.Messages[i].Content, _ = redactContent(redactor, v.Messages[i].Content, vault)
+	}
+	for i := range v.Summaries {
+		v.Summaries[i].Content, err = u.prepareImages(ctx, v.Summaries[i].Content)
+		if err != nil {
+			return core.ConversationTree{}, err
+		}
+		v.Summaries[i].Content, _ = redactContent(redactor, v.Summaries[i].Content, vault)
+	}
+
+	conversation, messages := core.ToRepoConversation(userID, v)
+	if err = u.repo.InsertConversation(ctx, conversation, messages); err != nil {
+		return core.ConversationTree{}, err
+	}
+
+	// the cached state of the previous conversation is replaced by the one of the imported branch
+	u.clearContextKeys(ctx, userID)
+	path := conversationPath(messages, conversation.ActiveLeaf)
+	if err = u.rebuildContext(ctx, userID, conversation.Summaries, path); err != nil {
+		return core.ConversationTree{}, err
+	}
+	if err = u.saveUserVault(ctx, userID, vault); err != nil {
+		return core.ConversationTree{}, err
+	}
+	if conversation.Persona != "" {
+		if err = u.cache.Set(ctx, fmt.Sprintf(redisKeyPersona, userID), conversation.Persona, u.contextTTL()); err != nil {
+			return core.ConversationTree{}, err
+		}
+	}
+	if v.Settings != nil {
+		data, err := json.Marshal(*v.Settings)
+		if err != nil {
+			return core.ConversationTree{}, err
+		}
+		if err = u.cache.Set(ctx, fmt.Sprintf(redisKeySettings, userID), data, u.contextTTL()); err != nil {
+			return core.ConversationTree{}, err
+		}
+	}
+
+	active := map[primitive.ObjectID]bool{}
+	for _, m := range path {
+		active[m.ID] = true
+	}
+	return core.ToCoreConversationTree(conversation, messages, active), nil
+}
+
+// inlineImages replaces blob references with data URIs, a reference to a missing blob is kept as it is
+func (u UserService) inlineImages(ctx context.Context, content []core.Content) []core.Content {
+	for i, c := range content {
+		if c.ImageURL == nil || !strings.HasPrefix(c.ImageURL.URL, blobScheme) {
+			continue
+		}
+		dataURI, err := u.blobDataURI(ctx, c.ImageURL.URL)
+		if err != nil {
+			continue
+		}
+		content[i].ImageURL = &core.ImageURL{URL: dataURI, Detail: c.ImageURL.Detail}
+	}
+	return content
+}
+
+// validateConversationImport checks that the messages of an import form a tree, parents come before their children
+func validateConversationImport(v core.ConversationExport) error {
+	if len(v.Messages) == 0 {
+		return fmt.Errorf("%w: a conversation needs messages", core.ErrInvalidRequest)
+	}
+
+	seen := map[string]bool{}
+	for i, m := range v.Messages {
+		if m.Role != userDefaultRole && m.Role != "assistant" {
+			return fmt.Errorf("%w: message %d: role must be user or assistant", core.ErrInvalidRequest, i)
+		}
+		if m.ParentID != "" && !seen[m.ParentID] {
+			return fmt.Errorf("%w: message %d: parent %s must come before it", core.ErrInvalidRequest, i, m.ParentID)
+		}
+		if m.ID != "" {
+			if seen[m.ID] {
+				return fmt.Errorf("%w: message %d: duplicate id %s", core.ErrInvalidRequest, i, m.ID)
+			}
+			seen[m.ID] = true
+		}
+	}
+	if v.ActiveLeaf != "" && !seen[v.ActiveLeaf] {
+		return fmt.Errorf("%w: active_leaf %s is not a message", core.ErrInvalidRequest, v.ActiveLeaf)
+	}
+	if depth := importedPathLength(v); depth > importMaxPath {
+		return fmt.Errorf("%w: the active branch has %d messages, the limit is %d", core.ErrInvalidRequest, depth, importMaxPath)
+	}
+	for i, s := range v.Summaries {
+		if !seen[s.UpTo] {
+			return fmt.Errorf("%w: summary %d: up_to %s is not a message", core.ErrInvalidRequest, i, s.UpTo)
+		}
+	}
+	return nil
+}
+
+// importedPathLength counts the messages from the root of an import down to the message it continues from.
+// Messages without ids chain to the one before them, like core.ToRepoConversation does.
+func importedPathLength(v core.ConversationExport) int {
+	depth := map[string]int{}
+	previous := 0
+	leaf := 0
+	for _, m := range v.Messages {
+		d := previous + 1
+		if m.ID != "" || m.ParentID != "" {
+			d = depth[m.ParentID] + 1
+		}
+		if m.ID != "" {
+			depth[m.ID] = d
+		}
+		previous = d
+		leaf = d
+	}
+	if v.ActiveLeaf != "" {
+		leaf = depth[v.ActiveLeaf]
+	}
+	return leaf
+}
@@ -0,0 +1,131 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SubmitFeedback rates an assistant message, a later rating by the same caller replaces the earlier one
+func (u UserService) SubmitFeedback(ctx context.Context, req core.FeedbackRequest) (core.Feedback, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::SubmitFeedback")
+	defer apm.EndTransaction(span)
+
+	id, err := primitive.ObjectIDFromHex(req.MessageID)
+	if err != nil {
+		return core.Feedback{}, fmt.Errorf("%w: message %s", core.ErrNotFound, req.MessageID)
+	}
+	message, err := u.repo.GetMessage(ctx, id)
+	if err != nil || !u.answered(ctx, message, req.Rater) {
+		return core.Feedback{}, fmt.Errorf("%w: message %s", core.ErrNotFound, req.MessageID)
+	}
+	if message.Role != "assistant" {
+		return core.Feedback{}, fmt.Errorf("%w: only answers can be rated", core.ErrInvalidRequest)
+	}
+
+	feedback := repository.Feedback{
+		Rating:    req.Rating,
+		Comment:   req.Comment,
+		Rater:     req.Rater,
+		CreatedAt: time.Now(),
+	}
+	if err = u.repo.SetMessageFeedback(ctx, id, feedback); err != nil {
+		return core.Feedback{}, err
+	}
+	return *core.ToCoreFeedback(&feedback), nil
+}
+
+// FeedbackReport aggregates the ratings given between from and to
+func (u UserService) FeedbackReport(ctx context.Context, groupBy string, from time.Time, to time.Time) ([]core.FeedbackReport, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::FeedbackReport")
+	defer apm.EndTransaction(span)
+
+	aggregates, err := u.repo.AggregateFeedback(ctx, groupBy, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return core.ToCoreFeedbackReports(aggregates), nil
+}
+
+// ExportFeedback returns the answers rated between from and to, each with the branch of messages that led to it
+func (u UserService) ExportFeedback(ctx context.Context, from time.Time, to time.Time, rating string) ([]core.RatedExchange, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ExportFeedback")
+	defer apm.EndTransaction(span)
+
+	rated, err := u.repo.ListRatedMessages(ctx, from, to, rating)
+	if err != nil {
+		return nil, err
+	}
+
+	// a conversation is loaded once however many of its answers were rated
+	conversations := map[primitive.ObjectID][]repository.Message{}
+	res := []core.RatedExchange{}
+	for _, v := range rated {
+		messages, loaded := conversations[v.ConversationID]
+		if !loaded {
+			messages, err = u.repo.ListConversationMessages(ctx, v.ConversationID)
+			if err != nil {
+				return nil, err
+			}
+			conversations[v.ConversationID] = messages
+		}
+		path := conversationPath(messages, v.ID)
+		if len(path) == 0 {
+			path = []repository.Message{v}
+		}
+		res = append(res, core.ToCoreRatedExchange(path))
+	}
+	return res, nil
+}
+
+// answered reports whether a message belongs to the conversation of a user or to a prompt of a service
+func (u UserService) answered(ctx context.Context, message repository.Message, caller string) bool {
+	if message.Service != "" {
+		return message.Service == caller
+	}
+	conversation, err := u.repo.GetConversation(ctx, message.ConversationID)
+	if err != nil {
+		return false
+	}
+	return conversation.UserID == caller
+}
+
+// serviceExchange returns the messages of a service prompt and its answer as a chain, the answer last
+func serviceExchange(serviceName string, messages []core.MessageRequest, answer core.GPT4PromptResponse, persona *repository.Persona, templateRef string) []repository.Message {
+	conversationID := primitive.NewObjectID()
+	var exchange []repository.Message
+	var parentID primitive.ObjectID
+	for _, v := range messages {
+		message := repository.Message{
+			ID:             primitive.NewObjectID(),
+			ConversationID: conversationID,
+			ParentID:       parentID,
+			Role:           v.Role,
+			Content:        core.ToContentRepo(v.Content),
+			Service:        serviceName,
+		}
+		parentID = message.ID
+		exchange = append(exchange, message)
+	}
+
+	text := answer.Choices[0].Message.Content
+	message := repository.Message{
+		ID:             primitive.NewObjectID(),
+		ConversationID: conversationID,
+		ParentID:       parentID,
+		Role:           "assistant",
+		Content:        []repository.Content{{Type: "text", Text: &text}},
+		Model:          answer.Model,
+		Template:       templateRef,
+		Service:        serviceName,
+	}
+	if persona != nil {
+		message.Persona = core.VersionedRef(persona.Name, persona.Version)
+	}
+	return append(exchange, message)
+}
@@ -0,0 +1,199 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/abialemuel/AI-Proxy-Service/config"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/guardrail"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/moderation_webservice"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// compiledGuard is the guardrail of a policy, built once since compiling its rules is costly
+type compiledGuard struct {
+	guard *guardrail.Guard
+	err   error
+}
+
+// newGuards compiles the guardrail policy of SSO users and of every service
+func newGuards(cfg *config.MainConfig) map[string]compiledGuard {
+	guards := map[string]compiledGuard{}
+	add := func(key string, policy config.GuardrailPolicy) {
+		g, err := guardrail.New(policy.Blocklist, policy.DeniedTopics, policy.MaxPromptChars)
+		guards[key] = compiledGuard{guard: g, err: err}
+	}
+	add(guardKey(principal{Type: principalTypeUser}), cfg.Guardrails.Users)
+	for _, service := range cfg.Services {
+		add(guardKey(principal{Name: service.Name, Type: principalTypeService}), service.Guardrails)
+	}
+	return guards
+}
+
+// guardKey names the policy a principal is screened with, SSO users share one
+func guardKey(p principal) string {
+	if p.Type == principalTypeService {
+		return principalTypeService + ":" + p.Name
+	}
+	return principalTypeUser
+}
+
+// guard returns the compiled guardrail of the policy of a principal
+func (u UserService) guard(p principal, policy config.GuardrailPolicy) (*guardrail.Guard, error) {
+	if compiled, ok := u.guards[guardKey(p)]; ok {
+		return compiled.guard, compiled.err
+	}
+	return guardrail.New(policy.Blocklist, policy.DeniedTopics, policy.MaxPromptChars)
+}
+
+// checkInput screens the text of prompt messages before they are sent upstream
+func (u UserService) checkInput(ctx context.Context, p principal, policy config.GuardrailPolicy, messages []gpt4_webservice.MessageReq) error {
+	texts := []string{}
+	for _, message := range messages {
+		texts = append(texts, messageText(message.Content))
+	}
+	return u.screen(ctx, p, policy, core.GuardrailStageInput, texts)
+}
+
+// checkOutput screens the answers of the upstream, an answer cut off by the upstream's own filter is always rejected
+func (u UserService) checkOutput(ctx context.Context, p principal, policy config.GuardrailPolicy, res gpt4_webservice.GPT4PromptResponseDao) error {
+	texts := []string{}
+	for _, choice := range res.Choices {
+		if choice.FinishReason == gpt4_webservice.FinishReasonContentFilter {
+			return u.contentFiltered(ctx, p, &core.ContentFilteredError{
+				Stage:  core.GuardrailStageOutput,
+				Rule:   core.GuardrailRuleUpstream,
+				Detail: filteredCategories(choice.ContentFilterResults),
+			})
+		}
+		texts = append(texts, choice.Message.Content)
+	}
+	return u.screen(ctx, p, policy, core.GuardrailStageOutput, texts)
+}
+
+// checkImport screens the messages and summaries of an imported conversation, each is held to the rules of a prompt
+func (u UserService) checkImport(ctx context.Context, p principal, policy config.GuardrailPolicy, contents [][]core.Content) error {
+	texts := []string{}
+	for _, content := range contents {
+		texts = append(texts, messageText(core.ToWebServiceUserPromtGPTContentRequest(content)))
+	}
+	return u.screen(ctx, p, policy, core.GuardrailStageImport, texts)
+}
+
+// screen runs the rules of a policy over texts, the length limit only applies to prompts and imported messages
+func (u UserService) screen(ctx context.Context, p principal, policy config.GuardrailPolicy, stage string, texts []string) error {
+	g, err := u.guard(p, policy)
+	if err != nil {
+		return err
+	}
+	if g.Empty() && !policy.Moderation {
+		return nil
+	}
+
+	switch stage {
+	case core.GuardrailStageInput:
+		if violation := g.CheckLength(strings.Join(texts, "\n")); violation != nil {
+			return u.contentFiltered(ctx, p, &core.ContentFilteredError{Stage: stage, Rule: violation.Rule, Detail: violation.Detail})
+		}
+	case core.GuardrailStageImport:
+		for _, text := range texts {
+			if violation := g.CheckLength(text); violation != nil {
+				return u.contentFiltered(ctx, p, &core.ContentFilteredError{Stage: stage, Rule: violation.Rule, Detail: violation.Detail})
+			}
+		}
+	}
+	for _, text := range texts {
+		if violation := g.Check(text); violation != nil {
+			return u.contentFiltered(ctx, p, &core.ContentFilteredError{Stage: stage, Rule: violation.Rule, Detail: violation.Detail})
+		}
+	}
+
+	if !policy.Moderation || u.moderationWebservice == nil {
+		return nil
+	}
+	categories, err := u.moderate(ctx, texts)
+	if err != nil {
+		// an unscreened prompt must not get through, so fail closed
+		return fmt.Errorf("error in moderation: %v", err)
+	}
+	if len(categories) > 0 {
+		return u.contentFiltered(ctx, p, &core.ContentFilteredError{
+			Stage:  stage,
+			Rule:   core.GuardrailRuleModeration,
+			Detail: strings.Join(categories, ", "),
+		})
+	}
+	return nil
+}
+
+// moderate returns the categories the moderation upstream flagged in any of the texts
+func (u UserService) moderate(ctx context.Context, texts []string) ([]string, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::Moderate")
+	defer apm.EndTransaction(span)
+
+	input := []string{}
+	for _, text := range texts {
+		if strings.TrimSpace(text) != "" {
+			input = append(input, text)
+		}
+	}
+	if len(input) == 0 {
+		return nil, nil
+	}
+
+	res, err := u.moderationWebservice.Moderate(ctx, moderation_webservice.ModerationRequestDao{
+		Model: u.cfg.Guardrails.Moderation.Model,
+		Input: input,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	categories := []string{}
+	for _, result := range res.Results {
+		if !result.Flagged {
+			continue
+		}
+		for category, flagged := range result.Categories {
+			if flagged {
+				categories = appendUnique(categories, category)
+			}
+		}
+		if len(categories) == 0 {
+			categories = append(categories, "flagged")
+		}
+	}
+	sort.Strings(categories)
+	return categories, nil
+}
+
+// contentFiltered records a rejected prompt or answer in the audit trail and returns the error
+func (u UserService) contentFiltered(ctx context.Context, p principal, err *core.ContentFilteredError) error {
+	apm.AddEvent(ctx, "ContentFiltered",
+		attribute.String("stage", err.Stage),
+		attribute.String("rule", err.Rule),
+	)
+	u.audit(p, core.AuditActionContentFiltered, map[string]string{
+		"stage":  err.Stage,
+		"rule":   err.Rule,
+		"detail": err.Detail,
+	})
+	return err
+}
+
+// filteredCategories returns the categories the upstream's content filter tripped on
+func filteredCategories(results map[string]gpt4_webservice.ContentFilterResult) string {
+	categories := []string{}
+	for category, result := range results {
+		if result.Filtered {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+	return strings.Join(categories, ", ")
+}
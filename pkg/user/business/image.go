@@ -0,0 +1,188 @@
+package business
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/media"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// blobScheme prefixes the references to images kept in the blob store
+const blobScheme = "blob://"
+
+// errImageNotFetched is returned for any remote image that could not be fetched
+var errImageNotFetched = fmt.Errorf("%w: image could not be fetched", core.ErrInvalidRequest)
+
+var defaultImageTypes = []string{media.TypePNG, media.TypeJPEG, media.TypeGIF, media.TypeWebP}
+
+// prepareImages validates the images of a message. Inline images, and remote ones when fetching is enabled,
+// are moved to the blob store so conversations only keep a reference.
+func (u UserService) prepareImages(ctx context.Context, content []core.Content) ([]core.Content, error) {
+	res := []core.Content{}
+	for _, c := range content {
+		if c.ImageURL == nil {
+			res = append(res, c)
+			continue
+		}
+		switch c.ImageURL.Detail {
+		case "", "auto", "low", "high":
+		default:
+			return nil, fmt.Errorf("%w: image detail must be auto, low or high", core.ErrInvalidRequest)
+		}
+
+		ref, err := u.prepareImage(ctx, c.ImageURL.URL)
+		if err != nil {
+			return nil, err
+		}
+		c.ImageURL = &core.ImageURL{URL: ref, Detail: c.ImageURL.Detail}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+// prepareMessages runs prepareImages over every message
+func (u UserService) prepareMessages(ctx context.Context, messages []core.MessageRequest) ([]core.MessageRequest, error) {
+	res := []core.MessageRequest{}
+	for _, message := range messages {
+		content, err := u.prepareImages(ctx, message.Content)
+		if err != nil {
+			return nil, err
+		}
+		message.Content = content
+		res = append(res, message)
+	}
+	return res, nil
+}
+
+// prepareImage returns the URL an image is kept under
+func (u UserService) prepareImage(ctx context.Context, imageURL string) (string, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::PrepareImage")
+	defer apm.EndTransaction(span)
+
+	if strings.HasPrefix(imageURL, "data:") {
+		// base64 is a third larger than what it encodes, refuse oversized images before decoding them
+		if int64(len(imageURL))/4*3 > u.cfg.Images.MaxBytes+3 {
+			return "", fmt.Errorf("%w: image is larger than %d bytes", core.ErrInvalidRequest, u.cfg.Images.MaxBytes)
+		}
+		_, data, err := media.ParseDataURI(imageURL)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", core.ErrInvalidRequest, err)
+		}
+		info, err := u.validateImage(data)
+		if err != nil {
+			return "", err
+		}
+		if u.blobStore == nil {
+			return media.DataURI(info.Type, data), nil
+		}
+		return u.storeImage(ctx, data, info)
+	}
+
+	parsed, err := url.Parse(imageURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Hostname() == "" {
+		return "", fmt.Errorf("%w: image_url must be an http(s) or base64 data URL", core.ErrInvalidRequest)
+	}
+	if !u.cfg.Images.FetchRemote || u.imageFetcher == nil || u.blobStore == nil {
+		return imageURL, nil
+	}
+	// every failure reads the same, the answer must not tell what is reachable from inside the network
+	if len(u.cfg.Images.FetchHosts) > 0 && !hostAllowed(parsed.Hostname(), u.cfg.Images.FetchHosts) {
+		return "", errImageNotFetched
+	}
+	data, err := u.imageFetcher.Fetch(ctx, imageURL, u.cfg.Images.MaxBytes)
+	if err != nil {
+		apm.AddEvent(ctx, "ImageFetchFailed", attribute.String("error", err.Error()))
+		return "", errImageNotFetched
+	}
+	info, err := u.validateImage(data)
+	if err != nil {
+		return "", err
+	}
+	if u.cfg.Images.Reencode {
+		data, err = media.Reencode(data, info)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", core.ErrInvalidRequest, err)
+		}
+	}
+	return u.storeImage(ctx, data, info)
+}
+
+// validateImage checks the size, type and dimensions of an image against the configured limits
+func (u UserService) validateImage(data []byte) (media.Info, error) {
+	if int64(len(data)) > u.cfg.Images.MaxBytes {
+		return media.Info{}, fmt.Errorf("%w: image is larger than %d bytes", core.ErrInvalidRequest, u.cfg.Images.MaxBytes)
+	}
+	info, err := media.Inspect(data)
+	if err != nil {
+		return media.Info{}, fmt.Errorf("%w: %v", core.ErrInvalidRequest, err)
+	}
+
+	allowed := u.cfg.Images.AllowedTypes
+	if len(allowed) == 0 {
+		allowed = defaultImageTypes
+	}
+	if !slices.ContainsFunc(allowed, func(t string) bool { return strings.EqualFold(t, info.Type) }) {
+		return media.Info{}, fmt.Errorf("%w: image type %s is not allowed", core.ErrInvalidRequest, info.Type)
+	}
+	if (u.cfg.Images.MaxWidth > 0 && info.Width > u.cfg.Images.MaxWidth) || (u.cfg.Images.MaxHeight > 0 && info.Height > u.cfg.Images.MaxHeight) {
+		return media.Info{}, fmt.Errorf("%w: image is %dx%d, the limit is %dx%d", core.ErrInvalidRequest, info.Width, info.Height, u.cfg.Images.MaxWidth, u.cfg.Images.MaxHeight)
+	}
+	return info, nil
+}
+
+// storeImage puts an image into the blob store under its content hash, so the same image is only kept once
+func (u UserService) storeImage(ctx context.Context, data []byte, info media.Info) (string, error) {
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:]) + "." + media.Extension(info.Type)
+	if err := u.blobStore.Put(ctx, key, data); err != nil {
+		return "", err
+	}
+	return blobScheme + key, nil
+}
+
+// resolveImages replaces blob references with data URIs right before a prompt is sent upstream
+func (u UserService) resolveImages(ctx context.Context, messages []gpt4_webservice.MessageReq) ([]gpt4_webservice.MessageReq, error) {
+	res := make([]gpt4_webservice.MessageReq, 0, len(messages))
+	for _, message := range messages {
+		content := make([]gpt4_webservice.Content, 0, len(message.Content))
+		for _, c := range message.Content {
+			if c.ImageURL != nil && strings.HasPrefix(c.ImageURL.URL, blobScheme) {
+				dataURI, err := u.blobDataURI(ctx, c.ImageURL.URL)
+				if err != nil {
+					return nil, err
+				}
+				c.ImageURL = &gpt4_webservice.ImageURL{
+					URL:    dataURI,
+					Detail: c.ImageURL.Detail,
+				}
+			}
+			content = append(content, c)
+		}
+		message.Content = content
+		res = append(res, message)
+	}
+	return res, nil
+}
+
+// blobDataURI returns the image a blob reference points to as a data URI
+func (u UserService) blobDataURI(ctx context.Context, ref string) (string, error) {
+	key := strings.TrimPrefix(ref, blobScheme)
+	if u.blobStore == nil {
+		return "", fmt.Errorf("image %s: no blob store", key)
+	}
+	data, err := u.blobStore.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("image %s: %v", key, err)
+	}
+	return media.DataURI(media.TypeOfExtension(path.Ext(key)), data), nil
+}
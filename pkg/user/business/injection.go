@@ -0,0 +1,136 @@
+package business
+
+import (
+	"context"
+	"strings"
+
+	"github.com/abialemuel/AI-Proxy-Service/config"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/injection"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/redact"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+)
+
+const (
+	injectionReplacement = "[removed]"
+	leakReplacement      = "[withheld]"
+	leakDefaultMinWords  = 8
+)
+
+// inspectInjection scores the untrusted messages of a service prompt, system messages come from the service itself.
+// Injected instructions are stripped or the prompt is blocked depending on the thresholds of the policy.
+func (u UserService) inspectInjection(ctx context.Context, p principal, policy config.InjectionPolicy, messages []core.MessageRequest) ([]core.MessageRequest, *core.PromptInspection, error) {
+	if policy.AnnotateThreshold <= 0 && policy.StripThreshold <= 0 && policy.BlockThreshold <= 0 {
+		return messages, nil, nil
+	}
+
+	inspection := &core.PromptInspection{}
+	scores := make([]float64, len(messages))
+	for i, message := range messages {
+		if message.Role == "system" {
+			continue
+		}
+		score, signals := injection.Score(contentText(message.Content))
+		scores[i] = score
+		if score > inspection.InjectionScore {
+			inspection.InjectionScore = score
+		}
+		inspection.InjectionSignals = appendUnique(inspection.InjectionSignals, signals...)
+	}
+
+	if policy.BlockThreshold > 0 && inspection.InjectionScore >= policy.BlockThreshold {
+		return nil, nil, u.contentFiltered(ctx, p, &core.ContentFilteredError{
+			Stage:  core.GuardrailStageInput,
+			Rule:   core.GuardrailRuleInjection,
+			Detail: strings.Join(inspection.InjectionSignals, ", "),
+		})
+	}
+
+	res := []core.MessageRequest{}
+	for i, message := range messages {
+		if policy.StripThreshold > 0 && scores[i] >= policy.StripThreshold {
+			content := []core.Content{}
+			for _, c := range message.Content {
+				if c.Text != nil {
+					text := injection.Strip(*c.Text, injectionReplacement)
+					c.Text = &text
+				}
+				content = append(content, c)
+			}
+			message.Content = content
+			inspection.Stripped = true
+		}
+		res = append(res, message)
+	}
+
+	if inspection.Stripped || (policy.AnnotateThreshold > 0 && inspection.InjectionScore >= policy.AnnotateThreshold) {
+		return res, inspection, nil
+	}
+	return res, nil, nil
+}
+
+// inspectLeaks scans the answers for text of the system prompts and for credentials, stripping them or blocking the answer
+// depending on the policy. It returns the kinds of leaks found and whether they were stripped.
+func (u UserService) inspectLeaks(ctx context.Context, p principal, policy config.InjectionPolicy, system []string, res *gpt4_webservice.GPT4PromptResponseDao) ([]string, bool, error) {
+	if policy.Leaks == "" {
+		return nil, false, nil
+	}
+	minWords := policy.LeakMinWords
+	if minWords <= 0 {
+		minWords = leakDefaultMinWords
+	}
+	secrets, _ := redact.New(redact.ModeMask, []string{redact.DetectorSecret}, "")
+
+	leaks := []string{}
+	for i, choice := range res.Choices {
+		content := choice.Message.Content
+		for _, prompt := range system {
+			if spans := injection.LeakSpans(content, prompt, minWords); len(spans) > 0 {
+				leaks = appendUnique(leaks, core.LeakSystemPrompt)
+				content = injection.Remove(content, spans, leakReplacement)
+			}
+		}
+		masked, found := secrets.Redact(content, nil)
+		if len(found) > 0 {
+			leaks = appendUnique(leaks, core.LeakSecret)
+			content = masked
+		}
+		if policy.Leaks == "strip" {
+			res.Choices[i].Message.Content = content
+		}
+	}
+	if len(leaks) == 0 {
+		return nil, false, nil
+	}
+
+	if policy.Leaks == "block" {
+		return nil, false, u.contentFiltered(ctx, p, &core.ContentFilteredError{
+			Stage:  core.GuardrailStageOutput,
+			Rule:   core.GuardrailRuleLeak,
+			Detail: strings.Join(leaks, ", "),
+		})
+	}
+	return leaks, policy.Leaks == "strip", nil
+}
+
+// systemPrompts returns the text of the system messages of a prompt
+func systemPrompts(messages []gpt4_webservice.MessageReq) []string {
+	prompts := []string{}
+	for _, message := range messages {
+		if message.Role == "system" {
+			prompts = append(prompts, messageText(message.Content))
+		}
+	}
+	return prompts
+}
+
+// contentText joins the text parts of a message
+func contentText(content []core.Content) string {
+	texts := []string{}
+	for _, c := range content {
+		if c.Text != nil {
+			texts = append(texts, *c.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
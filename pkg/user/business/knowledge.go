@@ -0,0 +1,342 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/document"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/redact"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/embedding_webservice"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ingestTimeout      = 10 * time.Minute
+	citationSnippetLen = 200
+	ragBrief           = "Answer using the numbered context below when it is relevant. Cite the sources you use with their number, e.g. [1]. If the context does not contain the answer, say so."
+)
+
+// CreateKnowledgeBase creates a named knowledge base, names are unique per owner
+func (u UserService) CreateKnowledgeBase(ctx context.Context, owner string, name string, description string) (core.KnowledgeBase, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::CreateKnowledgeBase")
+	defer apm.EndTransaction(span)
+
+	if _, err := u.repo.GetKnowledgeBase(ctx, owner, name); err == nil {
+		return core.KnowledgeBase{}, fmt.Errorf("%w: knowledge base %s already exists", core.ErrInvalidRequest, name)
+	}
+
+	kb, err := u.repo.InsertKnowledgeBase(ctx, repository.KnowledgeBase{
+		Owner:       owner,
+		Name:        name,
+		Description: description,
+	})
+	if err != nil {
+		return core.KnowledgeBase{}, err
+	}
+	return core.ToCoreKnowledgeBase(kb), nil
+}
+
+// GetKnowledgeBase returns a knowledge base of the caller
+func (u UserService) GetKnowledgeBase(ctx context.Context, owner string, name string) (core.KnowledgeBase, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::GetKnowledgeBase")
+	defer apm.EndTransaction(span)
+
+	kb, err := u.knowledgeBase(ctx, owner, name)
+	if err != nil {
+		return core.KnowledgeBase{}, err
+	}
+	return core.ToCoreKnowledgeBase(kb), nil
+}
+
+// ListKnowledgeBases returns the knowledge bases of the caller
+func (u UserService) ListKnowledgeBases(ctx context.Context, owner string) ([]core.KnowledgeBase, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListKnowledgeBases")
+	defer apm.EndTransaction(span)
+
+	kbs, err := u.repo.ListKnowledgeBases(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return core.ToCoreKnowledgeBases(kbs), nil
+}
+
+// DeleteKnowledgeBase removes a knowledge base with its documents and chunks
+func (u UserService) DeleteKnowledgeBase(ctx context.Context, owner string, name string) error {
+	ctx, span := apm.StartTransaction(ctx, "Service::DeleteKnowledgeBase")
+	defer apm.EndTransaction(span)
+
+	kb, err := u.knowledgeBase(ctx, owner, name)
+	if err != nil {
+		return err
+	}
+	if err := u.vectorRepo.DeleteKnowledgeBaseChunks(ctx, kb.ID); err != nil {
+		return err
+	}
+	return u.repo.DeleteKnowledgeBase(ctx, kb.ID)
+}
+
+// UploadDocument stores a document in a knowledge base. Text is extracted up front so unsupported
+// documents are rejected, chunking and embedding run in the background while the document is processing.
+func (u UserService) UploadDocument(ctx context.Context, owner string, kbName string, filename string, contentType string, content []byte) (core.Document, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::UploadDocument")
+	defer apm.EndTransaction(span)
+
+	kb, err := u.knowledgeBase(ctx, owner, kbName)
+	if err != nil {
+		return core.Document{}, err
+	}
+	if int64(len(content)) > u.cfg.RAG.MaxDocumentBytes {
+		return core.Document{}, fmt.Errorf("%w: document exceeds %d bytes", core.ErrInvalidRequest, u.cfg.RAG.MaxDocumentBytes)
+	}
+
+	mimeType := document.DetectMimeType(filename, contentType)
+	text, err := document.ExtractText(mimeType, content, u.cfg.RAG.MaxDocumentBytes)
+	if err != nil {
+		return core.Document{}, fmt.Errorf("%w: %v", core.ErrInvalidRequest, err)
+	}
+	chunks := document.Chunk(text, u.cfg.RAG.ChunkSize, u.cfg.RAG.ChunkOverlap)
+	if len(chunks) == 0 {
+		return core.Document{}, fmt.Errorf("%w: document has no text", core.ErrInvalidRequest)
+	}
+
+	p := u.ownerPrincipal(owner)
+	if err := u.validateQuota(ctx, p); err != nil {
+		return core.Document{}, err
+	}
+
+	doc, err := u.repo.InsertDocument(ctx, repository.Document{
+		KnowledgeBaseID: kb.ID,
+		Owner:           owner,
+		Filename:        filename,
+		MimeType:        mimeType,
+		Bytes:           int64(len(content)),
+		Status:          core.DocumentStatusProcessing,
+	})
+	if err != nil {
+		return core.Document{}, err
+	}
+
+	// the request context ends with the response
+	go u.ingestDocument(p, doc, chunks)
+
+	return core.ToCoreDocument(doc), nil
+}
+
+// ListDocuments returns the documents of a knowledge base of the caller
+func (u UserService) ListDocuments(ctx context.Context, owner string, kbName string) ([]core.Document, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListDocuments")
+	defer apm.EndTransaction(span)
+
+	kb, err := u.knowledgeBase(ctx, owner, kbName)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := u.repo.ListDocuments(ctx, kb.ID)
+	if err != nil {
+		return nil, err
+	}
+	return core.ToCoreDocuments(docs), nil
+}
+
+// DeleteDocument removes a document and its chunks
+func (u UserService) DeleteDocument(ctx context.Context, owner string, kbName string, id string) error {
+	ctx, span := apm.StartTransaction(ctx, "Service::DeleteDocument")
+	defer apm.EndTransaction(span)
+
+	kb, err := u.knowledgeBase(ctx, owner, kbName)
+	if err != nil {
+		return err
+	}
+	doc, err := u.repo.GetDocument(ctx, id)
+	if err != nil || doc.KnowledgeBaseID != kb.ID {
+		return fmt.Errorf("%w: document %s", core.ErrNotFound, id)
+	}
+	if err := u.vectorRepo.DeleteDocumentChunks(ctx, doc.ID); err != nil {
+		return err
+	}
+	return u.repo.DeleteDocument(ctx, doc.ID)
+}
+
+// knowledgeBase returns a knowledge base of the caller or ErrNotFound
+func (u UserService) knowledgeBase(ctx context.Context, owner string, name string) (repository.KnowledgeBase, error) {
+	kb, err := u.repo.GetKnowledgeBase(ctx, owner, name)
+	if err != nil {
+		return repository.KnowledgeBase{}, fmt.Errorf("%w: knowledge base %s", core.ErrNotFound, name)
+	}
+	return kb, nil
+}
+
+// ingestDocument embeds the chunks of a document in batches and marks it ready, or failed with the reason
+func (u UserService) ingestDocument(p principal, doc repository.Document, texts []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), ingestTimeout)
+	defer cancel()
+
+	tokens, err := u.embedChunks(ctx, p, doc, texts)
+	if tokens > 0 {
+		if err := u.addTokenUsage(ctx, p, tokens); err != nil {
+			fmt.Println("Error adding ingest token usage:", err)
+		}
+	}
+
+	doc.Status = core.DocumentStatusReady
+	doc.Chunks = len(texts)
+	if err != nil {
+		doc.Status = core.DocumentStatusFailed
+		doc.Chunks = 0
+		doc.Error = err.Error()
+		// don't leave a partial document searchable
+		if err := u.vectorRepo.DeleteDocumentChunks(ctx, doc.ID); err != nil {
+			fmt.Println("Error deleting document chunks:", err)
+		}
+	}
+	doc.UpdatedAt = time.Now()
+	if err := u.repo.UpdateDocument(ctx, doc); err != nil {
+		fmt.Println("Error updating document:", err)
+	}
+}
+
+// embedChunks embeds and stores the chunks of a document, returning the tokens spent
+func (u UserService) embedChunks(ctx context.Context, p principal, doc repository.Document, texts []string) (int, error) {
+	batchSize := u.cfg.RAG.EmbedBatchSize
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+
+	// chunks are stored as uploaded and redacted again when they are retrieved into a prompt
+	redactor := u.principalRedactor(p)
+	vault := redact.Vault{}
+	tokens := 0
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+		res, err := u.embed(ctx, p, usageKindRAGIngest, embedding_webservice.EmbeddingRequestDao{
+			Model: u.cfg.Embeddings.Model,
+			Input: redactTexts(redactor, texts[start:end], vault),
+		})
+		if err != nil {
+			return tokens, fmt.Errorf("error in embeddings: %v", err)
+		}
+		tokens += res.Usage.TotalTokens
+		if len(res.Data) != end-start {
+			return tokens, errors.New("error in embeddings: unexpected number of embeddings")
+		}
+
+		chunks := []repository.Chunk{}
+		for i, data := range res.Data {
+			chunks = append(chunks, repository.Chunk{
+				KnowledgeBaseID: doc.KnowledgeBaseID,
+				DocumentID:      doc.ID,
+				Filename:        doc.Filename,
+				Index:           start + i,
+				Text:            texts[start+i],
+				Vector:          data.Embedding,
+			})
+		}
+		if err := u.vectorRepo.InsertChunks(ctx, chunks); err != nil {
+			return tokens, err
+		}
+	}
+	return tokens, nil
+}
+
+// retrieveContext embeds the query and returns a system message with the top-k chunks of the owner's
+// knowledge bases, the citations for them and the tokens spent. No message is returned when nothing matched.
+// The query must already be redacted, the chunks are redacted into the vault of the prompt.
+func (u UserService) retrieveContext(ctx context.Context, p principal, owner string, kbNames []string, query string, vault redact.Vault) (*gpt4_webservice.MessageReq, []core.Citation, int, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::RetrieveContext")
+	defer apm.EndTransaction(span)
+
+	names := map[primitive.ObjectID]string{}
+	ids := []primitive.ObjectID{}
+	for _, name := range kbNames {
+		kb, err := u.knowledgeBase(ctx, owner, name)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		names[kb.ID] = kb.Name
+		ids = append(ids, kb.ID)
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, nil, 0, nil
+	}
+
+	res, err := u.embed(ctx, p, usageKindRAGQuery, embedding_webservice.EmbeddingRequestDao{
+		Model: u.cfg.Embeddings.Model,
+		Input: []string{query},
+	})
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("error in embeddings: %v", err)
+	}
+	if len(res.Data) == 0 {
+		return nil, nil, res.Usage.TotalTokens, nil
+	}
+
+	matches, err := u.vectorRepo.SearchChunks(ctx, ids, res.Data[0].Embedding, u.cfg.RAG.TopK, u.cfg.RAG.MinScore)
+	if err != nil {
+		return nil, nil, res.Usage.TotalTokens, err
+	}
+	if len(matches) == 0 {
+		return nil, nil, res.Usage.TotalTokens, nil
+	}
+
+	var text strings.Builder
+	text.WriteString(ragBrief)
+	citations := []core.Citation{}
+	redactor := u.principalRedactor(p)
+	for i, match := range matches {
+		chunk := match.Text
+		if redactor != nil {
+			chunk, _ = redactor.Redact(chunk, vault)
+		}
+		fmt.Fprintf(&text, "\n\n[%d] %s\n%s", i+1, match.Filename, chunk)
+		citations = append(citations, core.Citation{
+			Index:         i + 1,
+			KnowledgeBase: names[match.KnowledgeBaseID],
+			DocumentID:    match.DocumentID.Hex(),
+			Filename:      match.Filename,
+			Chunk:         match.Index,
+			Score:         match.Score,
+			Snippet:       snippet(match.Text, citationSnippetLen),
+		})
+	}
+
+	contextText := text.String()
+	return &gpt4_webservice.MessageReq{
+		Role:    "system",
+		Content: []gpt4_webservice.Content{{Type: "text", Text: &contextText}},
+	}, citations, res.Usage.TotalTokens, nil
+}
+
+// withContext inserts the retrieved context right before the final message
+func withContext(messages []gpt4_webservice.MessageReq, contextMsg gpt4_webservice.MessageReq) []gpt4_webservice.MessageReq {
+	res := make([]gpt4_webservice.MessageReq, 0, len(messages)+1)
+	res = append(res, messages[:len(messages)-1]...)
+	res = append(res, contextMsg)
+	return append(res, messages[len(messages)-1])
+}
+
+// messageText joins the text parts of a message
+func messageText(content []gpt4_webservice.Content) string {
+	texts := []string{}
+	for _, part := range content {
+		if part.Text != nil {
+			texts = append(texts, *part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// snippet returns the first n runes of text
+func snippet(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "..."
+}
@@ -0,0 +1,183 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+)
+
+const redisKeyPersona = "persona-%s"
+
+// CreatePersona stores a new version of a persona, earlier versions stay available for pinned references
+func (u UserService) CreatePersona(ctx context.Context, payload core.PersonaRequest) (core.Persona, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::CreatePersona")
+	defer apm.EndTransaction(span)
+
+	persona, err := u.repo.InsertPersona(ctx, repository.Persona{
+		Name:         payload.Name,
+		Description:  payload.Description,
+		SystemPrompt: payload.SystemPrompt,
+		Model:        payload.Model,
+		Temperature:  payload.Temperature,
+		TopP:         payload.TopP,
+		MaxTokens:    payload.MaxTokens,
+		CreatedBy:    payload.CreatedBy,
+	})
+	if err != nil {
+		return core.Persona{}, err
+	}
+	return core.ToCorePersona(persona), nil
+}
+
+// GetPersona returns a persona by reference, either name for the latest version or name@vN
+func (u UserService) GetPersona(ctx context.Context, ref string) (core.Persona, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::GetPersona")
+	defer apm.EndTransaction(span)
+
+	persona, err := u.resolvePersona(ctx, ref)
+	if err != nil {
+		return core.Persona{}, err
+	}
+	return core.ToCorePersona(persona), nil
+}
+
+// ListPersonas returns the latest version of every persona
+func (u UserService) ListPersonas(ctx context.Context) ([]core.Persona, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListPersonas")
+	defer apm.EndTransaction(span)
+
+	personas, err := u.repo.ListPersonas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return core.ToCorePersonas(personas), nil
+}
+
+// ListPersonaVersions returns every version of a persona, newest first
+func (u UserService) ListPersonaVersions(ctx context.Context, name string) ([]core.Persona, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListPersonaVersions")
+	defer apm.EndTransaction(span)
+
+	personas, err := u.repo.ListPersonaVersions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(personas) == 0 {
+		return nil, fmt.Errorf("%w: persona %s", core.ErrNotFound, name)
+	}
+	return core.ToCorePersonas(personas), nil
+}
+
+// resolvePersona finds the persona version a reference points to
+func (u UserService) resolvePersona(ctx context.Context, ref string) (repository.Persona, error) {
+	name, version, err := parseVersionedRef(ref)
+	if err != nil {
+		return repository.Persona{}, err
+	}
+	persona, err := u.repo.GetPersona(ctx, name, version)
+	if err != nil {
+		return repository.Persona{}, fmt.Errorf("%w: persona %s", core.ErrNotFound, ref)
+	}
+	return persona, nil
+}
+
+// conversationPersona returns the persona of a user's conversation. A persona selected in the request
+// replaces the current one and is pinned to its version, so later edits don't change a running conversation.
+func (u UserService) conversationPersona(ctx context.Context, userID string, ref string) (*repository.Persona, error) {
+	personaKey := fmt.Sprintf(redisKeyPersona, userID)
+	if ref == "" {
+		stored, found := u.cache.Get(ctx, personaKey)
+		if !found {
+			return nil, nil
+		}
+		ref = stored.(string)
+	}
+
+	persona, err := u.resolvePersona(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	pinned := core.VersionedRef(persona.Name, persona.Version)
+	if pinned != ref {
+		if err := u.cache.Set(ctx, personaKey, pinned, u.contextTTL()); err != nil {
+			return nil, err
+		}
+		if err := u.repo.SetConversationPersona(ctx, userID, pinned); err != nil {
+			return nil, err
+		}
+	}
+	return &persona, nil
+}
+
+// servicePersona returns the persona of a service prompt, the one in the request wins over the one bound to the service
+func (u UserService) servicePersona(ctx context.Context, serviceName string, ref string) (*repository.Persona, error) {
+	if ref == "" {
+		service, _ := u.backendService(serviceName)
+		ref = service.Persona
+	}
+	if ref == "" {
+		return nil, nil
+	}
+
+	persona, err := u.resolvePersona(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &persona, nil
+}
+
+// serviceModel returns the model a service prompt runs on, the one in the request wins over the one of its persona
+func serviceModel(model string, persona *repository.Persona) string {
+	if model == "" && persona != nil {
+		return persona.Model
+	}
+	return model
+}
+
+// applyPersonaDefaults sets the model parameters a persona defines
+func applyPersonaDefaults(payload *gpt4_webservice.GPT4PromptRequestDao, persona repository.Persona) {
+	if persona.Model != "" {
+		payload.Model = persona.Model
+	}
+	if persona.Temperature != nil {
+		payload.Temperature = *persona.Temperature
+	}
+	if persona.TopP != nil {
+		payload.TopP = *persona.TopP
+	}
+	if persona.MaxTokens != nil {
+		payload.MaxTokens = *persona.MaxTokens
+	}
+}
+
+// personaMessage returns the system message of a persona
+func personaMessage(persona repository.Persona) gpt4_webservice.MessageReq {
+	text := persona.SystemPrompt
+	return gpt4_webservice.MessageReq{
+		Role:    "system",
+		Content: []gpt4_webservice.Content{{Type: "text", Text: &text}},
+	}
+}
+
+// parseVersionedRef splits a name or name@vN reference, version 0 means the latest
+func parseVersionedRef(ref string) (string, int, error) {
+	name, version, found := strings.Cut(ref, "@")
+	if name == "" {
+		return "", 0, fmt.Errorf("%w: empty reference", core.ErrInvalidRequest)
+	}
+	if !found {
+		return name, 0, nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil || n < 1 {
+		return "", 0, fmt.Errorf("%w: invalid version in %s", core.ErrInvalidRequest, ref)
+	}
+	return name, n, nil
+}
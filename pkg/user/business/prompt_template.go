@@ -0,0 +1,161 @@
+package business
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/prompttemplate"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+)
+
+// renderedTemplateKey carries the template a prompt was rendered from to the usage ledger
+type renderedTemplateKey struct{}
+
+type renderedTemplate struct {
+	Ref    string
+	Prompt string
+}
+
+// CreatePromptTemplate validates and stores a new version of a prompt template
+func (u UserService) CreatePromptTemplate(ctx context.Context, payload core.PromptTemplateRequest) (core.PromptTemplate, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::CreatePromptTemplate")
+	defer apm.EndTransaction(span)
+
+	tmpl := repository.PromptTemplate{
+		Name:        payload.Name,
+		Description: payload.Description,
+		CreatedBy:   payload.CreatedBy,
+	}
+	for _, m := range payload.Messages {
+		tmpl.Messages = append(tmpl.Messages, repository.TemplateMessage{Role: m.Role, Template: m.Template})
+	}
+	for _, v := range payload.Variables {
+		variable := repository.TemplateVariable{Name: v.Name, Type: v.Type, Required: v.Required, Description: v.Description}
+		if v.Default != nil {
+			data, err := json.Marshal(v.Default)
+			if err != nil {
+				return core.PromptTemplate{}, fmt.Errorf("%w: default of %s: %v", core.ErrInvalidRequest, v.Name, err)
+			}
+			variable.Default = string(data)
+		}
+		tmpl.Variables = append(tmpl.Variables, variable)
+	}
+
+	messages, variables := templateDefinition(tmpl)
+	if err := prompttemplate.Validate(messages, variables); err != nil {
+		return core.PromptTemplate{}, fmt.Errorf("%w: %v", core.ErrInvalidRequest, err)
+	}
+
+	tmpl, err := u.repo.InsertPromptTemplate(ctx, tmpl)
+	if err != nil {
+		return core.PromptTemplate{}, err
+	}
+	return core.ToCorePromptTemplate(tmpl), nil
+}
+
+// GetPromptTemplate returns a prompt template by reference, either name for the latest version or name@vN
+func (u UserService) GetPromptTemplate(ctx context.Context, ref string) (core.PromptTemplate, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::GetPromptTemplate")
+	defer apm.EndTransaction(span)
+
+	tmpl, err := u.resolvePromptTemplate(ctx, ref)
+	if err != nil {
+		return core.PromptTemplate{}, err
+	}
+	return core.ToCorePromptTemplate(tmpl), nil
+}
+
+// ListPromptTemplates returns the latest version of every prompt template
+func (u UserService) ListPromptTemplates(ctx context.Context) ([]core.PromptTemplate, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListPromptTemplates")
+	defer apm.EndTransaction(span)
+
+	templates, err := u.repo.ListPromptTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return core.ToCorePromptTemplates(templates), nil
+}
+
+// ListPromptTemplateVersions returns every version of a prompt template, newest first
+func (u UserService) ListPromptTemplateVersions(ctx context.Context, name string) ([]core.PromptTemplate, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListPromptTemplateVersions")
+	defer apm.EndTransaction(span)
+
+	templates, err := u.repo.ListPromptTemplateVersions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("%w: template %s", core.ErrNotFound, name)
+	}
+	return core.ToCorePromptTemplates(templates), nil
+}
+
+// resolvePromptTemplate finds the template version a reference points to
+func (u UserService) resolvePromptTemplate(ctx context.Context, ref string) (repository.PromptTemplate, error) {
+	name, version, err := parseVersionedRef(ref)
+	if err != nil {
+		return repository.PromptTemplate{}, err
+	}
+	tmpl, err := u.repo.GetPromptTemplate(ctx, name, version)
+	if err != nil {
+		return repository.PromptTemplate{}, fmt.Errorf("%w: template %s", core.ErrNotFound, ref)
+	}
+	return tmpl, nil
+}
+
+// renderPromptTemplate renders a template into messages and returns them with the name@vN of the rendered version
+func (u UserService) renderPromptTemplate(ctx context.Context, ref string, values map[string]interface{}) (string, []core.MessageRequest, error) {
+	tmpl, err := u.resolvePromptTemplate(ctx, ref)
+	if err != nil {
+		return "", nil, err
+	}
+
+	messages, variables := templateDefinition(tmpl)
+	rendered, err := prompttemplate.Render(messages, variables, values)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", core.ErrInvalidRequest, err)
+	}
+
+	res := []core.MessageRequest{}
+	for _, m := range rendered {
+		text := m.Text
+		res = append(res, core.MessageRequest{Role: m.Role, Content: []core.Content{{Type: "text", Text: &text}}})
+	}
+	return core.VersionedRef(tmpl.Name, tmpl.Version), res, nil
+}
+
+// withRenderedTemplate records the template and the prompt rendered from it in the usage ledger of the prompt
+func withRenderedTemplate(ctx context.Context, ref string, messages []core.MessageRequest) context.Context {
+	var prompt strings.Builder
+	for _, m := range messages {
+		for _, c := range m.Content {
+			if c.Text != nil {
+				fmt.Fprintf(&prompt, "%s: %s\n", m.Role, *c.Text)
+			}
+		}
+	}
+	return context.WithValue(ctx, renderedTemplateKey{}, renderedTemplate{Ref: ref, Prompt: prompt.String()})
+}
+
+// templateDefinition converts a stored template for the prompttemplate package
+func templateDefinition(tmpl repository.PromptTemplate) ([]prompttemplate.Message, []prompttemplate.Variable) {
+	messages := []prompttemplate.Message{}
+	for _, m := range tmpl.Messages {
+		messages = append(messages, prompttemplate.Message{Role: m.Role, Template: m.Template})
+	}
+	variables := []prompttemplate.Variable{}
+	for _, v := range tmpl.Variables {
+		variable := prompttemplate.Variable{Name: v.Name, Type: v.Type, Required: v.Required}
+		if v.Default != "" {
+			json.Unmarshal([]byte(v.Default), &variable.Default)
+		}
+		variables = append(variables, variable)
+	}
+	return messages, variables
+}
@@ -0,0 +1,134 @@
+package business
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/abialemuel/AI-Proxy-Service/config"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/redact"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+)
+
+const redisKeyRedactionVault = "redaction-vault-%s"
+
+// redactor returns the redactor of a policy, nil when redaction is disabled
+func (u UserService) redactor(policy config.RedactionPolicy) *redact.Redactor {
+	if policy.Mode == "" {
+		return nil
+	}
+	r, err := redact.New(policy.Mode, policy.Detectors, u.cfg.Redaction.HashKey)
+	if err != nil {
+		// a broken policy must not let PII through, mask with every detector instead
+		fmt.Println("Error creating redactor:", err)
+		r, _ = redact.New(redact.ModeMask, nil, "")
+	}
+	return r
+}
+
+// principalRedactor returns the redactor of the policy that covers a principal's prompts
+func (u UserService) principalRedactor(p principal) *redact.Redactor {
+	if p.Type == principalTypeService {
+		service, _ := u.backendService(p.Name)
+		return u.redactor(service.Redaction)
+	}
+	return u.redactor(u.cfg.Redaction.Users)
+}
+
+// redactTexts redacts a list of texts, such as embedding inputs
+func redactTexts(r *redact.Redactor, texts []string, vault redact.Vault) []string {
+	if r == nil {
+		return texts
+	}
+
+	res := make([]string, 0, len(texts))
+	for _, text := range texts {
+		redacted, _ := r.Redact(text, vault)
+		res = append(res, redacted)
+	}
+	return res
+}
+
+// userVault returns the placeholders of a user's conversation, so a value keeps its placeholder across turns
+func (u UserService) userVault(ctx context.Context, userID string) (redact.Vault, error) {
+	vault := redact.Vault{}
+	data, found := u.cache.Get(ctx, fmt.Sprintf(redisKeyRedactionVault, userID))
+	if !found {
+		return vault, nil
+	}
+	if err := json.Unmarshal([]byte(data.(string)), &vault); err != nil {
+		return nil, fmt.Errorf("error in GPT4 prompt: Unmarshal: %v", err)
+	}
+	return vault, nil
+}
+
+// saveUserVault keeps the placeholders of a user's conversation until the context is cleared
+func (u UserService) saveUserVault(ctx context.Context, userID string, vault redact.Vault) error {
+	if len(vault) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(vault)
+	if err != nil {
+		return err
+	}
+	return u.cache.Set(ctx, fmt.Sprintf(redisKeyRedactionVault, userID), data, u.contextTTL())
+}
+
+// redactContent redacts the text parts of a message
+func redactContent(r *redact.Redactor, content []core.Content, vault redact.Vault) ([]core.Content, []string) {
+	if r == nil {
+		return content, nil
+	}
+
+	res := []core.Content{}
+	detected := []string{}
+	for _, c := range content {
+		if c.Text != nil {
+			text, found := r.Redact(*c.Text, vault)
+			c.Text = &text
+			detected = appendUnique(detected, found...)
+		}
+		res = append(res, c)
+	}
+	return res, detected
+}
+
+// redactMessages redacts the text parts of every message
+func redactMessages(r *redact.Redactor, messages []core.MessageRequest, vault redact.Vault) ([]core.MessageRequest, []string) {
+	if r == nil {
+		return messages, nil
+	}
+
+	res := []core.MessageRequest{}
+	detected := []string{}
+	for _, m := range messages {
+		var found []string
+		m.Content, found = redactContent(r, m.Content, vault)
+		detected = appendUnique(detected, found...)
+		res = append(res, m)
+	}
+	return res, detected
+}
+
+// restoreResponse puts the redacted values back in place of their placeholders
+func restoreResponse(res *core.GPT4PromptResponse, vault redact.Vault) {
+	for i := range res.Choices {
+		res.Choices[i].Message.Content = redact.Restore(res.Choices[i].Message.Content, vault)
+	}
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
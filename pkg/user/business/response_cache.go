@@ -0,0 +1,63 @@
+package business
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+)
+
+const redisKeyResponseCache = "response-cache-%s-%s"
+
+// responseCacheKey returns the cache key of a service prompt, or false when its response must not be cached.
+// Only deterministic prompts of services with a response cache TTL are cached.
+func (u UserService) responseCacheKey(serviceName string, payload gpt4_webservice.GPT4PromptRequestDao) (string, bool) {
+	service, _ := u.backendService(serviceName)
+	if service.ResponseCacheTTL <= 0 || payload.Temperature != 0 {
+		return "", false
+	}
+
+	// struct fields marshal in a fixed order, so equal requests hash equally
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(redisKeyResponseCache, serviceName, hex.EncodeToString(sum[:])), true
+}
+
+// getCachedResponse returns a previously stored response for key
+func (u UserService) getCachedResponse(ctx context.Context, key string) (core.ServicePromGPTResponse, bool) {
+	data, found := u.cache.Get(ctx, key)
+	if !found {
+		return core.ServicePromGPTResponse{}, false
+	}
+	raw, ok := data.(string)
+	if !ok {
+		return core.ServicePromGPTResponse{}, false
+	}
+
+	var res core.ServicePromGPTResponse
+	if err := json.Unmarshal([]byte(raw), &res); err != nil {
+		return core.ServicePromGPTResponse{}, false
+	}
+	res.Cached = true
+	return res, true
+}
+
+// setCachedResponse stores a response for the service's response cache TTL
+func (u UserService) setCachedResponse(ctx context.Context, serviceName string, key string, res core.ServicePromGPTResponse) {
+	service, _ := u.backendService(serviceName)
+	data, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	if err := u.cache.Set(ctx, key, data, time.Duration(service.ResponseCacheTTL)*time.Second); err != nil {
+		fmt.Println("Error caching response:", err)
+	}
+}
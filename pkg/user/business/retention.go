@@ -0,0 +1,216 @@
+package business
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+)
+
+// erasedPrefix marks the pseudonym left in the usage ledger and the audit trail in place of an erased email
+const erasedPrefix = "erased-"
+
+// samplePseudonymKey is the placeholder of the sample config, pseudonyms made with it could be reversed by anyone
+const samplePseudonymKey = "change-me"
+
+// RunRetention purges expired conversations and service prompts every purge interval until ctx is done
+func (u UserService) RunRetention(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(u.cfg.Retention.PurgeInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		if err := u.PurgeExpired(ctx); err != nil {
+			fmt.Println("Error purging expired data:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpired removes the conversations and service prompts older than their retention policy,
+// then the images in the blob store nothing refers to anymore.
+func (u UserService) PurgeExpired(ctx context.Context) error {
+	ctx, span := apm.StartTransaction(ctx, "Service::PurgeExpired")
+	defer apm.EndTransaction(span)
+
+	now := time.Now()
+	var tenants, images []string
+	for domain, policy := range u.cfg.Retention.Tenants {
+		tenants = append(tenants, domain)
+		if policy.Days <= 0 {
+			continue
+		}
+		_, refs, err := u.repo.PurgeConversations(ctx, []string{domain}, nil, now.AddDate(0, 0, -policy.Days))
+		if err != nil {
+			return fmt.Errorf("purging conversations of %s: %w", domain, err)
+		}
+		images = append(images, refs...)
+	}
+	// users of a tenant follow its policy only, even when it keeps their data for longer
+	if days := u.cfg.Retention.Users.Days; days > 0 {
+		_, refs, err := u.repo.PurgeConversations(ctx, nil, tenants, now.AddDate(0, 0, -days))
+		if err != nil {
+			return fmt.Errorf("purging conversations: %w", err)
+		}
+		images = append(images, refs...)
+	}
+
+	for _, service := range u.cfg.Services {
+		if service.Retention.Days <= 0 {
+			continue
+		}
+		_, refs, err := u.repo.PurgeServiceMessages(ctx, service.Name, now.AddDate(0, 0, -service.Retention.Days))
+		if err != nil {
+			return fmt.Errorf("purging prompts of %s: %w", service.Name, err)
+		}
+		images = append(images, refs...)
+	}
+
+	u.deleteUnreferencedImages(ctx, images)
+	return nil
+}
+
+// EraseUserData removes everything kept about a user: the cached context, conversations, shares, knowledge
+// bases, the images only they referred to and the semantic cache entries of their prompts. Usage records and audit entries are kept for chargeback under
+// a pseudonym. The user document of a blocked user is kept so the block outlives the erasure.
+// actor is the admin who asked for it, or empty when the user did.
+func (u UserService) EraseUserData(ctx context.Context, email string, actor string) (core.ErasureReport, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::EraseUserData")
+	defer apm.EndTransaction(span)
+
+	var report core.ErasureReport
+	// the pseudonym is checked first, an erasure must not stop halfway
+	pseudonym, err := u.erasedPseudonym(email)
+	if err != nil {
+		return report, err
+	}
+	images, err := u.repo.ListConversationImages(ctx, email)
+	if err != nil {
+		return report, err
+	}
+
+	// token usage and grants stay, erasing data must not reset a quota
+	u.clearContextKeys(ctx, email)
+
+	report.Conversations, err = u.repo.DeleteUserConversations(ctx, email)
+	if err != nil {
+		return report, err
+	}
+	if err = u.repo.DeleteShares(ctx, email); err != nil {
+		return report, err
+	}
+
+	kbs, err := u.repo.ListKnowledgeBases(ctx, email)
+	if err != nil {
+		return report, err
+	}
+	for _, kb := range kbs {
+		if u.vectorRepo != nil {
+			if err = u.vectorRepo.DeleteKnowledgeBaseChunks(ctx, kb.ID); err != nil {
+				return report, err
+			}
+		}
+		if err = u.repo.DeleteKnowledgeBase(ctx, kb.ID); err != nil {
+			return report, err
+		}
+		report.KnowledgeBases++
+	}
+
+	report.Images = u.deleteUnreferencedImages(ctx, images)
+
+	if u.semanticCache != nil {
+		report.CacheEntries, err = u.semanticCache.DeleteOwner(ctx, email)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	report.UsageRecords, err = u.repo.PseudonymizeUsage(ctx, email, pseudonym)
+	if err != nil {
+		return report, err
+	}
+	report.AuditEntries, err = u.repo.PseudonymizeAuditEntries(ctx, email, pseudonym)
+	if err != nil {
+		return report, err
+	}
+
+	if user, err := u.repo.GetUser(ctx, email); err == nil && !user.Blocked {
+		if err = u.repo.DeleteUser(ctx, email); err != nil {
+			return report, err
+		}
+	}
+
+	if actor == "" {
+		actor = "self"
+	}
+	u.audit(principal{Name: pseudonym, Type: principalTypeUser}, core.AuditActionDataErased, map[string]string{
+		"actor":           actor,
+		"conversations":   strconv.FormatInt(report.Conversations, 10),
+		"knowledge_bases": strconv.Itoa(report.KnowledgeBases),
+		"images":          strconv.Itoa(report.Images),
+		"cache_entries":   strconv.Itoa(report.CacheEntries),
+		"usage_records":   strconv.FormatInt(report.UsageRecords, 10),
+		"audit_entries":   strconv.FormatInt(report.AuditEntries, 10),
+	})
+	return report, nil
+}
+
+// deleteUnreferencedImages removes the images of refs from the blob store unless a message or a share still
+// refers to them. Images are stored once by content hash, another user may have sent the same one.
+// It returns the number of images removed.
+func (u UserService) deleteUnreferencedImages(ctx context.Context, refs []string) int {
+	if u.blobStore == nil {
+		return 0
+	}
+	deleted := 0
+	for _, ref := range refs {
+		referenced, err := u.repo.ImageReferenced(ctx, ref)
+		if err != nil || referenced {
+			continue
+		}
+		if err = u.blobStore.Delete(ctx, strings.TrimPrefix(ref, blobScheme)); err == nil {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// erasedPseudonym derives a stable pseudonym from an email, so the ledger of an erased user still adds up.
+// It needs its own secret key, with a known one the pseudonym of any email could be computed.
+func (u UserService) erasedPseudonym(email string) (string, error) {
+	key := u.cfg.Retention.PseudonymKey
+	if key == "" || key == samplePseudonymKey {
+		return "", fmt.Errorf("%w: erasure needs a pseudonym key", core.ErrNotConfigured)
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strings.ToLower(email)))
+	return erasedPrefix + hex.EncodeToString(mac.Sum(nil))[:16], nil
+}
+
+// contextTTL is how long the cached state of an idle conversation is kept, 0 keeps it until it is cleared
+func (u UserService) contextTTL() time.Duration {
+	return time.Duration(u.cfg.Retention.ContextTTL) * time.Second
+}
+
+// touchContextKeys restarts the TTL of the cached conversation state that a prompt did not write
+func (u UserService) touchContextKeys(ctx context.Context, userID string) {
+	ttl := u.contextTTL()
+	if ttl <= 0 {
+		return
+	}
+	for _, format := range []string{redisKeySummary, redisKeyPersona, redisKeySettings, redisKeyRedactionVault} {
+		key := fmt.Sprintf(format, userID)
+		if val, found := u.cache.Get(ctx, key); found {
+			u.cache.Set(ctx, key, val, ttl)
+		}
+	}
+}
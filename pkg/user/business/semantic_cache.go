@@ -0,0 +1,115 @@
+package business
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/embedding_webservice"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/vectorstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+)
+
+const semanticScopeUsers = "users"
+
+// semanticQuery is a prompt looked up in the semantic cache, kept to store the answer on a miss
+type semanticQuery struct {
+	owner     string
+	namespace string
+	vector    []float32
+}
+
+// semanticHit is an answer served from the semantic cache
+type semanticHit struct {
+	response gpt4_webservice.GPT4PromptResponseDao
+	score    float64
+}
+
+// semanticCacheLookup embeds the final user message and searches past answers given the same preceding messages.
+// The query is nil when the prompt can't be cached, e.g. its final message has images.
+func (u UserService) semanticCacheLookup(ctx context.Context, p principal, scope string, threshold float64, payload gpt4_webservice.GPT4PromptRequestDao) (*semanticQuery, *semanticHit) {
+	if !u.cfg.SemanticCache.Enabled || u.semanticCache == nil || u.embeddingWebservice == nil || len(payload.Message) == 0 {
+		return nil, nil
+	}
+	last := payload.Message[len(payload.Message)-1]
+	if last.Role != userDefaultRole {
+		return nil, nil
+	}
+	var parts []string
+	for _, content := range last.Content {
+		if content.Type != "text" || content.Text == nil {
+			return nil, nil
+		}
+		parts = append(parts, *content.Text)
+	}
+	text := strings.TrimSpace(strings.Join(parts, "\n"))
+	if text == "" {
+		return nil, nil
+	}
+
+	// answers only match prompts with the same model and earlier messages
+	prefix, err := json.Marshal(struct {
+		Model    string                       `json:"model"`
+		Messages []gpt4_webservice.MessageReq `json:"messages"`
+	}{payload.Model, payload.Message[:len(payload.Message)-1]})
+	if err != nil {
+		return nil, nil
+	}
+	sum := sha256.Sum256(prefix)
+	query := &semanticQuery{owner: p.Name, namespace: fmt.Sprintf("%s-%s", scope, hex.EncodeToString(sum[:]))}
+
+	res, err := u.embed(ctx, p, usageKindSemanticCache, embedding_webservice.EmbeddingRequestDao{
+		Model: u.cfg.Embeddings.Model,
+		Input: []string{text},
+	})
+	if err != nil || len(res.Data) == 0 {
+		fmt.Println("Error embedding prompt for the semantic cache:", err)
+		return nil, nil
+	}
+	query.vector = res.Data[0].Embedding
+
+	if threshold <= 0 {
+		threshold = u.cfg.SemanticCache.Threshold
+	}
+	match, found := u.semanticCache.Search(ctx, query.namespace, query.vector, threshold)
+	if !found {
+		return query, nil
+	}
+	var response gpt4_webservice.GPT4PromptResponseDao
+	if err := json.Unmarshal([]byte(match.Payload), &response); err != nil {
+		return query, nil
+	}
+
+	apm.AddEvent(ctx, "SemanticCacheHit",
+		attribute.String("user_id", p.Name),
+		attribute.Float64("score", match.Score),
+	)
+	return query, &semanticHit{response: response, score: match.Score}
+}
+
+// semanticCacheStore remembers the answer to a prompt that missed the semantic cache
+func (u UserService) semanticCacheStore(ctx context.Context, query *semanticQuery, res gpt4_webservice.GPT4PromptResponseDao) {
+	if query == nil || query.vector == nil || len(res.Choices) == 0 {
+		return
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	err = u.semanticCache.Add(ctx, query.namespace, vectorstore.Entry{
+		ID:      primitive.NewObjectID().Hex(),
+		Vector:  query.vector,
+		Payload: string(data),
+		Owner:   query.owner,
+	})
+	if err != nil {
+		fmt.Println("Error storing semantic cache entry:", err)
+	}
+}
@@ -0,0 +1,135 @@
+package business
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+)
+
+const redisKeySettings = "settings-%s"
+
+// conversationSettings returns the model settings of a user's conversation. Settings in the request are
+// merged in and kept for later turns. Stored settings are checked against the role on every turn, as the
+// role may have changed since they were chosen.
+func (u UserService) conversationSettings(ctx context.Context, userID string, role string, requested core.ModelSettings) (core.ModelSettings, error) {
+	settingsKey := fmt.Sprintf(redisKeySettings, userID)
+	var settings core.ModelSettings
+	if stored, found := u.cache.Get(ctx, settingsKey); found {
+		if err := json.Unmarshal([]byte(stored.(string)), &settings); err != nil {
+			return core.ModelSettings{}, fmt.Errorf("error in GPT4 prompt: Unmarshal: %v", err)
+		}
+	}
+
+	settings = mergeModelSettings(settings, requested)
+	if err := u.validateModelSettings(ctx, role, settings); err != nil {
+		return core.ModelSettings{}, err
+	}
+	if !hasModelSettings(requested) {
+		return settings, nil
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return core.ModelSettings{}, err
+	}
+	if err := u.cache.Set(ctx, settingsKey, data, u.contextTTL()); err != nil {
+		return core.ModelSettings{}, err
+	}
+	if err := u.repo.SetConversationSettings(ctx, userID, core.ToRepoModelSettings(settings)); err != nil {
+		return core.ModelSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// validateModelSettings checks user chosen settings against the allowed models and ranges of the role
+func (u UserService) validateModelSettings(ctx context.Context, role string, settings core.ModelSettings) error {
+	if settings.Model != "" {
+		if err := u.validateModel(ctx, role, settings.Model); err != nil {
+			return err
+		}
+	}
+
+	policy := u.cfg.Roles[role]
+	if settings.Temperature != nil && policy.MinTemperature > 0 && *settings.Temperature < policy.MinTemperature {
+		return fmt.Errorf("%w: temperature below %v is not allowed for role %s", core.ErrInvalidRequest, policy.MinTemperature, role)
+	}
+	if settings.Temperature != nil && policy.MaxTemperature > 0 && *settings.Temperature > policy.MaxTemperature {
+		return fmt.Errorf("%w: temperature above %v is not allowed for role %s", core.ErrInvalidRequest, policy.MaxTemperature, role)
+	}
+	if settings.TopP != nil && policy.MinTopP > 0 && *settings.TopP < policy.MinTopP {
+		return fmt.Errorf("%w: top_p below %v is not allowed for role %s", core.ErrInvalidRequest, policy.MinTopP, role)
+	}
+	if settings.TopP != nil && policy.MaxTopP > 0 && *settings.TopP > policy.MaxTopP {
+		return fmt.Errorf("%w: top_p above %v is not allowed for role %s", core.ErrInvalidRequest, policy.MaxTopP, role)
+	}
+	if settings.MaxTokens != nil && policy.MaxTokens > 0 && *settings.MaxTokens > policy.MaxTokens {
+		return fmt.Errorf("%w: max_tokens above %d is not allowed for role %s", core.ErrInvalidRequest, policy.MaxTokens, role)
+	}
+	if policy.MaxStop > 0 && len(settings.Stop) > policy.MaxStop {
+		return fmt.Errorf("%w: more than %d stop sequences are not allowed for role %s", core.ErrInvalidRequest, policy.MaxStop, role)
+	}
+	if policy.MaxStopLength > 0 {
+		for _, stop := range settings.Stop {
+			if utf8.RuneCountInString(stop) > policy.MaxStopLength {
+				return fmt.Errorf("%w: stop sequences longer than %d characters are not allowed for role %s", core.ErrInvalidRequest, policy.MaxStopLength, role)
+			}
+		}
+	}
+	if settings.Seed != nil && policy.DenySeed {
+		return fmt.Errorf("%w: seed is not allowed for role %s", core.ErrInvalidRequest, role)
+	}
+	return nil
+}
+
+// applyModelSettings sets the parameters a user chose
+func applyModelSettings(payload *gpt4_webservice.GPT4PromptRequestDao, settings core.ModelSettings) {
+	if settings.Model != "" {
+		payload.Model = settings.Model
+	}
+	if settings.Temperature != nil {
+		payload.Temperature = *settings.Temperature
+	}
+	if settings.TopP != nil {
+		payload.TopP = *settings.TopP
+	}
+	if settings.MaxTokens != nil {
+		payload.MaxTokens = *settings.MaxTokens
+	}
+	if len(settings.Stop) > 0 {
+		payload.Stop = settings.Stop
+	}
+	payload.Seed = settings.Seed
+}
+
+// mergeModelSettings overrides the fields of base that are set in override, an empty stop list clears it
+func mergeModelSettings(base core.ModelSettings, override core.ModelSettings) core.ModelSettings {
+	if override.Model != "" {
+		base.Model = override.Model
+	}
+	if override.Temperature != nil {
+		base.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		base.TopP = override.TopP
+	}
+	if override.MaxTokens != nil {
+		base.MaxTokens = override.MaxTokens
+	}
+	if override.Stop != nil {
+		base.Stop = override.Stop
+	}
+	if override.Seed != nil {
+		base.Seed = override.Seed
+	}
+	return base
+}
+
+func hasModelSettings(settings core.ModelSettings) bool {
+	return settings.Model != "" || settings.Temperature != nil || settings.TopP != nil ||
+		settings.MaxTokens != nil || settings.Stop != nil || settings.Seed != nil
+}
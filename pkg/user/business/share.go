@@ -0,0 +1,160 @@
+package business
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// shareTokenBytes is the entropy of a share token, the token is the only secret protecting an open share
+const shareTokenBytes = 24
+
+// CreateShare takes a snapshot of the current branch of a conversation and returns the token to view it.
+// The token is only returned here, the share keeps its hash.
+func (u UserService) CreateShare(ctx context.Context, req core.ShareRequest) (core.Share, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::CreateShare")
+	defer apm.EndTransaction(span)
+
+	id, err := primitive.ObjectIDFromHex(req.ConversationID)
+	if err != nil {
+		return core.Share{}, fmt.Errorf("%w: conversation %s", core.ErrNotFound, req.ConversationID)
+	}
+	conversation, err := u.repo.GetConversation(ctx, id)
+	if err != nil || conversation.UserID != req.Owner {
+		return core.Share{}, fmt.Errorf("%w: conversation %s", core.ErrNotFound, req.ConversationID)
+	}
+	messages, err := u.repo.ListConversationMessages(ctx, conversation.ID)
+	if err != nil {
+		return core.Share{}, err
+	}
+	path := conversationPath(messages, conversation.ActiveLeaf)
+	if len(path) == 0 {
+		return core.Share{}, fmt.Errorf("%w: the conversation has no messages", core.ErrInvalidRequest)
+	}
+	// ratings are the owner's own business
+	for i := range path {
+		path[i].Feedback = nil
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return core.Share{}, err
+	}
+	share := repository.Share{
+		TokenHash:      hashShareToken(token),
+		Owner:          req.Owner,
+		ConversationID: conversation.ID,
+		Persona:        conversation.Persona,
+		Messages:       path,
+	}
+	if req.SameDomain {
+		share.Domain = emailDomain(req.Owner)
+	}
+	if req.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(req.ExpiresIn)
+		share.ExpiresAt = &expiresAt
+	}
+
+	share, err = u.repo.InsertShare(ctx, share)
+	if err != nil {
+		return core.Share{}, err
+	}
+	res := core.ToCoreShare(share)
+	res.Token = token
+	return res, nil
+}
+
+// ListShares returns the shares created by a user
+func (u UserService) ListShares(ctx context.Context, owner string) ([]core.Share, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListShares")
+	defer apm.EndTransaction(span)
+
+	shares, err := u.repo.ListShares(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return core.ToCoreShares(shares), nil
+}
+
+// RevokeShare deletes a share of a user
+func (u UserService) RevokeShare(ctx context.Context, owner string, id string) error {
+	ctx, span := apm.StartTransaction(ctx, "Service::RevokeShare")
+	defer apm.EndTransaction(span)
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: share %s", core.ErrNotFound, id)
+	}
+	if err = u.repo.DeleteShare(ctx, owner, objectID); err != nil {
+		return fmt.Errorf("%w: share %s", core.ErrNotFound, id)
+	}
+	return nil
+}
+
+// ViewShare returns the snapshot behind a share token, viewer is empty for anonymous callers
+func (u UserService) ViewShare(ctx context.Context, token string, viewer string) (core.SharedConversation, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ViewShare")
+	defer apm.EndTransaction(span)
+
+	share, err := u.repo.GetShareByTokenHash(ctx, hashShareToken(token))
+	if err != nil || (share.ExpiresAt != nil && share.ExpiresAt.Before(time.Now())) {
+		return core.SharedConversation{}, fmt.Errorf("%w: share", core.ErrNotFound)
+	}
+	if share.Domain != "" {
+		if viewer == "" {
+			return core.SharedConversation{}, fmt.Errorf("%w: the conversation is shared with signed in users only", core.ErrSignInRequired)
+		}
+		if emailDomain(viewer) != share.Domain {
+			return core.SharedConversation{}, fmt.Errorf("%w: the conversation is shared with %s users only", core.ErrShareNotAllowed, share.Domain)
+		}
+	}
+
+	shared := core.ToCoreSharedConversation(share)
+	for i := range shared.Messages {
+		shared.Messages[i].Content = u.inlineImages(ctx, shared.Messages[i].Content)
+	}
+	return shared, nil
+}
+
+// ForkShare copies a shared snapshot into a new conversation of the viewer, it becomes their current conversation
+func (u UserService) ForkShare(ctx context.Context, token string, userID string, role string) (core.ConversationTree, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ForkShare")
+	defer apm.EndTransaction(span)
+
+	shared, err := u.ViewShare(ctx, token, userID)
+	if err != nil {
+		return core.ConversationTree{}, err
+	}
+	return u.ImportConversation(ctx, userID, role, core.ConversationExport{
+		Persona:  shared.Persona,
+		Messages: shared.Messages,
+	})
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// emailDomain returns the lower-cased domain of an email address
+func emailDomain(email string) string {
+	return strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+}
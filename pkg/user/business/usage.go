@@ -0,0 +1,210 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/config"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/embedding_webservice"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+)
+
+// modelVersionSuffix matches the date a model snapshot is versioned with
+var modelVersionSuffix = regexp.MustCompile(`-\d{4}-\d{2}-\d{2}$`)
+
+const (
+	usageKindPrompt  = "prompt"
+	usageKindSummary = "summary"
+	usageKindService = "service"
+
+	usageKindEmbedding     = "embedding"
+	usageKindSemanticCache = "semantic_cache"
+	usageKindRAGIngest     = "rag_ingest"
+	usageKindRAGQuery      = "rag_query"
+
+	principalTypeUser    = "user"
+	principalTypeService = "service"
+)
+
+// principal identifies who an upstream call is charged to
+type principal struct {
+	Name  string
+	Type  string
+	Tribe string
+}
+
+// userPrincipal returns the principal of an SSO user, charged to the tribe configured for their email domain
+func (u UserService) userPrincipal(userID string) principal {
+	return principal{Name: userID, Type: principalTypeUser, Tribe: u.cfg.UserTribes[emailDomain(userID)]}
+}
+
+// servicePrincipal returns the principal of a backend service with its configured tribe
+func (u UserService) servicePrincipal(serviceName string) principal {
+	service, _ := u.backendService(serviceName)
+	return principal{Name: serviceName, Type: principalTypeService, Tribe: service.Tribe}
+}
+
+// ownerPrincipal returns the principal of a resource owner, which is either a backend service name or a user email
+func (u UserService) ownerPrincipal(owner string) principal {
+	if _, ok := u.backendService(owner); ok {
+		return u.servicePrincipal(owner)
+	}
+	return u.userPrincipal(owner)
+}
+
+// prompt calls the upstream and records the call in the usage ledger
+func (u UserService) prompt(ctx context.Context, p principal, kind string, payload gpt4_webservice.GPT4PromptRequestDao) (gpt4_webservice.GPT4PromptResponseDao, error) {
+	// history keeps blob references, the upstream gets the images themselves
+	messages, err := u.resolveImages(ctx, payload.Message)
+	if err != nil {
+		return gpt4_webservice.GPT4PromptResponseDao{}, err
+	}
+	payload.Message = messages
+
+	start := time.Now()
+	res, err := u.gpt4Webservice.Prompt(ctx, payload)
+	if errors.Is(err, gpt4_webservice.ErrContentFilter) {
+		err = u.contentFiltered(ctx, p, &core.ContentFilteredError{Stage: core.GuardrailStageInput, Rule: core.GuardrailRuleUpstream})
+	}
+
+	usage := repository.Usage{
+		Principal:        p.Name,
+		PrincipalType:    p.Type,
+		Tribe:            p.Tribe,
+		Kind:             kind,
+		Model:            res.Model,
+		Upstream:         u.upstreamHost(),
+		PromptTokens:     res.Usage.PromptTokens,
+		CompletionTokens: res.Usage.CompletionTokens,
+		TotalTokens:      res.Usage.TotalTokens,
+		LatencyMs:        time.Since(start).Milliseconds(),
+		Status:           "success",
+		CreatedAt:        start,
+	}
+	if usage.Model == "" {
+		usage.Model = payload.Model
+	}
+	usage.Cost = u.usageCost(usage.Model, usage.PromptTokens, usage.CompletionTokens)
+	if err != nil {
+		usage.Status = "error"
+		usage.Error = err.Error()
+	}
+	if rendered, ok := ctx.Value(renderedTemplateKey{}).(renderedTemplate); ok {
+		usage.Template = rendered.Ref
+		usage.RenderedPrompt = rendered.Prompt
+	}
+	go u.insertUsage(context.Background(), usage)
+	u.publish(ctx, core.EventPromptCompleted, core.PromptCompletedEvent{
+		Principal:        usage.Principal,
+		PrincipalType:    usage.PrincipalType,
+		Tribe:            usage.Tribe,
+		Kind:             usage.Kind,
+		Model:            usage.Model,
+		Upstream:         usage.Upstream,
+		Status:           usage.Status,
+		Error:            usage.Error,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		Cost:             usage.Cost,
+		LatencyMs:        usage.LatencyMs,
+		Template:         usage.Template,
+	})
+
+	return res, err
+}
+
+// embed calls the embeddings upstream and records the call in the usage ledger
+func (u UserService) embed(ctx context.Context, p principal, kind string, payload embedding_webservice.EmbeddingRequestDao) (embedding_webservice.EmbeddingResponseDao, error) {
+	start := time.Now()
+	res, err := u.embeddingWebservice.Embed(ctx, payload)
+
+	usage := repository.Usage{
+		Principal:     p.Name,
+		PrincipalType: p.Type,
+		Tribe:         p.Tribe,
+		Kind:          kind,
+		Model:         res.Model,
+		Upstream:      hostOf(u.cfg.Embeddings.Host),
+		PromptTokens:  res.Usage.PromptTokens,
+		TotalTokens:   res.Usage.TotalTokens,
+		LatencyMs:     time.Since(start).Milliseconds(),
+		Status:        "success",
+		CreatedAt:     start,
+	}
+	if usage.Model == "" {
+		usage.Model = payload.Model
+	}
+	usage.Cost = u.usageCost(usage.Model, usage.PromptTokens, 0)
+	if err != nil {
+		usage.Status = "error"
+		usage.Error = err.Error()
+	}
+	go u.insertUsage(context.Background(), usage)
+
+	return res, err
+}
+
+// usageCost computes the cost of a call from the configured pricing
+func (u UserService) usageCost(model string, promptTokens int, completionTokens int) float64 {
+	pricing, ok := u.modelPricing(model)
+	if !ok {
+		return 0
+	}
+	return float64(promptTokens)/1000*pricing.PromptPer1K + float64(completionTokens)/1000*pricing.CompletionPer1K
+}
+
+// modelPricing returns the price of a model. Azure reports versioned names such as gpt-4o-mini-2024-07-18,
+// those fall back to the price of the unversioned model.
+func (u UserService) modelPricing(model string) (config.ModelPricing, bool) {
+	// viper lowercases map keys
+	model = strings.ToLower(model)
+	if pricing, ok := u.cfg.Pricing[model]; ok {
+		return pricing, true
+	}
+	pricing, ok := u.cfg.Pricing[modelVersionSuffix.ReplaceAllString(model, "")]
+	return pricing, ok
+}
+
+// upstreamHost returns the host of the configured upstream
+func (u UserService) upstreamHost() string {
+	return hostOf(u.cfg.OpenAI.Host)
+}
+
+// hostOf returns the host of an upstream URL, or the URL itself when it has none
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return rawURL
+	}
+	return parsed.Host
+}
+
+func (u UserService) insertUsage(ctx context.Context, usage repository.Usage) error {
+	err := u.repo.InsertUsage(ctx, usage)
+	if err != nil {
+		fmt.Println("Error inserting usage:", err)
+		return err
+	}
+	return nil
+}
+
+// UsageReport aggregates the usage ledger between from and to
+func (u UserService) UsageReport(ctx context.Context, groupBy string, from time.Time, to time.Time) ([]core.UsageReport, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::UsageReport")
+	defer apm.EndTransaction(span)
+
+	aggregates, err := u.repo.AggregateUsage(ctx, groupBy, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return core.ToCoreUsageReports(aggregates), nil
+}
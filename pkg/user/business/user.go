@@ -5,14 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/abialemuel/AI-Proxy-Service/config"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/redact"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/contract"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/gpt4_webservice"
 	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
 	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.opentelemetry.io/otel/attribute"
 )
 
@@ -25,6 +28,8 @@ const (
 	redisKeyContext           = "context-%s"
 	redisKeySummary           = "summary-%s"
 	redisKeyTokenUsage        = "token-usage-%s"
+	redisKeyTokenGrant        = "token-grant-%s"
+	redisKeyServiceTokenUsage = "service-token-usage-%s"
 	summaryDefaultTemperature = 0.4  // Lower temperature for more focused summaries
 	summaryDefaultTop         = 0.65 // Slightly lower for more predictable responses
 	summaryDefaultMaxTokens   = 100  // A shorter max token count for concise summaries
@@ -37,6 +42,18 @@ type UserService struct {
 	cache          contract.Cache
 	cfg            *config.MainConfig
 	gpt4Webservice contract.GPT4WebService
+	publisher      contract.Publisher
+	webhookSender  contract.WebhookSender
+	batches        *batchRunner
+
+	embeddingWebservice contract.EmbeddingWebService
+	semanticCache       contract.VectorStore
+	vectorRepo          contract.VectorRepository
+
+	moderationWebservice contract.ModerationWebService
+	blobStore            contract.BlobStore
+	imageFetcher         contract.ImageFetcher
+	guards               map[string]compiledGuard
 }
 
 // NewUserService creates a new instance of UserService
@@ -45,8 +62,33 @@ func NewUserService(
 	cache contract.Cache,
 	cfg *config.MainConfig,
 	gpt4Webservice contract.GPT4WebService,
+	publisher contract.Publisher,
+	webhookSender contract.WebhookSender,
+	embeddingWebservice contract.EmbeddingWebService,
+	semanticCache contract.VectorStore,
+	vectorRepo contract.VectorRepository,
+	moderationWebservice contract.ModerationWebService,
+	blobStore contract.BlobStore,
+	imageFetcher contract.ImageFetcher,
 ) UserService {
-	return UserService{repo: repo, cache: cache, cfg: cfg, gpt4Webservice: gpt4Webservice}
+	return UserService{
+		repo:           repo,
+		cache:          cache,
+		cfg:            cfg,
+		gpt4Webservice: gpt4Webservice,
+		publisher:      publisher,
+		webhookSender:  webhookSender,
+		batches:        newBatchRunner(cfg.Batch.Concurrency),
+
+		embeddingWebservice: embeddingWebservice,
+		semanticCache:       semanticCache,
+		vectorRepo:          vectorRepo,
+
+		moderationWebservice: moderationWebservice,
+		blobStore:            blobStore,
+		imageFetcher:         imageFetcher,
+		guards:               newGuards(cfg),
+	}
 }
 
 // UserPromtGPT handles the GPT prompt request for a user
@@ -62,18 +104,82 @@ func (u UserService) UserPromtGPT(ctx context.Context, payload core.UserPromtGPT
 	}()
 	defer apm.EndTransaction(span)
 
+	persona, err := u.conversationPersona(ctx, payload.UserID, payload.Persona)
+	if err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+	gpt4Payload := gpt4_webservice.GPT4PromptRequestDao{
+		Model:       userDefaultModel,
+		Temperature: userDefaultTemperature,
+		MaxTokens:   userDefaultMaxTokens,
+		TopP:        userDefaultTop,
+	}
+	if persona != nil {
+		applyPersonaDefaults(&gpt4Payload, *persona)
+	}
+
+	// settings chosen by the user win over the persona
+	settings, err := u.conversationSettings(ctx, payload.UserID, payload.Role, payload.Settings)
+	if err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+	applyModelSettings(&gpt4Payload, settings)
+
+	err = u.validateModel(ctx, payload.Role, gpt4Payload.Model)
+	if err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+
 	// Validate token usage
-	token, valid, expiredDuration, tokenExist := u.validateTokenUsage(ctx, payload.UserID)
-	if !valid {
-		return core.UserPromGPTResponse{}, fmt.Errorf("token usage limit reached: %d token, Your limit resets after %s", token, expiredDuration)
+	if err = u.validateUserQuota(ctx, payload.UserID); err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+	// tokens spent by this prompt, charged once it is answered
+	var token int
+
+	// images are validated and kept in the blob store, history only holds their references.
+	// A prompt taken from the conversation was prepared when it was first sent.
+	if payload.PromptMessageID == "" {
+		payload.Content, err = u.prepareImages(ctx, payload.Content)
+		if err != nil {
+			return core.UserPromGPTResponse{}, err
+		}
 	}
 
+	// PII is replaced before the prompt is sent or stored, placeholders are put back in the answer only
+	vault, err := u.userVault(ctx, payload.UserID)
+	if err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+	redactor := u.redactor(u.cfg.Redaction.Users)
+	payload.Content, res.Redactions = redactContent(redactor, payload.Content, vault)
+	if err = u.saveUserVault(ctx, payload.UserID, vault); err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+	defer func() {
+		if err == nil {
+			restoreResponse(&res.GPT4PromptResponse, vault)
+		}
+	}()
+
 	var existingMsgs, existingSummary []gpt4_webservice.MessageReq
 	var newSummary gpt4_webservice.MessageReq
 	contextKey := fmt.Sprintf(redisKeyContext, payload.UserID)
 	summaryKey := fmt.Sprintf(redisKeySummary, payload.UserID)
 	newContent := core.ToWebServiceUserPromtGPTContentRequest(payload.Content)
 
+	// earlier turns were screened when they were sent, only the new one is
+	err = u.checkInput(ctx, u.userPrincipal(payload.UserID), u.cfg.Guardrails.Users, []gpt4_webservice.MessageReq{{Content: newContent, Role: userDefaultRole}})
+	if err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+
+	// the answer continues the branch the context is read from, even when another prompt is stored first
+	current, err := u.activeConversation(ctx, payload.UserID)
+	if err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+
 	// Retrieve existing messages from cache
 	existingData, success := u.cache.Get(ctx, contextKey)
 	if success {
@@ -108,25 +214,65 @@ func (u UserService) UserPromtGPT(ctx context.Context, payload core.UserPromtGPT
 		promptPayload = existingMsgs
 	}
 
+	// the persona leads the prompt, it is never part of the stored context or its summaries
+	if persona != nil {
+		promptPayload = append([]gpt4_webservice.MessageReq{personaMessage(*persona)}, promptPayload...)
+	}
+
+	// inject knowledge base context for this turn only, it isn't kept in the conversation
+	if len(payload.KnowledgeBases) > 0 {
+		contextMsg, citations, ragTokens, err := u.retrieveContext(ctx, u.userPrincipal(payload.UserID), payload.UserID, payload.KnowledgeBases, messageText(newContent), vault)
+		if err != nil {
+			return core.UserPromGPTResponse{}, err
+		}
+		token += ragTokens
+		if contextMsg != nil {
+			// placeholders of the context may show up in answers of later turns
+			if err = u.saveUserVault(ctx, payload.UserID, vault); err != nil {
+				return core.UserPromGPTResponse{}, err
+			}
+			promptPayload = withContext(promptPayload, *contextMsg)
+			res.Citations = citations
+		}
+	}
+
 	// Prepare OpenAI prompt request
-	gpt4Payload := gpt4_webservice.GPT4PromptRequestDao{
-		Message:     promptPayload,
-		Temperature: userDefaultTemperature,
-		MaxTokens:   userDefaultMaxTokens,
-		TopP:        userDefaultTop,
+	gpt4Payload.Message = promptPayload
+
+	// only the opening prompt of a conversation is answered from the semantic cache, a regeneration wants a new answer
+	var semantic *semanticQuery
+	var hit *semanticHit
+	if u.cfg.SemanticCache.Users && len(existingSummary) == 0 && len(existingMsgs) == 1 && payload.PromptMessageID == "" {
+		semantic, hit = u.semanticCacheLookup(ctx, u.userPrincipal(payload.UserID), semanticScopeUsers, 0, gpt4Payload)
 	}
-	gpt4Response, err := u.gpt4Webservice.Prompt(ctx, gpt4Payload)
-	if err != nil {
-		return core.UserPromGPTResponse{}, fmt.Errorf("error in GPT4 prompt: %v", err)
+	var gpt4Response gpt4_webservice.GPT4PromptResponseDao
+	if hit != nil {
+		gpt4Response = hit.response
+		res.Cached = true
+		res.Similarity = hit.score
+	} else {
+		gpt4Response, err = u.prompt(ctx, u.userPrincipal(payload.UserID), usageKindPrompt, gpt4Payload)
+		if err != nil {
+			return core.UserPromGPTResponse{}, fmt.Errorf("error in GPT4 prompt: %w", err)
+		}
+		// a rejected answer is still paid for
+		if err = u.checkOutput(ctx, u.userPrincipal(payload.UserID), u.cfg.Guardrails.Users, gpt4Response); err != nil {
+			if chargeErr := u.addUserTokenUsage(ctx, payload.UserID, token+gpt4Response.Usage.TotalTokens); chargeErr != nil {
+				return core.UserPromGPTResponse{}, chargeErr
+			}
+			return core.UserPromGPTResponse{}, err
+		}
+		u.semanticCacheStore(ctx, semantic, gpt4Response)
 	}
 	res.GPT4PromptResponse = core.ToCoreGPT4PromptResponse(gpt4Response)
 	res.UserID = payload.UserID
 
-	// Append assistant's response to existing messages
+	// Append assistant's response to existing messages, a copy as the response gets its placeholders restored
+	assistantText := res.GPT4PromptResponse.Choices[0].Message.Content
 	assistantResp := gpt4_webservice.MessageReq{
 		Content: []gpt4_webservice.Content{{
 			Type: "text",
-			Text: &res.GPT4PromptResponse.Choices[0].Message.Content,
+			Text: &assistantText,
 		}},
 		Role: "assistant",
 	}
@@ -134,10 +280,13 @@ func (u UserService) UserPromtGPT(ctx context.Context, payload core.UserPromtGPT
 
 	// Summarize conversation if message count exceeds threshold
 	if len(existingMsgs) >= 10 {
-		newSummary, err = u.userSummaryGPT(ctx, existingMsgs, &token)
+		tokenBeforeSummary := token
+		var summaryModel string
+		newSummary, summaryModel, err = u.userSummaryGPT(ctx, payload.UserID, existingMsgs, &token)
 		if err != nil {
 			return core.UserPromGPTResponse{}, fmt.Errorf("error in GPT4 summary: %v", err)
 		}
+		summaryTokens := token - tokenBeforeSummary
 
 		existingSummary = append(existingSummary, newSummary)
 		jsonData, err := json.Marshal(existingSummary)
@@ -145,10 +294,15 @@ func (u UserService) UserPromtGPT(ctx context.Context, payload core.UserPromtGPT
 			return core.UserPromGPTResponse{}, fmt.Errorf("error in GPT4 summary: %v", err)
 		}
 
-		err = u.cache.Set(ctx, summaryKey, jsonData, 0)
+		err = u.cache.Set(ctx, summaryKey, jsonData, u.contextTTL())
 		if err != nil {
 			return core.UserPromGPTResponse{}, err
 		}
+		u.publish(ctx, core.EventSummaryCreated, core.SummaryCreatedEvent{
+			UserID:      payload.UserID,
+			Model:       summaryModel,
+			TotalTokens: summaryTokens,
+		})
 
 		u.cache.Delete(ctx, contextKey)
 	} else {
@@ -157,11 +311,12 @@ func (u UserService) UserPromtGPT(ctx context.Context, payload core.UserPromtGPT
 			return core.UserPromGPTResponse{}, fmt.Errorf("error in GPT4 prompt: %v", err)
 		}
 
-		err = u.cache.Set(ctx, contextKey, jsonData, 0)
+		err = u.cache.Set(ctx, contextKey, jsonData, u.contextTTL())
 		if err != nil {
 			return core.UserPromGPTResponse{}, err
 		}
 	}
+	u.touchContextKeys(ctx, payload.UserID)
 
 	// add metadata token usage to span
 	apm.AddEvent(ctx, "TokenUsage",
@@ -169,35 +324,45 @@ func (u UserService) UserPromtGPT(ctx context.Context, payload core.UserPromtGPT
 		attribute.Int("token_usage", res.Usage.TotalTokens),
 	)
 
-	// Update token usage
-	token += res.Usage.TotalTokens
-	duration := time.Second * time.Duration(u.cfg.OpenAI.TokenLifetime)
-	if tokenExist {
-		duration = -1
-		err = u.cache.Set(ctx, fmt.Sprintf(redisKeyTokenUsage, payload.UserID), token, duration)
-	} else {
-		err = u.cache.Set(ctx, fmt.Sprintf(redisKeyTokenUsage, payload.UserID), token, duration)
+	// Update token usage, cached answers are free
+	if !res.Cached {
+		token += res.Usage.TotalTokens
 	}
-
-	if err != nil {
+	if err = u.addUserTokenUsage(ctx, payload.UserID, token); err != nil {
 		return core.UserPromGPTResponse{}, err
 	}
 
-	// upsert to mongo
-	mongoContent := core.ToContentRepo(payload.Content)
-	mongoMessage := []repository.Message{
-		{
-			Content: mongoContent,
-			Role:    userDefaultRole,
-		},
-		{
-			Role: assistantResp.Role,
-			Content: []repository.Content{{
-				Type: "text",
-				Text: assistantResp.Content[0].Text,
-			}},
-		},
+	// upsert to mongo, a prompt already in the conversation only gets the new answer
+	promptMessage := repository.Message{
+		ID:      primitive.NewObjectID(),
+		Content: core.ToContentRepo(payload.Content),
+		Role:    userDefaultRole,
+	}
+	if payload.PromptMessageID != "" {
+		promptMessage.ID, err = primitive.ObjectIDFromHex(payload.PromptMessageID)
+		if err != nil {
+			return core.UserPromGPTResponse{}, fmt.Errorf("%w: message %s", core.ErrInvalidRequest, payload.PromptMessageID)
+		}
 	}
+	answerMessage := repository.Message{
+		ID:       primitive.NewObjectID(),
+		ParentID: promptMessage.ID,
+		Role:     assistantResp.Role,
+		Content: []repository.Content{{
+			Type: "text",
+			Text: assistantResp.Content[0].Text,
+		}},
+		Model: res.GPT4PromptResponse.Model,
+	}
+	if persona != nil {
+		answerMessage.Persona = core.VersionedRef(persona.Name, persona.Version)
+	}
+	mongoMessage := []repository.Message{promptMessage, answerMessage}
+	if payload.PromptMessageID != "" {
+		mongoMessage = []repository.Message{answerMessage}
+	}
+	res.PromptMessageID = promptMessage.ID.Hex()
+	res.MessageID = answerMessage.ID.Hex()
 
 	// newSummary to repo summary if newSummary not nil or empty
 	mongoSummary := repository.Summary{}
@@ -210,7 +375,10 @@ func (u UserService) UserPromtGPT(ctx context.Context, payload core.UserPromtGPT
 			Role: newSummary.Role,
 		}
 	}
-	go u.upsertConversation(context.Background(), payload.UserID, mongoMessage, mongoSummary)
+	if err = u.upsertConversation(ctx, payload.UserID, current, mongoMessage, mongoSummary); err != nil {
+		return core.UserPromGPTResponse{}, err
+	}
+	go u.repo.TouchUser(context.Background(), payload.UserID, "")
 
 	return res, nil
 }
@@ -228,16 +396,145 @@ func (u UserService) ServicePrompt(ctx context.Context, payload core.ServiceProm
 	}()
 	defer apm.EndTransaction(span)
 
+	var templateRef string
+	var rendered []core.MessageRequest
+	if payload.Template != "" {
+		templateRef, rendered, err = u.renderPromptTemplate(ctx, payload.Template, payload.Variables)
+		if err != nil {
+			return core.ServicePromGPTResponse{}, err
+		}
+		payload.Messages = append(rendered, payload.Messages...)
+	}
+	if len(payload.Messages) == 0 {
+		return core.ServicePromGPTResponse{}, fmt.Errorf("%w: messages or template is required", core.ErrInvalidRequest)
+	}
+	payload.Messages, err = u.prepareMessages(ctx, payload.Messages)
+	if err != nil {
+		return core.ServicePromGPTResponse{}, err
+	}
+
+	// PII is replaced before anything leaves the network, placeholders are put back in the answer
+	service, _ := u.backendService(payload.ServiceName)
+	vault := redact.Vault{}
+	payload.Messages, res.Redactions = redactMessages(u.redactor(service.Redaction), payload.Messages, vault)
+	defer func() {
+		if err == nil {
+			restoreResponse(&res.GPT4PromptResponse, vault)
+		}
+	}()
+	if templateRef != "" {
+		ctx = withRenderedTemplate(ctx, templateRef, payload.Messages[:len(rendered)])
+	}
+
+	// the caller's messages are screened, the persona and retrieved context are trusted
+	err = u.checkInput(ctx, u.servicePrincipal(payload.ServiceName), service.Guardrails, core.ToWebServicePromtGPTMsgRequest(payload.Messages))
+	if err != nil {
+		return core.ServicePromGPTResponse{}, err
+	}
+	payload.Messages, res.Inspection, err = u.inspectInjection(ctx, u.servicePrincipal(payload.ServiceName), service.Injection, payload.Messages)
+	if err != nil {
+		return core.ServicePromGPTResponse{}, err
+	}
+
 	// Prepare OpenAI prompt request
 	gpt4Payload := gpt4_webservice.GPT4PromptRequestDao{
-		Message:     core.ToWebServicePromtGPTMsgRequest(payload.Messages),
-		Temperature: payload.Temperature,
-		MaxTokens:   payload.MaxTokens,
-		TopP:        payload.TopP,
+		Model:     payload.Model,
+		Message:   core.ToWebServicePromtGPTMsgRequest(payload.Messages),
+		MaxTokens: payload.MaxTokens,
+		TopP:      payload.TopP,
+	}
+	if payload.Temperature != nil {
+		gpt4Payload.Temperature = *payload.Temperature
 	}
-	gpt4Response, err := u.gpt4Webservice.Prompt(ctx, gpt4Payload)
+
+	// parameters left out by the caller come from the persona
+	persona, err := u.servicePersona(ctx, payload.ServiceName, payload.Persona)
 	if err != nil {
-		return core.ServicePromGPTResponse{}, fmt.Errorf("error in GPT4 prompt: %v", err)
+		return core.ServicePromGPTResponse{}, err
+	}
+	gpt4Payload.Model = serviceModel(payload.Model, persona)
+	if persona != nil {
+		if payload.Temperature == nil && persona.Temperature != nil {
+			gpt4Payload.Temperature = *persona.Temperature
+		}
+		if gpt4Payload.MaxTokens == 0 && persona.MaxTokens != nil {
+			gpt4Payload.MaxTokens = *persona.MaxTokens
+		}
+		if gpt4Payload.TopP == 0 && persona.TopP != nil {
+			gpt4Payload.TopP = *persona.TopP
+		}
+		gpt4Payload.Message = append([]gpt4_webservice.MessageReq{personaMessage(*persona)}, gpt4Payload.Message...)
+	}
+	if gpt4Payload.Model == "" {
+		return core.ServicePromGPTResponse{}, fmt.Errorf("%w: model is required", core.ErrInvalidRequest)
+	}
+	if gpt4Payload.MaxTokens == 0 {
+		gpt4Payload.MaxTokens = userDefaultMaxTokens
+	}
+	if gpt4Payload.TopP == 0 {
+		gpt4Payload.TopP = userDefaultTop
+	}
+
+	err = u.validateModel(ctx, payload.Role, gpt4Payload.Model)
+	if err != nil {
+		return core.ServicePromGPTResponse{}, err
+	}
+
+	// answers are checked for leaks of the system prompts, retrieved context may be quoted
+	system := systemPrompts(gpt4Payload.Message)
+
+	// retrieved context becomes part of the prompt, and so of the cache keys
+	if len(payload.KnowledgeBases) > 0 && len(gpt4Payload.Message) > 0 {
+		if err = u.validateServiceTokenUsage(ctx, payload.ServiceName); err != nil {
+			return core.ServicePromGPTResponse{}, err
+		}
+		last := gpt4Payload.Message[len(gpt4Payload.Message)-1]
+		contextMsg, citations, ragTokens, err := u.retrieveContext(ctx, u.servicePrincipal(payload.ServiceName), payload.ServiceName, payload.KnowledgeBases, messageText(last.Content), vault)
+		if err != nil {
+			return core.ServicePromGPTResponse{}, err
+		}
+		if err = u.addServiceTokenUsage(ctx, payload.ServiceName, ragTokens); err != nil {
+			return core.ServicePromGPTResponse{}, err
+		}
+		if contextMsg != nil {
+			gpt4Payload.Message = withContext(gpt4Payload.Message, *contextMsg)
+			res.Citations = citations
+		}
+	}
+
+	// cache hits are served without calling upstream and don't count against the quota
+	cacheKey, cacheable := u.responseCacheKey(payload.ServiceName, gpt4Payload)
+	if cacheable && !payload.NoCache {
+		if cached, found := u.getCachedResponse(ctx, cacheKey); found {
+			apm.AddEvent(ctx, "ResponseCacheHit",
+				attribute.String("user_id", payload.ServiceName),
+			)
+			cached.Redactions = res.Redactions
+			return cached, nil
+		}
+	}
+
+	var semantic *semanticQuery
+	if service.SemanticCache && !payload.NoCache {
+		var hit *semanticHit
+		semantic, hit = u.semanticCacheLookup(ctx, u.servicePrincipal(payload.ServiceName), "service-"+payload.ServiceName, service.SemanticCacheThreshold, gpt4Payload)
+		if hit != nil {
+			res.GPT4PromptResponse = core.ToCoreGPT4PromptResponse(hit.response)
+			res.UserID = payload.ServiceName
+			res.Cached = true
+			res.Similarity = hit.score
+			return res, nil
+		}
+	}
+
+	// Validate service token usage
+	err = u.validateServiceTokenUsage(ctx, payload.ServiceName)
+	if err != nil {
+		return core.ServicePromGPTResponse{}, err
+	}
+	gpt4Response, err := u.prompt(ctx, u.servicePrincipal(payload.ServiceName), usageKindService, gpt4Payload)
+	if err != nil {
+		return core.ServicePromGPTResponse{}, fmt.Errorf("error in GPT4 prompt: %w", err)
 	}
 	res.GPT4PromptResponse = core.ToCoreGPT4PromptResponse(gpt4Response)
 	res.UserID = payload.ServiceName
@@ -248,6 +545,42 @@ func (u UserService) ServicePrompt(ctx context.Context, payload core.ServiceProm
 		attribute.Int("token_usage", res.Usage.TotalTokens),
 	)
 
+	// Update service token usage, a rejected answer is still paid for
+	err = u.addServiceTokenUsage(ctx, payload.ServiceName, res.Usage.TotalTokens)
+	if err != nil {
+		return core.ServicePromGPTResponse{}, err
+	}
+	err = u.checkOutput(ctx, u.servicePrincipal(payload.ServiceName), service.Guardrails, gpt4Response)
+	if err != nil {
+		return core.ServicePromGPTResponse{}, err
+	}
+	leaks, stripped, err := u.inspectLeaks(ctx, u.servicePrincipal(payload.ServiceName), service.Injection, system, &gpt4Response)
+	if err != nil {
+		return core.ServicePromGPTResponse{}, err
+	}
+	if len(leaks) > 0 {
+		if res.Inspection == nil {
+			res.Inspection = &core.PromptInspection{}
+		}
+		res.Inspection.Leaks = leaks
+		res.Inspection.LeaksStripped = stripped
+		res.GPT4PromptResponse = core.ToCoreGPT4PromptResponse(gpt4Response)
+	}
+
+	// the exchange is kept so the answer can be rated, cache hits point to the stored answer
+	if !payload.NoStore {
+		exchange := serviceExchange(payload.ServiceName, payload.Messages, res.GPT4PromptResponse, persona, templateRef)
+		res.MessageID = exchange[len(exchange)-1].ID.Hex()
+		go u.repo.InsertMessages(context.Background(), exchange)
+	}
+
+	if cacheable && !payload.NoStore {
+		u.setCachedResponse(ctx, payload.ServiceName, cacheKey, res)
+	}
+	if !payload.NoStore {
+		u.semanticCacheStore(ctx, semantic, gpt4Response)
+	}
+
 	return res, nil
 }
 
@@ -262,21 +595,23 @@ func (u UserService) GetUserTokenUsage(ctx context.Context, userID string) core.
 		tokenCount = 0
 	}
 
+	tokenLimit := u.tokenLimit(ctx, userID)
 	warn := false
-	if tokenCount > u.cfg.OpenAI.TokenLimit/2 {
+	if tokenCount > tokenLimit/2 {
 		warn = true
 	}
 
 	return core.UserTokenUsage{
-		TokenLimit: u.cfg.OpenAI.TokenLimit,
+		TokenLimit: tokenLimit,
 		TokenUsage: tokenCount,
 		Warning:    warn,
 	}
 }
 
-// userSummaryGPT generates a summary of the conversation
-func (u UserService) userSummaryGPT(ctx context.Context, payload []gpt4_webservice.MessageReq, token *int) (res gpt4_webservice.MessageReq, err error) {
-	text := fmt.Sprintf("%s conversation: %s", systemBrief, formatConversation(payload))
+// userSummaryGPT generates a summary of the conversation and returns the model that wrote it
+func (u UserService) userSummaryGPT(ctx context.Context, userID string, payload []gpt4_webservice.MessageReq, token *int) (res gpt4_webservice.MessageReq, model string, err error) {
+	conversation, images := formatConversation(payload)
+	text := fmt.Sprintf("%s conversation: %s", systemBrief, conversation)
 	msg := []gpt4_webservice.MessageReq{{
 		Content: []gpt4_webservice.Content{{
 			Type: "text",
@@ -285,14 +620,24 @@ func (u UserService) userSummaryGPT(ctx context.Context, payload []gpt4_webservi
 		Role: "system",
 	}}
 	gpt4Payload := gpt4_webservice.GPT4PromptRequestDao{
+		Model:       userDefaultModel,
 		Message:     msg,
 		Temperature: summaryDefaultTemperature,
 		MaxTokens:   summaryDefaultMaxTokens,
 		TopP:        summaryDefaultTop,
 	}
-	gpt4Response, err := u.gpt4Webservice.Prompt(ctx, gpt4Payload)
+
+	// the summarizer looks at the images so what they show survives in the summary
+	if u.cfg.Summary.DescribeImages && len(images) > 0 {
+		gpt4Payload.Message = append(gpt4Payload.Message, summaryImages(images, u.cfg.Summary.MaxImages))
+		if u.cfg.Summary.VisionModel != "" {
+			gpt4Payload.Model = u.cfg.Summary.VisionModel
+		}
+	}
+
+	gpt4Response, err := u.prompt(ctx, u.userPrincipal(userID), usageKindSummary, gpt4Payload)
 	if err != nil {
-		return gpt4_webservice.MessageReq{}, err
+		return gpt4_webservice.MessageReq{}, "", err
 	}
 
 	*token += gpt4Response.Usage.TotalTokens
@@ -303,20 +648,50 @@ func (u UserService) userSummaryGPT(ctx context.Context, payload []gpt4_webservi
 			Text: &gpt4Response.Choices[0].Message.Content,
 		}},
 		Role: "system",
-	}, nil
+	}, gpt4Payload.Model, nil
 }
 
-// formatConversation formats the conversation for summarization
-func formatConversation(conversation []gpt4_webservice.MessageReq) string {
-	var formattedText string
+// formatConversation formats the conversation for summarization.
+// Every text part is kept, images are replaced by numbered [image N] markers and returned in the same order.
+func formatConversation(conversation []gpt4_webservice.MessageReq) (string, []gpt4_webservice.ImageURL) {
+	var formattedText strings.Builder
+	images := []gpt4_webservice.ImageURL{}
 
 	for _, entry := range conversation {
-		role := entry.Role
-		text := entry.Content[0].Text
-		formattedText += role + ": " + *text + "\n"
+		parts := []string{}
+		for _, content := range entry.Content {
+			switch {
+			case content.Text != nil:
+				parts = append(parts, *content.Text)
+			case content.ImageURL != nil:
+				images = append(images, *content.ImageURL)
+				parts = append(parts, fmt.Sprintf("[image %d]", len(images)))
+			}
+		}
+		formattedText.WriteString(entry.Role + ": " + strings.Join(parts, " ") + "\n")
 	}
 
-	return formattedText
+	return formattedText.String(), images
+}
+
+// summaryImages returns a message showing the summarizer the latest images, each labelled with its marker
+func summaryImages(images []gpt4_webservice.ImageURL, maxImages int) gpt4_webservice.MessageReq {
+	first := 0
+	if maxImages > 0 && len(images) > maxImages {
+		first = len(images) - maxImages
+	}
+
+	intro := "Describe what these images of the conversation show where their markers appear."
+	content := []gpt4_webservice.Content{{Type: "text", Text: &intro}}
+	for i := first; i < len(images); i++ {
+		label := fmt.Sprintf("[image %d]", i+1)
+		content = append(content,
+			gpt4_webservice.Content{Type: "text", Text: &label},
+			// low detail is enough to describe an image and keeps the summary cheap
+			gpt4_webservice.Content{Type: "image_url", ImageURL: &gpt4_webservice.ImageURL{URL: images[i].URL, Detail: "low"}},
+		)
+	}
+	return gpt4_webservice.MessageReq{Content: content, Role: userDefaultRole}
 }
 
 // validateTokenUsage checks if the user has exceeded their token usage limit
@@ -333,7 +708,7 @@ func (u UserService) validateTokenUsage(ctx context.Context, userID string) (tok
 		return tokenCount, true, nil, exist
 	}
 
-	if tokenCount > u.cfg.OpenAI.TokenLimit {
+	if tokenCount > u.tokenLimit(ctx, userID) {
 		expiredData, _ := u.cache.TTL(ctx, redisKey)
 		u.cache.Delete(ctx, fmt.Sprintf(redisKeyContext, userID))
 		return tokenCount, false, &expiredData, exist
@@ -341,8 +716,8 @@ func (u UserService) validateTokenUsage(ctx context.Context, userID string) (tok
 	return tokenCount, true, nil, exist
 }
 
-func (u UserService) upsertConversation(ctx context.Context, userID string, messages []repository.Message, summary repository.Summary) error {
-	err := u.repo.UpsertConversation(ctx, userID, messages, &summary)
+func (u UserService) upsertConversation(ctx context.Context, userID string, conversation repository.Conversation, messages []repository.Message, summary repository.Summary) error {
+	err := u.repo.UpsertConversation(ctx, userID, conversation.ID, conversation.ActiveLeaf, messages, &summary)
 	if err != nil {
 		fmt.Println("Error upserting conversation:", err)
 		return err
@@ -352,11 +727,121 @@ func (u UserService) upsertConversation(ctx context.Context, userID string, mess
 
 // UserClearContext
 func (u UserService) UserClearContext(ctx context.Context, userID string) error {
-	contextKey := fmt.Sprintf(redisKeyContext, userID)
-	summaryKey := fmt.Sprintf(redisKeySummary, userID)
+	u.clearContextKeys(ctx, userID)
+
+	// the next prompt opens a new conversation instead of growing the branch tree of the old one
+	if err := u.repo.StartConversation(ctx, userID); err != nil {
+		return err
+	}
+
+	u.publish(ctx, core.EventContextCleared, core.ContextClearedEvent{UserID: userID})
+
+	return nil
+}
+
+// clearContextKeys removes the cached state of a user's conversation
+func (u UserService) clearContextKeys(ctx context.Context, userID string) {
+	u.cache.Delete(ctx, fmt.Sprintf(redisKeyContext, userID))
+	u.cache.Delete(ctx, fmt.Sprintf(redisKeySummary, userID))
+	u.cache.Delete(ctx, fmt.Sprintf(redisKeyPersona, userID))
+	u.cache.Delete(ctx, fmt.Sprintf(redisKeySettings, userID))
+	u.cache.Delete(ctx, fmt.Sprintf(redisKeyRedactionVault, userID))
+}
+
+// validateUserQuota checks if the user has exceeded their token limit
+func (u UserService) validateUserQuota(ctx context.Context, userID string) error {
+	token, valid, expiredDuration, _ := u.validateTokenUsage(ctx, userID)
+	if valid {
+		return nil
+	}
+
+	u.publish(ctx, core.EventQuotaExceeded, core.QuotaExceededEvent{
+		Principal:     userID,
+		PrincipalType: principalTypeUser,
+		TokenUsage:    token,
+		TokenLimit:    u.tokenLimit(ctx, userID),
+	})
+	return fmt.Errorf("%w: %d token, Your limit resets after %s", core.ErrQuotaExceeded, token, expiredDuration)
+}
+
+// addUserTokenUsage adds tokens to the user usage window
+func (u UserService) addUserTokenUsage(ctx context.Context, userID string, tokens int) error {
+	redisKey := fmt.Sprintf(redisKeyTokenUsage, userID)
+	tokenData, success := u.cache.Get(ctx, redisKey)
+	if !success {
+		return u.cache.Set(ctx, redisKey, tokens, time.Second*time.Duration(u.cfg.OpenAI.TokenLifetime))
+	}
+
+	tokenCount, _ := strconv.Atoi(tokenData.(string))
+	return u.cache.Set(ctx, redisKey, tokenCount+tokens, -1)
+}
 
-	u.cache.Delete(ctx, contextKey)
-	u.cache.Delete(ctx, summaryKey)
+// backendService returns the configured backend service with the given name
+func (u UserService) backendService(name string) (config.BackendService, bool) {
+	for _, service := range u.cfg.Services {
+		if service.Name == name {
+			return service, true
+		}
+	}
+	return config.BackendService{}, false
+}
+
+// validateServiceTokenUsage checks if the service has exceeded its token limit, services without a limit are not tracked
+func (u UserService) validateServiceTokenUsage(ctx context.Context, serviceName string) error {
+	service, _ := u.backendService(serviceName)
+	if service.TokenLimit <= 0 {
+		return nil
+	}
+
+	redisKey := fmt.Sprintf(redisKeyServiceTokenUsage, serviceName)
+	tokenData, success := u.cache.Get(ctx, redisKey)
+	if !success {
+		return nil
+	}
 
+	tokenCount, _ := strconv.Atoi(tokenData.(string))
+	if tokenCount > service.TokenLimit {
+		u.publish(ctx, core.EventQuotaExceeded, core.QuotaExceededEvent{
+			Principal:     serviceName,
+			PrincipalType: principalTypeService,
+			TokenUsage:    tokenCount,
+			TokenLimit:    service.TokenLimit,
+		})
+		expiredData, _ := u.cache.TTL(ctx, redisKey)
+		return fmt.Errorf("%w: %d token, Your limit resets after %s", core.ErrQuotaExceeded, tokenCount, expiredData)
+	}
 	return nil
 }
+
+// addServiceTokenUsage adds tokens to the service usage window
+func (u UserService) addServiceTokenUsage(ctx context.Context, serviceName string, tokens int) error {
+	service, _ := u.backendService(serviceName)
+	if service.TokenLimit <= 0 {
+		return nil
+	}
+
+	redisKey := fmt.Sprintf(redisKeyServiceTokenUsage, serviceName)
+	tokenData, success := u.cache.Get(ctx, redisKey)
+	if !success {
+		return u.cache.Set(ctx, redisKey, tokens, time.Second*time.Duration(u.cfg.OpenAI.TokenLifetime))
+	}
+
+	tokenCount, _ := strconv.Atoi(tokenData.(string))
+	return u.cache.Set(ctx, redisKey, tokenCount+tokens, -1)
+}
+
+// validateQuota checks the quota of a user or service principal
+func (u UserService) validateQuota(ctx context.Context, p principal) error {
+	if p.Type == principalTypeService {
+		return u.validateServiceTokenUsage(ctx, p.Name)
+	}
+	return u.validateUserQuota(ctx, p.Name)
+}
+
+// addTokenUsage adds tokens to the usage window of a user or service principal
+func (u UserService) addTokenUsage(ctx context.Context, p principal, tokens int) error {
+	if p.Type == principalTypeService {
+		return u.addServiceTokenUsage(ctx, p.Name, tokens)
+	}
+	return u.addUserTokenUsage(ctx, p.Name, tokens)
+}
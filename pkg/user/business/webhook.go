@@ -0,0 +1,314 @@
+package business
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/correlation"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/business/core"
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/repository"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+)
+
+const (
+	servicePromptTimeout = 1 * time.Minute
+	webhookPollInterval  = 5 * time.Second // how often due retries are picked up
+)
+
+// ServicePromptAsync accepts a service prompt and POSTs the result to callbackURL once the upstream answers.
+// Model, quota and callback checks run before the prompt is accepted.
+func (u UserService) ServicePromptAsync(ctx context.Context, payload core.ServicePromptRequest, callbackURL string) (core.WebhookDelivery, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ServicePromptAsync")
+	defer apm.EndTransaction(span)
+
+	if err := u.validateCallbackURL(payload.ServiceName, callbackURL); err != nil {
+		return core.WebhookDelivery{}, err
+	}
+	// the model may come from the persona, as it does for the synchronous endpoint
+	persona, err := u.servicePersona(ctx, payload.ServiceName, payload.Persona)
+	if err != nil {
+		return core.WebhookDelivery{}, err
+	}
+	model := serviceModel(payload.Model, persona)
+	if model == "" {
+		return core.WebhookDelivery{}, fmt.Errorf("%w: model is required", core.ErrInvalidRequest)
+	}
+	if err := u.validateModel(ctx, payload.Role, model); err != nil {
+		return core.WebhookDelivery{}, err
+	}
+	if err := u.validateServiceTokenUsage(ctx, payload.ServiceName); err != nil {
+		return core.WebhookDelivery{}, err
+	}
+
+	delivery, err := u.repo.InsertWebhookDelivery(ctx, repository.WebhookDelivery{
+		Owner:         payload.ServiceName,
+		Event:         core.WebhookEventServicePrompt,
+		CallbackURL:   callbackURL,
+		CorrelationID: correlation.FromContext(ctx),
+		Status:        core.WebhookStatusPending,
+	})
+	if err != nil {
+		return core.WebhookDelivery{}, err
+	}
+
+	go func() {
+		// the request context ends with the 202 response
+		promptCtx, cancel := context.WithTimeout(correlation.WithID(context.Background(), delivery.CorrelationID), servicePromptTimeout)
+		defer cancel()
+
+		callback := core.ServicePromptCallback{
+			DeliveryID:    delivery.ID.Hex(),
+			CorrelationID: delivery.CorrelationID,
+			Status:        "completed",
+		}
+		res, err := u.ServicePrompt(promptCtx, payload)
+		if err != nil {
+			callback.Status = "failed"
+			callback.Error = &core.CallbackError{Code: promptErrorCode(err), Message: err.Error()}
+		} else {
+			callback.Result = &res
+		}
+
+		u.deliverWebhook(delivery, callback)
+	}()
+
+	return core.ToCoreWebhookDelivery(delivery), nil
+}
+
+// GetWebhookDelivery returns a webhook delivery owned by the caller
+func (u UserService) GetWebhookDelivery(ctx context.Context, owner string, id string) (core.WebhookDelivery, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::GetWebhookDelivery")
+	defer apm.EndTransaction(span)
+
+	delivery, err := u.repo.GetWebhookDelivery(ctx, id)
+	if err != nil || delivery.Owner != owner {
+		return core.WebhookDelivery{}, fmt.Errorf("%w: webhook delivery %s", core.ErrNotFound, id)
+	}
+	return core.ToCoreWebhookDelivery(delivery), nil
+}
+
+// ListWebhookDeliveries returns the latest webhook deliveries of the caller
+func (u UserService) ListWebhookDeliveries(ctx context.Context, owner string, limit int) ([]core.WebhookDelivery, error) {
+	ctx, span := apm.StartTransaction(ctx, "Service::ListWebhookDeliveries")
+	defer apm.EndTransaction(span)
+
+	deliveries, err := u.repo.ListWebhookDeliveries(ctx, owner, limit)
+	if err != nil {
+		return nil, err
+	}
+	return core.ToCoreWebhookDeliveries(deliveries), nil
+}
+
+// notifyBatch delivers the final state of a batch to its callback_url, if any
+func (u UserService) notifyBatch(batch repository.Batch) {
+	if batch.CallbackURL == "" {
+		return
+	}
+
+	delivery, err := u.repo.InsertWebhookDelivery(context.Background(), repository.WebhookDelivery{
+		Owner:         batch.Owner,
+		Event:         core.WebhookEventBatch,
+		CallbackURL:   batch.CallbackURL,
+		CorrelationID: batch.ID.Hex(),
+		Status:        core.WebhookStatusPending,
+	})
+	if err != nil {
+		fmt.Println("Error creating webhook delivery:", err)
+		return
+	}
+
+	u.deliverWebhook(delivery, core.BatchCallback{
+		DeliveryID:    delivery.ID.Hex(),
+		CorrelationID: delivery.CorrelationID,
+		Batch:         core.ToCoreBatch(batch),
+	})
+}
+
+// validateCallbackURL checks that callbackURL is an http(s) URL on one of the service's callback hosts
+func (u UserService) validateCallbackURL(serviceName string, callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(callbackURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Hostname() == "" {
+		return fmt.Errorf("%w: invalid callback_url", core.ErrInvalidRequest)
+	}
+
+	service, _ := u.backendService(serviceName)
+	// an unsigned callback could be forged by anyone who knows the URL
+	if service.CallbackSecret == "" {
+		return fmt.Errorf("%w: service %s has no callback secret to sign callbacks with", core.ErrCallbackNotAllowed, serviceName)
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if hostAllowed(host, service.CallbackHosts) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", core.ErrCallbackNotAllowed, host)
+}
+
+// hostAllowed reports whether host is one of the allowed hosts, a `*.` prefix matches subdomains
+func hostAllowed(host string, allowedHosts []string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed {
+			return true
+		}
+		if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverWebhook stores the body of a delivery and makes its first attempt. Retries are scheduled on the
+// delivery and made by RunWebhookRetries, so they survive a restart.
+func (u UserService) deliverWebhook(delivery repository.WebhookDelivery, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		fmt.Println("Error marshalling webhook body:", err)
+		return
+	}
+	delivery.Payload = string(data)
+	delivery.Status = core.WebhookStatusDelivering
+	u.attemptWebhook(delivery)
+}
+
+// RunWebhookRetries makes the webhook attempts that are due until ctx is done. Deliveries whose prompt
+// was lost with its instance get a failed callback.
+func (u UserService) RunWebhookRetries(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			delivery, err := u.repo.ClaimStalePendingWebhookDelivery(ctx, time.Now().Add(-2*servicePromptTimeout))
+			if err != nil {
+				break
+			}
+			u.deliverWebhook(delivery, core.ServicePromptCallback{
+				DeliveryID:    delivery.ID.Hex(),
+				CorrelationID: delivery.CorrelationID,
+				Status:        "failed",
+				Error:         &core.CallbackError{Code: "interrupted", Message: "the prompt was interrupted before it was answered, send it again"},
+			})
+		}
+		for {
+			delivery, err := u.repo.ClaimDueWebhookDelivery(ctx, u.webhookLease())
+			if err != nil {
+				break
+			}
+			u.attemptWebhook(delivery)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// attemptWebhook POSTs the signed payload of a delivery once, records the attempt and schedules the next
+// one with exponential backoff when it may be retried
+func (u UserService) attemptWebhook(delivery repository.WebhookDelivery) {
+	service, _ := u.backendService(delivery.Owner)
+	data := []byte(delivery.Payload)
+
+	// re-sign every attempt so the timestamp stays fresh
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	headers := map[string]string{
+		core.WebhookHeaderSignature:     signWebhook(service.CallbackSecret, timestamp, data),
+		core.WebhookHeaderTimestamp:     timestamp,
+		core.WebhookHeaderDeliveryID:    delivery.ID.Hex(),
+		core.WebhookHeaderEvent:         delivery.Event,
+		correlation.HeaderCorrelationID: delivery.CorrelationID,
+	}
+
+	var (
+		statusCode int
+		err        error
+	)
+	start := time.Now()
+	if service.CallbackSecret == "" {
+		// the secret was removed after the callback was accepted
+		err = errors.New("service has no callback secret, the callback can't be signed")
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(u.cfg.Webhook.Timeout)*time.Second)
+		statusCode, err = u.webhookSender.Send(ctx, delivery.CallbackURL, headers, data)
+		cancel()
+	}
+
+	record := repository.WebhookAttempt{At: start, StatusCode: statusCode, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	delivery.Attempts = append(delivery.Attempts, record)
+
+	retry := service.CallbackSecret != "" && (err != nil || statusCode >= http.StatusInternalServerError ||
+		statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests)
+	delivery.NextAttemptAt = nil
+	switch {
+	case err == nil && statusCode >= 200 && statusCode < 300:
+		delivery.Status = core.WebhookStatusSucceeded
+	case !retry || len(delivery.Attempts) >= u.cfg.Webhook.MaxAttempts:
+		delivery.Status = core.WebhookStatusFailed
+	default:
+		next := time.Now().Add(u.webhookBackoff(len(delivery.Attempts)))
+		delivery.NextAttemptAt = &next
+	}
+	if err := u.repo.UpdateWebhookDelivery(context.Background(), delivery); err != nil {
+		fmt.Println("Error updating webhook delivery:", err)
+	}
+}
+
+// webhookBackoff is the wait after the given number of failed attempts, doubled after each of them
+func (u UserService) webhookBackoff(attempts int) time.Duration {
+	backoff := time.Duration(u.cfg.Webhook.InitialBackoff) * time.Second
+	maxBackoff := time.Duration(u.cfg.Webhook.MaxBackoff) * time.Second
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// webhookLease is how long a claimed retry is hidden from other instances, longer than an attempt can take
+func (u UserService) webhookLease() time.Duration {
+	return 2*time.Duration(u.cfg.Webhook.Timeout)*time.Second + webhookPollInterval
+}
+
+// signWebhook returns the X-Signature-256 value, an HMAC-SHA256 over "<timestamp>.<body>"
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// promptErrorCode maps a service prompt error to the code reported in callbacks and batch error files
+func promptErrorCode(err error) string {
+	switch {
+	case errors.Is(err, core.ErrQuotaExceeded):
+		return "quota_exceeded"
+	case errors.Is(err, core.ErrModelNotAllowed):
+		return "model_not_allowed"
+	case errors.Is(err, core.ErrContentFiltered):
+		return "content_filtered"
+	default:
+		return "upstream_error"
+	}
+}
@@ -0,0 +1,27 @@
+package embedding_webservice
+
+// EmbeddingRequestDao is the request to the embeddings upstream
+type EmbeddingRequestDao struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions *int     `json:"dimensions,omitempty"`
+}
+
+// EmbeddingResponseDao is the response from the embeddings upstream
+type EmbeddingResponseDao struct {
+	Object string      `json:"object"`
+	Data   []Embedding `json:"data"`
+	Model  string      `json:"model"`
+	Usage  Usage       `json:"usage"`
+}
+
+type Embedding struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+type Usage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
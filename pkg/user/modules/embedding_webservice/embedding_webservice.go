@@ -0,0 +1,67 @@
+package embedding_webservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type EmbeddingWebService struct {
+	client *http.Client
+	url    string
+	apiKey string
+}
+
+func NewEmbeddingWebService(url, apiKey string) EmbeddingWebService {
+	client := &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 10,
+		},
+	}
+
+	return EmbeddingWebService{
+		client: client,
+		url:    url,
+		apiKey: apiKey,
+	}
+}
+
+func (ws EmbeddingWebService) Embed(ctx context.Context, payload EmbeddingRequestDao) (result EmbeddingResponseDao, err error) {
+	jsonBody, _ := json.Marshal(payload)
+	reqBody := bytes.NewBuffer(jsonBody)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, ws.url, reqBody)
+	if err != nil {
+		return result, err
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("Api-Key", ws.apiKey)
+
+	response, err := ws.client.Do(request)
+	if err != nil {
+		return result, err
+	}
+	defer response.Body.Close()
+
+	// validate response status code
+	if response.StatusCode != http.StatusOK {
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(response.Body)
+		fmt.Println(buf.String())
+		if response.StatusCode == 429 {
+			return result, fmt.Errorf("Requests to the Embeddings_Create Operation have exceeded the rate limit of the upstream. Please retry later.")
+		}
+		return result, fmt.Errorf("unexpected status code: %d", response.StatusCode)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
@@ -7,6 +7,8 @@ type GPT4PromptRequestDao struct {
 	Temperature float64      `json:"temperature"`
 	MaxTokens   int          `json:"max_tokens"`
 	TopP        float64      `json:"top_p"`
+	Stop        []string     `json:"stop,omitempty"`
+	Seed        *int         `json:"seed,omitempty"`
 }
 
 // GPT4PromptResponse is the response from GPT4 prompt
@@ -31,12 +33,24 @@ type Content struct {
 }
 
 type ImageURL struct {
-	URL string `json:"url"`
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"` // auto, low or high
 }
 
 type Choices struct {
-	Index   int     `json:"index"`
-	Message Message `json:"message"`
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason,omitempty"`
+	// ContentFilterResults is reported by Azure OpenAI per filter category, e.g. hate or violence
+	ContentFilterResults map[string]ContentFilterResult `json:"content_filter_results,omitempty"`
+}
+
+// FinishReasonContentFilter is the finish reason of an answer cut off by the upstream's content filter
+const FinishReasonContentFilter = "content_filter"
+
+type ContentFilterResult struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity,omitempty"`
 }
 
 type Message struct {
@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
 
+// ErrContentFilter is returned when the upstream's content filter rejects the prompt
+var ErrContentFilter = errors.New("prompt rejected by the upstream content filter")
+
 type GPT4WebService struct {
 	client *http.Client
 	url    string
@@ -58,6 +62,17 @@ func (ws GPT4WebService) Prompt(ctx context.Context, payload GPT4PromptRequestDa
 		if response.StatusCode == 429 {
 			return result, fmt.Errorf("Requests to the ChatCompletions_Create Operation under Azure OpenAI API have exceeded token rate limit of your current OpenAI S0 pricing tier. Please retry later.")
 		}
+		// azure answers {"error":{"code":"content_filter",...}} when the prompt trips its filter
+		if response.StatusCode == http.StatusBadRequest {
+			var body struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			if json.Unmarshal(buf.Bytes(), &body) == nil && body.Error.Code == FinishReasonContentFilter {
+				return result, ErrContentFilter
+			}
+		}
 		return result, fmt.Errorf("unexpected status code: %d", response.StatusCode)
 	}
 
@@ -0,0 +1,102 @@
+package image_fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrAddressNotAllowed is returned when a host resolves to an address that is not on the public internet
+var ErrAddressNotAllowed = errors.New("address is not allowed")
+
+// reservedNetworks are the non-public IPv4 ranges net.IP does not classify: "this network" and carrier-grade NAT
+var reservedNetworks = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+}
+
+type ImageFetcher struct {
+	client *http.Client
+}
+
+func NewImageFetcher(timeout time.Duration) ImageFetcher {
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// the address is checked after DNS resolution, so a public name pointing inside the network is refused too
+			DialContext: (&net.Dialer{
+				Timeout: timeout,
+				Control: publicOnly,
+			}).DialContext,
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 2,
+		},
+		// the URL was checked against the allowed hosts, redirects could lead anywhere
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return ImageFetcher{client: client}
+}
+
+// Fetch downloads url, failing when the body is larger than maxBytes
+func (f ImageFetcher) Fetch(ctx context.Context, url string, maxBytes int64) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add("Accept", "image/*")
+
+	response, err := f.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", response.StatusCode)
+	}
+	if response.ContentLength > maxBytes {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxBytes)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxBytes)
+	}
+	return data, nil
+}
+
+// publicOnly refuses to connect to loopback, private, link-local and other non-public addresses
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !publicIP(ip) {
+		return ErrAddressNotAllowed
+	}
+	return nil
+}
+
+func publicIP(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
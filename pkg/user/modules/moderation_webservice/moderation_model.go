@@ -0,0 +1,20 @@
+package moderation_webservice
+
+// ModerationRequestDao is the request to the moderation upstream
+type ModerationRequestDao struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+// ModerationResponseDao is the response from the moderation upstream, with one result per input
+type ModerationResponseDao struct {
+	ID      string             `json:"id"`
+	Model   string             `json:"model"`
+	Results []ModerationResult `json:"results"`
+}
+
+type ModerationResult struct {
+	Flagged        bool               `json:"flagged"`
+	Categories     map[string]bool    `json:"categories"`
+	CategoryScores map[string]float64 `json:"category_scores"`
+}
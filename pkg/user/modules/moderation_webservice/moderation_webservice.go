@@ -0,0 +1,64 @@
+package moderation_webservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type ModerationWebService struct {
+	client *http.Client
+	url    string
+	apiKey string
+}
+
+func NewModerationWebService(url, apiKey string) ModerationWebService {
+	client := &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 10,
+		},
+	}
+
+	return ModerationWebService{
+		client: client,
+		url:    url,
+		apiKey: apiKey,
+	}
+}
+
+func (ws ModerationWebService) Moderate(ctx context.Context, payload ModerationRequestDao) (result ModerationResponseDao, err error) {
+	jsonBody, _ := json.Marshal(payload)
+	reqBody := bytes.NewBuffer(jsonBody)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, ws.url, reqBody)
+	if err != nil {
+		return result, err
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("Api-Key", ws.apiKey)
+
+	response, err := ws.client.Do(request)
+	if err != nil {
+		return result, err
+	}
+	defer response.Body.Close()
+
+	// validate response status code
+	if response.StatusCode != http.StatusOK {
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(response.Body)
+		fmt.Println(buf.String())
+		return result, fmt.Errorf("unexpected status code: %d", response.StatusCode)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
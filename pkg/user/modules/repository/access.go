@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetServiceKey finds the key set for a service, returns mongo.ErrNoDocuments if it still uses the configured one
+func (r *MongoDBRepository) GetServiceKey(ctx context.Context, service string) (ServiceKey, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetServiceKey")
+	defer apm.EndTransaction(span)
+
+	var key ServiceKey
+	err := r.db.Collection("service_keys").FindOne(ctx, bson.M{"service": service}).Decode(&key)
+	return key, err
+}
+
+// UpsertServiceKey replaces the key of a service
+func (r *MongoDBRepository) UpsertServiceKey(ctx context.Context, key ServiceKey) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::UpsertServiceKey")
+	defer apm.EndTransaction(span)
+
+	update := bson.M{"$set": bson.M{
+		"password_hash": key.PasswordHash,
+		"created_by":    key.CreatedBy,
+		"created_at":    time.Now(),
+	}}
+	_, err := r.db.Collection("service_keys").UpdateOne(ctx, bson.M{"service": key.Service}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// DeleteServiceKey removes the key set for a service, it falls back to the configured one
+func (r *MongoDBRepository) DeleteServiceKey(ctx context.Context, service string) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::DeleteServiceKey")
+	defer apm.EndTransaction(span)
+
+	_, err := r.db.Collection("service_keys").DeleteOne(ctx, bson.M{"service": service})
+	return err
+}
+
+// GetRoleModels finds the model allow-list set for a role, returns mongo.ErrNoDocuments if it uses the configured one
+func (r *MongoDBRepository) GetRoleModels(ctx context.Context, role string) (RoleModels, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetRoleModels")
+	defer apm.EndTransaction(span)
+
+	var models RoleModels
+	err := r.db.Collection("role_models").FindOne(ctx, bson.M{"role": role}).Decode(&models)
+	return models, err
+}
+
+// UpsertRoleModels replaces the model allow-list of a role
+func (r *MongoDBRepository) UpsertRoleModels(ctx context.Context, models RoleModels) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::UpsertRoleModels")
+	defer apm.EndTransaction(span)
+
+	if models.AllowedModels == nil {
+		models.AllowedModels = []string{}
+	}
+	update := bson.M{"$set": bson.M{
+		"allowed_models": models.AllowedModels,
+		"updated_by":     models.UpdatedBy,
+		"updated_at":     time.Now(),
+	}}
+	_, err := r.db.Collection("role_models").UpdateOne(ctx, bson.M{"role": models.Role}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// DeleteRoleModels removes the model allow-list set for a role, it falls back to the configured one
+func (r *MongoDBRepository) DeleteRoleModels(ctx context.Context, role string) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::DeleteRoleModels")
+	defer apm.EndTransaction(span)
+
+	_, err := r.db.Collection("role_models").DeleteOne(ctx, bson.M{"role": role})
+	return err
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServiceKey is the password of a backend service set through the admin API, it replaces the configured one.
+type ServiceKey struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"` // Unique identifier for the key
+	Service      string             `bson:"service"`       // Name of the backend service
+	PasswordHash string             `bson:"password_hash"` // Hex SHA-256 of the password, the password itself is never stored
+	CreatedBy    string             `bson:"created_by"`    // Email of the admin who set the key
+	CreatedAt    time.Time          `bson:"created_at"`    // Timestamp when the key was set
+}
+
+// RoleModels is the model allow-list of a role set through the admin API, it replaces the configured one.
+type RoleModels struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`  // Unique identifier for the allow-list
+	Role          string             `bson:"role"`           // Either admin, user, or service
+	AllowedModels []string           `bson:"allowed_models"` // Empty means every model is allowed
+	UpdatedBy     string             `bson:"updated_by"`     // Email of the admin who last changed the list
+	UpdatedAt     time.Time          `bson:"updated_at"`     // Timestamp when the list was last changed
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertAuditEntry stores a new audit entry
+func (r *MongoDBRepository) InsertAuditEntry(ctx context.Context, entry AuditEntry) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertAuditEntry")
+	defer apm.EndTransaction(span)
+
+	entry.ID = primitive.NewObjectID()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	_, err := r.db.Collection("audit").InsertOne(ctx, entry)
+	return err
+}
+
+// ListAuditEntries returns the latest audit entries, optionally of a single action
+func (r *MongoDBRepository) ListAuditEntries(ctx context.Context, action string, limit int) ([]AuditEntry, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListAuditEntries")
+	defer apm.EndTransaction(span)
+
+	filter := bson.M{}
+	if action != "" {
+		filter["action"] = action
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.db.Collection("audit").Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
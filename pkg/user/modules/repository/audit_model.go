@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntry represents a security relevant action kept for later review.
+type AuditEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`    // Unique identifier for the entry
+	Action        string             `bson:"action"`           // What happened, e.g. content_filtered
+	Principal     string             `bson:"principal"`        // User email or service name the action concerns
+	PrincipalType string             `bson:"principal_type"`   // Either user or service
+	Tribe         string             `bson:"tribe,omitempty"`  // Tribe owning the principal, if known
+	Detail        map[string]string  `bson:"detail,omitempty"` // Action specific details, e.g. the rule that matched
+	CreatedAt     time.Time          `bson:"created_at"`       // Timestamp when the action happened
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const filesBucket = "files"
+
+// InsertFile stores the file metadata and its content in GridFS
+func (r *MongoDBRepository) InsertFile(ctx context.Context, file File, content []byte) (File, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertFile")
+	defer apm.EndTransaction(span)
+
+	bucket, err := gridfs.NewBucket(r.db, options.GridFSBucket().SetName(filesBucket))
+	if err != nil {
+		return File{}, err
+	}
+
+	file.ID = primitive.NewObjectID()
+	file.Bytes = int64(len(content))
+	file.CreatedAt = time.Now()
+	err = bucket.UploadFromStreamWithID(file.ID, file.Filename, bytes.NewReader(content))
+	if err != nil {
+		return File{}, err
+	}
+
+	_, err = r.db.Collection("files").InsertOne(ctx, file)
+	return file, err
+}
+
+// GetFile finds file metadata by ID
+func (r *MongoDBRepository) GetFile(ctx context.Context, id string) (File, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetFile")
+	defer apm.EndTransaction(span)
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return File{}, mongo.ErrNoDocuments
+	}
+
+	var file File
+	err = r.db.Collection("files").FindOne(ctx, bson.M{"_id": objectID}).Decode(&file)
+	return file, err
+}
+
+// GetFileContent reads the content of a file from GridFS
+func (r *MongoDBRepository) GetFileContent(ctx context.Context, id string) ([]byte, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetFileContent")
+	defer apm.EndTransaction(span)
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	bucket, err := gridfs.NewBucket(r.db, options.GridFSBucket().SetName(filesBucket))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	_, err = bucket.DownloadToStream(objectID, &buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// InsertBatch stores a new batch
+func (r *MongoDBRepository) InsertBatch(ctx context.Context, batch Batch) (Batch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertBatch")
+	defer apm.EndTransaction(span)
+
+	batch.ID = primitive.NewObjectID()
+	batch.CreatedAt = time.Now()
+	batch.UpdatedAt = batch.CreatedAt
+	_, err := r.db.Collection("batches").InsertOne(ctx, batch)
+	return batch, err
+}
+
+// GetBatch finds a batch by ID
+func (r *MongoDBRepository) GetBatch(ctx context.Context, id string) (Batch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetBatch")
+	defer apm.EndTransaction(span)
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Batch{}, mongo.ErrNoDocuments
+	}
+
+	var batch Batch
+	err = r.db.Collection("batches").FindOne(ctx, bson.M{"_id": objectID}).Decode(&batch)
+	return batch, err
+}
+
+// ListBatches returns the latest batches of an owner
+func (r *MongoDBRepository) ListBatches(ctx context.Context, owner string, limit int) ([]Batch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListBatches")
+	defer apm.EndTransaction(span)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.db.Collection("batches").Find(ctx, bson.M{"owner": owner}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	batches := []Batch{}
+	if err := cursor.All(ctx, &batches); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// StartBatch moves a validating batch to in_progress, it returns mongo.ErrNoDocuments when the batch
+// is no longer validating, e.g. because it was cancelled meanwhile
+func (r *MongoDBRepository) StartBatch(ctx context.Context, id primitive.ObjectID, total int) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::StartBatch")
+	defer apm.EndTransaction(span)
+
+	now := time.Now()
+	result, err := r.db.Collection("batches").UpdateOne(ctx, bson.M{"_id": id, "status": "validating"}, bson.M{"$set": bson.M{
+		"status":               "in_progress",
+		"in_progress_at":       now,
+		"request_counts.total": total,
+		"updated_at":           now,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// UpdateBatchCounts stores the request counts of a running batch, its status is left alone
+func (r *MongoDBRepository) UpdateBatchCounts(ctx context.Context, id primitive.ObjectID, counts BatchRequestCounts) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::UpdateBatchCounts")
+	defer apm.EndTransaction(span)
+
+	_, err := r.db.Collection("batches").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"request_counts": counts,
+		"updated_at":     time.Now(),
+	}})
+	return err
+}
+
+// TouchBatch marks a running batch as alive and returns its stored state
+func (r *MongoDBRepository) TouchBatch(ctx context.Context, id primitive.ObjectID) (Batch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::TouchBatch")
+	defer apm.EndTransaction(span)
+
+	var batch Batch
+	err := r.db.Collection("batches").
+		FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"updated_at": time.Now()}}, options.FindOneAndUpdate().SetReturnDocument(options.After)).
+		Decode(&batch)
+	return batch, err
+}
+
+// FinishBatch stores the final state of a batch when its current status is one of from,
+// it returns mongo.ErrNoDocuments otherwise
+func (r *MongoDBRepository) FinishBatch(ctx context.Context, batch Batch, from ...string) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::FinishBatch")
+	defer apm.EndTransaction(span)
+
+	update := bson.M{
+		"status":         batch.Status,
+		"errors":         batch.Errors,
+		"request_counts": batch.RequestCounts,
+		"output_file_id": batch.OutputFileID,
+		"error_file_id":  batch.ErrorFileID,
+		"completed_at":   batch.CompletedAt,
+		"failed_at":      batch.FailedAt,
+		"expired_at":     batch.ExpiredAt,
+		"cancelled_at":   batch.CancelledAt,
+		"updated_at":     time.Now(),
+	}
+	result, err := r.db.Collection("batches").UpdateOne(ctx, bson.M{"_id": batch.ID, "status": bson.M{"$in": from}}, bson.M{"$set": update})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ListStaleBatches returns the unfinished batches no instance has touched since before
+func (r *MongoDBRepository) ListStaleBatches(ctx context.Context, before time.Time) ([]Batch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListStaleBatches")
+	defer apm.EndTransaction(span)
+
+	filter := bson.M{
+		"status":     bson.M{"$in": bson.A{"validating", "in_progress", "cancelling"}},
+		"updated_at": bson.M{"$lt": before},
+	}
+	cursor, err := r.db.Collection("batches").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	batches := []Batch{}
+	if err := cursor.All(ctx, &batches); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// CancelBatch moves a validating or in progress batch to cancelling and returns the updated batch
+func (r *MongoDBRepository) CancelBatch(ctx context.Context, id string) (Batch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::CancelBatch")
+	defer apm.EndTransaction(span)
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Batch{}, mongo.ErrNoDocuments
+	}
+
+	now := time.Now()
+	filter := bson.M{"_id": objectID, "status": bson.M{"$in": bson.A{"validating", "in_progress"}}}
+	update := bson.M{"$set": bson.M{"status": "cancelling", "cancelling_at": now, "updated_at": now}}
+
+	var batch Batch
+	err = r.db.Collection("batches").
+		FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).
+		Decode(&batch)
+	return batch, err
+}
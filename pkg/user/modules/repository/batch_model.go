@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// File represents an uploaded file, its content is stored in GridFS under the same ID.
+type File struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"` // Unique identifier, also the GridFS file ID
+	Owner     string             `bson:"owner"`         // Service or user that uploaded the file
+	Purpose   string             `bson:"purpose"`       // Either batch or batch_output
+	Filename  string             `bson:"filename"`      // Original file name
+	Bytes     int64              `bson:"bytes"`         // Size of the content
+	CreatedAt time.Time          `bson:"created_at"`    // Timestamp when the file was uploaded
+}
+
+// BatchRequestCounts counts the requests of a batch by outcome.
+type BatchRequestCounts struct {
+	Total     int `bson:"total"`
+	Completed int `bson:"completed"`
+	Failed    int `bson:"failed"`
+}
+
+// Batch represents a batch of requests processed in the background.
+type Batch struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty"`     // Unique identifier for the batch
+	Owner            string             `bson:"owner"`             // Service or user that created the batch
+	Endpoint         string             `bson:"endpoint"`          // Endpoint every request is sent to
+	InputFileID      string             `bson:"input_file_id"`     // File holding the requests
+	OutputFileID     string             `bson:"output_file_id"`    // File holding successful responses
+	ErrorFileID      string             `bson:"error_file_id"`     // File holding failed requests
+	CompletionWindow string             `bson:"completion_window"` // Time frame the batch must complete in
+	Status           string             `bson:"status"`            // validating, failed, in_progress, completed, expired, cancelling, or cancelled
+	Errors           []string           `bson:"errors"`            // Validation errors of the input file
+	RequestCounts    BatchRequestCounts `bson:"request_counts"`    // Requests by outcome
+	Metadata         map[string]string  `bson:"metadata"`          // Caller supplied metadata
+	CallbackURL      string             `bson:"callback_url"`      // Notified when the batch ends, if set
+	CreatedAt        time.Time          `bson:"created_at"`        // Timestamp when the batch was created
+	InProgressAt     *time.Time         `bson:"in_progress_at"`    // Timestamp when processing started
+	CompletedAt      *time.Time         `bson:"completed_at"`      // Timestamp when processing completed
+	FailedAt         *time.Time         `bson:"failed_at"`         // Timestamp when validation failed
+	ExpiredAt        *time.Time         `bson:"expired_at"`        // Timestamp when the completion window ran out
+	CancellingAt     *time.Time         `bson:"cancelling_at"`     // Timestamp when cancellation was requested
+	CancelledAt      *time.Time         `bson:"cancelled_at"`      // Timestamp when the batch was cancelled
+	UpdatedAt        time.Time          `bson:"updated_at"`        // Timestamp when the batch was last updated
+}
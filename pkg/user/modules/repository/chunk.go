@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/user/modules/vectorstore"
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertChunks stores embedded chunks
+func (r *MongoDBRepository) InsertChunks(ctx context.Context, chunks []Chunk) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertChunks")
+	defer apm.EndTransaction(span)
+
+	if len(chunks) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(chunks))
+	for _, chunk := range chunks {
+		chunk.ID = primitive.NewObjectID()
+		docs = append(docs, chunk)
+	}
+	_, err := r.db.Collection("document_chunks").InsertMany(ctx, docs)
+	return err
+}
+
+// SearchChunks returns the topK chunks of the knowledge bases most similar to vector with a score of at least minScore.
+// Chunks are scored in the application, which is fine for the few thousand chunks a knowledge base usually holds.
+func (r *MongoDBRepository) SearchChunks(ctx context.Context, knowledgeBaseIDs []primitive.ObjectID, vector []float32, topK int, minScore float64) ([]ChunkMatch, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::SearchChunks")
+	defer apm.EndTransaction(span)
+
+	cursor, err := r.db.Collection("document_chunks").Find(ctx, bson.M{"knowledge_base_id": bson.M{"$in": knowledgeBaseIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	matches := []ChunkMatch{}
+	for cursor.Next(ctx) {
+		var chunk Chunk
+		if err := cursor.Decode(&chunk); err != nil {
+			return nil, err
+		}
+		score := vectorstore.Cosine(vector, chunk.Vector)
+		if score < minScore {
+			continue
+		}
+		matches = append(matches, ChunkMatch{Chunk: chunk, Score: score})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches, nil
+}
+
+// DeleteDocumentChunks removes the chunks of a document
+func (r *MongoDBRepository) DeleteDocumentChunks(ctx context.Context, documentID primitive.ObjectID) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::DeleteDocumentChunks")
+	defer apm.EndTransaction(span)
+
+	_, err := r.db.Collection("document_chunks").DeleteMany(ctx, bson.M{"document_id": documentID})
+	return err
+}
+
+// DeleteKnowledgeBaseChunks removes the chunks of every document of a knowledge base
+func (r *MongoDBRepository) DeleteKnowledgeBaseChunks(ctx context.Context, knowledgeBaseID primitive.ObjectID) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::DeleteKnowledgeBaseChunks")
+	defer apm.EndTransaction(span)
+
+	_, err := r.db.Collection("document_chunks").DeleteMany(ctx, bson.M{"knowledge_base_id": knowledgeBaseID})
+	return err
+}
@@ -23,44 +23,56 @@ func NewMongoDBRepository(db *mongo.Database) *MongoDBRepository {
 	return &repo
 }
 
-// UpsertConversation inserts new messages into the messages collection and updates the conversation
-func (r *MongoDBRepository) UpsertConversation(ctx context.Context, userID string, messages []Message, summary *Summary) error {
+// UpsertConversation inserts new messages into the messages collection and updates the conversation.
+// The first message continues parent, each following one continues the message before it unless it names its
+// own parent. A zero conversationID stores them in the latest conversation of the user, opening one if needed.
+func (r *MongoDBRepository) UpsertConversation(ctx context.Context, userID string, conversationID primitive.ObjectID, parent primitive.ObjectID, messages []Message, summary *Summary) error {
 	ctx, span := apm.StartTransaction(ctx, "Repository::UpsertConversation")
 	defer apm.EndTransaction(span)
 
 	conversationsCollection := r.db.Collection("conversations")
 	messagesCollection := r.db.Collection("messages")
 
-	// Create filter to find the last conversation by UserID
-	filter := bson.M{"user_id": userID}
+	conversation := Conversation{ID: conversationID}
+	if conversationID.IsZero() {
+		// Create filter to find the last conversation by UserID
+		filter := bson.M{"user_id": userID}
 
-	// Find options to sort by updated_at in descending order and limit to 1
-	findOptions := options.FindOne().SetSort(bson.D{{"updated_at", -1}})
+		// Find options to sort by updated_at in descending order and limit to 1
+		findOptions := options.FindOne().SetSort(bson.D{{"updated_at", -1}})
 
-	var conversation Conversation
-	err := conversationsCollection.FindOne(ctx, filter, findOptions).Decode(&conversation)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			// No existing conversation found, create a new one
-			conversation = Conversation{
-				ID:        primitive.NewObjectID(),
-				UserID:    userID,
-				Summaries: []Summary{},
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			}
-			_, err = conversationsCollection.InsertOne(ctx, conversation)
-			if err != nil {
+		err := conversationsCollection.FindOne(ctx, filter, findOptions).Decode(&conversation)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				// No existing conversation found, create a new one
+				conversation = Conversation{
+					ID:        primitive.NewObjectID(),
+					UserID:    userID,
+					Summaries: []Summary{},
+					CreatedAt: time.Now(),
+					UpdatedAt: time.Now(),
+				}
+				_, err = conversationsCollection.InsertOne(ctx, conversation)
+				if err != nil {
+					return err
+				}
+			} else {
 				return err
 			}
-		} else {
-			return err
 		}
 	}
 
 	// Prepare messages for bulk insert
 	var messageDocs []interface{}
+	leaf := parent
 	for _, message := range messages {
+		if message.ID.IsZero() {
+			message.ID = primitive.NewObjectID()
+		}
+		if message.ParentID.IsZero() {
+			message.ParentID = leaf
+		}
+		leaf = message.ID
 		message.ConversationID = conversation.ID
 		message.Timestamp = time.Now()
 		messageDocs = append(messageDocs, message)
@@ -68,23 +80,153 @@ func (r *MongoDBRepository) UpsertConversation(ctx context.Context, userID strin
 
 	// Insert new messages into the messages collection
 	if len(messageDocs) > 0 {
-		_, err = messagesCollection.InsertMany(ctx, messageDocs)
-		if err != nil {
+		if _, err := messagesCollection.InsertMany(ctx, messageDocs); err != nil {
 			return err
 		}
 	}
 
 	// Update the conversation's updated_at timestamp
 	update := bson.M{
-		"$set": bson.M{"updated_at": time.Now()},
+		"$set": bson.M{"updated_at": time.Now(), "active_leaf": leaf},
 	}
 
 	if len(summary.Content) > 0 {
-		// Add the new summary to the conversation
+		// Add the new summary to the conversation, it covers the branch up to the last message
+		summary.UpTo = leaf
 		update["$push"] = bson.M{"summaries": summary}
 	}
 
 	// Perform the update operation on the conversation
-	_, err = conversationsCollection.UpdateOne(ctx, bson.M{"_id": conversation.ID}, update)
+	_, err := conversationsCollection.UpdateOne(ctx, bson.M{"_id": conversation.ID}, update)
+	return err
+}
+
+// SetConversationPersona records the persona selected for the latest conversation of a user
+func (r *MongoDBRepository) SetConversationPersona(ctx context.Context, userID string, persona string) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::SetConversationPersona")
+	defer apm.EndTransaction(span)
+
+	return r.setOnLatestConversation(ctx, userID, "persona", persona)
+}
+
+// SetConversationSettings records the model parameters chosen for the latest conversation of a user
+func (r *MongoDBRepository) SetConversationSettings(ctx context.Context, userID string, settings ModelSettings) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::SetConversationSettings")
+	defer apm.EndTransaction(span)
+
+	return r.setOnLatestConversation(ctx, userID, "settings", settings)
+}
+
+// setOnLatestConversation sets a field of the latest conversation of a user. A user without one yet,
+// e.g. on their first prompt, gets it created, the prompt's messages are then added to it.
+func (r *MongoDBRepository) setOnLatestConversation(ctx context.Context, userID string, field string, value interface{}) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{field: value},
+		"$setOnInsert": bson.M{
+			"summaries":  []Summary{},
+			"created_at": now,
+			"updated_at": now,
+		},
+	}
+	findOptions := options.FindOneAndUpdate().SetSort(bson.D{{Key: "updated_at", Value: -1}}).SetUpsert(true).SetReturnDocument(options.After)
+	return r.db.Collection("conversations").FindOneAndUpdate(ctx, bson.M{"user_id": userID}, update, findOptions).Err()
+}
+
+// StartConversation creates an empty conversation, later messages of the user are added to it
+func (r *MongoDBRepository) StartConversation(ctx context.Context, userID string) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::StartConversation")
+	defer apm.EndTransaction(span)
+
+	_, err := r.db.Collection("conversations").InsertOne(ctx, Conversation{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Summaries: []Summary{},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	return err
+}
+
+// GetLatestConversation returns the conversation a user is currently in
+func (r *MongoDBRepository) GetLatestConversation(ctx context.Context, userID string) (Conversation, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetLatestConversation")
+	defer apm.EndTransaction(span)
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	var conversation Conversation
+	err := r.db.Collection("conversations").FindOne(ctx, bson.M{"user_id": userID}, findOptions).Decode(&conversation)
+	return conversation, err
+}
+
+// ListConversationMessages returns every message of a conversation across all its branches, oldest first
+func (r *MongoDBRepository) ListConversationMessages(ctx context.Context, conversationID primitive.ObjectID) ([]Message, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListConversationMessages")
+	defer apm.EndTransaction(span)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.db.Collection("messages").Find(ctx, bson.M{"conversation_id": conversationID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	messages := []Message{}
+	if err = cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// SetActiveLeaf moves a conversation to the branch ending at leaf, a zero leaf starts a new branch from the root
+func (r *MongoDBRepository) SetActiveLeaf(ctx context.Context, conversationID primitive.ObjectID, leaf primitive.ObjectID) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::SetActiveLeaf")
+	defer apm.EndTransaction(span)
+
+	update := bson.M{"$set": bson.M{"active_leaf": leaf, "updated_at": time.Now()}}
+	if leaf.IsZero() {
+		update = bson.M{"$set": bson.M{"updated_at": time.Now()}, "$unset": bson.M{"active_leaf": ""}}
+	}
+	_, err := r.db.Collection("conversations").UpdateOne(ctx, bson.M{"_id": conversationID}, update)
+	return err
+}
+
+// ListConversations returns every conversation of a user, oldest first
+func (r *MongoDBRepository) ListConversations(ctx context.Context, userID string) ([]Conversation, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListConversations")
+	defer apm.EndTransaction(span)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.db.Collection("conversations").Find(ctx, bson.M{"user_id": userID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	conversations := []Conversation{}
+	if err = cursor.All(ctx, &conversations); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// InsertConversation stores a complete conversation with its messages, keeping their timestamps
+func (r *MongoDBRepository) InsertConversation(ctx context.Context, conversation Conversation, messages []Message) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertConversation")
+	defer apm.EndTransaction(span)
+
+	var docs []interface{}
+	for _, message := range messages {
+		message.ConversationID = conversation.ID
+		docs = append(docs, message)
+	}
+	if len(docs) > 0 {
+		if _, err := r.db.Collection("messages").InsertMany(ctx, docs); err != nil {
+			return err
+		}
+	}
+
+	// the conversation goes in last so it only becomes the latest one once its messages are there
+	_, err := r.db.Collection("conversations").InsertOne(ctx, conversation)
 	return err
 }
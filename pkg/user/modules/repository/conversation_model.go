@@ -10,29 +10,71 @@ import (
 type Content struct {
 	Type     string  `bson:"type"`      // Type of content, either text or image
 	Text     *string `bson:"text"`      // Text content of the message
-	ImageURL string  `bson:"image_url"` // URL of the image, if applicable
+	ImageURL string  `bson:"image_url"` // URL of the image or blob:// reference, if applicable
+
+	ImageDetail string `bson:"image_detail,omitempty"` // Detail the image was sent with, if set
 }
 
 // Message represents a single message in a conversation.
 type Message struct {
-	ID             primitive.ObjectID `bson:"_id,omitempty"`   // Unique identifier for the message
-	ConversationID primitive.ObjectID `bson:"conversation_id"` // Foreign key to link with a conversation
-	Role           string             `bson:"role"`            // Either user, system, or assistant
-	Content        []Content          `bson:"content"`         // The actual message content
-	Timestamp      time.Time          `bson:"timestamp"`       // Time when the message was sent
+	ID             primitive.ObjectID `bson:"_id,omitempty"`       // Unique identifier for the message
+	ConversationID primitive.ObjectID `bson:"conversation_id"`     // Foreign key to link with a conversation
+	ParentID       primitive.ObjectID `bson:"parent_id,omitempty"` // Previous message of the branch, unset for the first message
+	Role           string             `bson:"role"`                // Either user, system, or assistant
+	Content        []Content          `bson:"content"`             // The actual message content
+	Timestamp      time.Time          `bson:"timestamp"`           // Time when the message was sent
+
+	Model    string    `bson:"model,omitempty"`    // Model that wrote an assistant message
+	Persona  string    `bson:"persona,omitempty"`  // name@vN of the persona an assistant message was written with
+	Template string    `bson:"template,omitempty"` // name@vN of the prompt template of a service prompt
+	Service  string    `bson:"service,omitempty"`  // Backend service that sent the prompt, unset for users
+	Feedback *Feedback `bson:"feedback,omitempty"` // Rating given to an assistant message
+}
+
+// Feedback represents the rating of an assistant message by the user or service it answered.
+type Feedback struct {
+	Rating    string    `bson:"rating"`            // Either up or down
+	Comment   string    `bson:"comment,omitempty"` // Free-text comment
+	Rater     string    `bson:"rater"`             // User email or service name
+	CreatedAt time.Time `bson:"created_at"`        // Timestamp when the rating was last given
+}
+
+// FeedbackAggregate represents ratings grouped by a single key.
+type FeedbackAggregate struct {
+	Key      string `bson:"_id"`      // Value of the grouped field
+	Ratings  int    `bson:"ratings"`  // Number of rated messages
+	Up       int    `bson:"up"`       // Number of thumbs up
+	Down     int    `bson:"down"`     // Number of thumbs down
+	Comments int    `bson:"comments"` // Number of ratings with a comment
 }
 
 // Summary represents a summarized form of a message in a conversation (without ID and timestamps).
 type Summary struct {
 	Role    string    `bson:"role"`    // Role in the conversation (e.g., user, system, assistant)
 	Content []Content `bson:"content"` // The summarized content of the conversation
+
+	UpTo primitive.ObjectID `bson:"up_to,omitempty"` // Last message covered by the summary
 }
 
 // Conversation represents a conversation tied to a user session.
 type Conversation struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"` // Unique identifier for the conversation
-	UserID    string             `bson:"user_id"`       // ID of the user associated with the conversation
-	Summaries []Summary          `bson:"summaries"`     // Array of summaries for the conversation
-	CreatedAt time.Time          `bson:"created_at"`    // Timestamp when the conversation was created
-	UpdatedAt time.Time          `bson:"updated_at"`    // Timestamp when the conversation was last updated
+	ID        primitive.ObjectID `bson:"_id,omitempty"`      // Unique identifier for the conversation
+	UserID    string             `bson:"user_id"`            // ID of the user associated with the conversation
+	Summaries []Summary          `bson:"summaries"`          // Array of summaries for the conversation
+	Persona   string             `bson:"persona,omitempty"`  // Persona reference (name@vN) selected for the conversation
+	Settings  *ModelSettings     `bson:"settings,omitempty"` // Model parameters chosen for the conversation
+
+	ActiveLeaf primitive.ObjectID `bson:"active_leaf,omitempty"` // Last message of the branch the user is on
+	CreatedAt  time.Time          `bson:"created_at"`            // Timestamp when the conversation was created
+	UpdatedAt  time.Time          `bson:"updated_at"`            // Timestamp when the conversation was last updated
+}
+
+// ModelSettings represents the model parameters a user chose for a conversation.
+type ModelSettings struct {
+	Model       string   `bson:"model,omitempty"`       // Model to prompt
+	Temperature *float64 `bson:"temperature,omitempty"` // Sampling temperature
+	TopP        *float64 `bson:"top_p,omitempty"`       // Nucleus sampling probability
+	MaxTokens   *int     `bson:"max_tokens,omitempty"`  // Completion token limit
+	Stop        []string `bson:"stop,omitempty"`        // Stop sequences
+	Seed        *int     `bson:"seed,omitempty"`        // Seed for deterministic sampling
 }
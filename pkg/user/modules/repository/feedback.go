@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// feedbackGroupFields maps a report grouping to the message field used as the group key
+var feedbackGroupFields = map[string]string{
+	"model":    "$model",
+	"persona":  "$persona",
+	"template": "$template",
+	"service":  "$service",
+}
+
+// InsertMessages stores messages that are not part of a user conversation, e.g. service prompts
+func (r *MongoDBRepository) InsertMessages(ctx context.Context, messages []Message) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertMessages")
+	defer apm.EndTransaction(span)
+
+	var docs []interface{}
+	for _, message := range messages {
+		message.Timestamp = time.Now()
+		docs = append(docs, message)
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	_, err := r.db.Collection("messages").InsertMany(ctx, docs)
+	return err
+}
+
+// GetMessage returns a message by its ID
+func (r *MongoDBRepository) GetMessage(ctx context.Context, id primitive.ObjectID) (Message, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetMessage")
+	defer apm.EndTransaction(span)
+
+	var message Message
+	err := r.db.Collection("messages").FindOne(ctx, bson.M{"_id": id}).Decode(&message)
+	return message, err
+}
+
+// GetConversation returns a conversation by its ID
+func (r *MongoDBRepository) GetConversation(ctx context.Context, id primitive.ObjectID) (Conversation, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetConversation")
+	defer apm.EndTransaction(span)
+
+	var conversation Conversation
+	err := r.db.Collection("conversations").FindOne(ctx, bson.M{"_id": id}).Decode(&conversation)
+	return conversation, err
+}
+
+// SetMessageFeedback records the rating of a message, replacing an earlier one
+func (r *MongoDBRepository) SetMessageFeedback(ctx context.Context, id primitive.ObjectID, feedback Feedback) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::SetMessageFeedback")
+	defer apm.EndTransaction(span)
+
+	result, err := r.db.Collection("messages").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"feedback": feedback}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// AggregateFeedback counts ratings given in [from, to) grouped by model, persona, template or service
+func (r *MongoDBRepository) AggregateFeedback(ctx context.Context, groupBy string, from time.Time, to time.Time) ([]FeedbackAggregate, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::AggregateFeedback")
+	defer apm.EndTransaction(span)
+
+	groupField, ok := feedbackGroupFields[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported group by: %s", groupBy)
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"feedback.created_at": bson.M{"$gte": from, "$lt": to}}},
+		{"$group": bson.M{
+			"_id":      bson.M{"$ifNull": bson.A{groupField, ""}},
+			"ratings":  bson.M{"$sum": 1},
+			"up":       bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$feedback.rating", "up"}}, 1, 0}}},
+			"down":     bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$feedback.rating", "down"}}, 1, 0}}},
+			"comments": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$feedback.comment", ""}}, 1, 0}}},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := r.db.Collection("messages").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []FeedbackAggregate{}
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListRatedMessages returns the messages rated in [from, to), optionally only those with the given rating, oldest rating first
+func (r *MongoDBRepository) ListRatedMessages(ctx context.Context, from time.Time, to time.Time, rating string) ([]Message, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListRatedMessages")
+	defer apm.EndTransaction(span)
+
+	filter := bson.M{"feedback.created_at": bson.M{"$gte": from, "$lt": to}}
+	if rating != "" {
+		filter["feedback.rating"] = rating
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "feedback.created_at", Value: 1}})
+	cursor, err := r.db.Collection("messages").Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	messages := []Message{}
+	if err = cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
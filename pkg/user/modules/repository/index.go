@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// versionInsertAttempts bounds the retries of an insert that raced another one for the same version
+const versionInsertAttempts = 5
+
+// EnsureIndexes creates the indexes the repository relies on, existing ones are left as they are
+func (r *MongoDBRepository) EnsureIndexes(ctx context.Context) error {
+	// a name@vN reference must resolve to a single document
+	versioned := mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	for _, collection := range []string{"personas", "prompt_templates"} {
+		if _, err := r.db.Collection(collection).Indexes().CreateOne(ctx, versioned); err != nil {
+			return err
+		}
+	}
+
+	// an upsert racing another one must not leave two keys or allow-lists behind
+	for collection, field := range map[string]string{"service_keys": "service", "role_models": "role"} {
+		unique := mongo.IndexModel{
+			Keys:    bson.D{{Key: field, Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}
+		if _, err := r.db.Collection(collection).Indexes().CreateOne(ctx, unique); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertKnowledgeBase stores a new knowledge base
+func (r *MongoDBRepository) InsertKnowledgeBase(ctx context.Context, kb KnowledgeBase) (KnowledgeBase, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertKnowledgeBase")
+	defer apm.EndTransaction(span)
+
+	kb.ID = primitive.NewObjectID()
+	kb.CreatedAt = time.Now()
+	kb.UpdatedAt = kb.CreatedAt
+	_, err := r.db.Collection("knowledge_bases").InsertOne(ctx, kb)
+	return kb, err
+}
+
+// GetKnowledgeBase finds a knowledge base by owner and name
+func (r *MongoDBRepository) GetKnowledgeBase(ctx context.Context, owner string, name string) (KnowledgeBase, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetKnowledgeBase")
+	defer apm.EndTransaction(span)
+
+	var kb KnowledgeBase
+	err := r.db.Collection("knowledge_bases").FindOne(ctx, bson.M{"owner": owner, "name": name}).Decode(&kb)
+	return kb, err
+}
+
+// ListKnowledgeBases returns the knowledge bases of an owner sorted by name
+func (r *MongoDBRepository) ListKnowledgeBases(ctx context.Context, owner string) ([]KnowledgeBase, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListKnowledgeBases")
+	defer apm.EndTransaction(span)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.db.Collection("knowledge_bases").Find(ctx, bson.M{"owner": owner}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	kbs := []KnowledgeBase{}
+	if err := cursor.All(ctx, &kbs); err != nil {
+		return nil, err
+	}
+	return kbs, nil
+}
+
+// DeleteKnowledgeBase removes a knowledge base and its documents, chunks are removed with DeleteKnowledgeBaseChunks
+func (r *MongoDBRepository) DeleteKnowledgeBase(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::DeleteKnowledgeBase")
+	defer apm.EndTransaction(span)
+
+	if _, err := r.db.Collection("documents").DeleteMany(ctx, bson.M{"knowledge_base_id": id}); err != nil {
+		return err
+	}
+	_, err := r.db.Collection("knowledge_bases").DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// InsertDocument stores a new document
+func (r *MongoDBRepository) InsertDocument(ctx context.Context, doc Document) (Document, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertDocument")
+	defer apm.EndTransaction(span)
+
+	doc.ID = primitive.NewObjectID()
+	doc.CreatedAt = time.Now()
+	doc.UpdatedAt = doc.CreatedAt
+	_, err := r.db.Collection("documents").InsertOne(ctx, doc)
+	return doc, err
+}
+
+// UpdateDocument replaces a document
+func (r *MongoDBRepository) UpdateDocument(ctx context.Context, doc Document) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::UpdateDocument")
+	defer apm.EndTransaction(span)
+
+	doc.UpdatedAt = time.Now()
+	_, err := r.db.Collection("documents").ReplaceOne(ctx, bson.M{"_id": doc.ID}, doc)
+	return err
+}
+
+// GetDocument finds a document by ID
+func (r *MongoDBRepository) GetDocument(ctx context.Context, id string) (Document, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetDocument")
+	defer apm.EndTransaction(span)
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Document{}, mongo.ErrNoDocuments
+	}
+
+	var doc Document
+	err = r.db.Collection("documents").FindOne(ctx, bson.M{"_id": objectID}).Decode(&doc)
+	return doc, err
+}
+
+// ListDocuments returns the documents of a knowledge base, latest first
+func (r *MongoDBRepository) ListDocuments(ctx context.Context, knowledgeBaseID primitive.ObjectID) ([]Document, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListDocuments")
+	defer apm.EndTransaction(span)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.db.Collection("documents").Find(ctx, bson.M{"knowledge_base_id": knowledgeBaseID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []Document{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// DeleteDocument removes a document, its chunks are removed with DeleteDocumentChunks
+func (r *MongoDBRepository) DeleteDocument(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::DeleteDocument")
+	defer apm.EndTransaction(span)
+
+	_, err := r.db.Collection("documents").DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
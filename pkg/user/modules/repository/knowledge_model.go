@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KnowledgeBase represents a named collection of documents used for retrieval-augmented generation.
+type KnowledgeBase struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"` // Unique identifier for the knowledge base
+	Owner       string             `bson:"owner"`         // User email or service name owning the knowledge base
+	Name        string             `bson:"name"`          // Name, unique per owner
+	Description string             `bson:"description"`   // Free-text description
+	CreatedAt   time.Time          `bson:"created_at"`    // Timestamp when the knowledge base was created
+	UpdatedAt   time.Time          `bson:"updated_at"`    // Timestamp when the knowledge base was last updated
+}
+
+// Document represents an uploaded document of a knowledge base.
+type Document struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"`     // Unique identifier for the document
+	KnowledgeBaseID primitive.ObjectID `bson:"knowledge_base_id"` // Knowledge base the document belongs to
+	Owner           string             `bson:"owner"`             // User email or service name that uploaded the document
+	Filename        string             `bson:"filename"`          // Original file name
+	MimeType        string             `bson:"mime_type"`         // text/plain, text/markdown, text/html, or application/pdf
+	Bytes           int64              `bson:"bytes"`             // Size of the uploaded content
+	Chunks          int                `bson:"chunks"`            // Number of chunks embedded
+	Status          string             `bson:"status"`            // processing, ready, or failed
+	Error           string             `bson:"error,omitempty"`   // Reason the document failed
+	CreatedAt       time.Time          `bson:"created_at"`        // Timestamp when the document was uploaded
+	UpdatedAt       time.Time          `bson:"updated_at"`        // Timestamp when the document was last updated
+}
+
+// Chunk represents an embedded piece of a document.
+type Chunk struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"`     // Unique identifier for the chunk
+	KnowledgeBaseID primitive.ObjectID `bson:"knowledge_base_id"` // Knowledge base the chunk belongs to
+	DocumentID      primitive.ObjectID `bson:"document_id"`       // Document the chunk was cut from
+	Filename        string             `bson:"filename"`          // File name of the document, kept for citations
+	Index           int                `bson:"index"`             // Position of the chunk in the document
+	Text            string             `bson:"text"`              // Text of the chunk
+	Vector          []float32          `bson:"vector"`            // Embedding of the text
+}
+
+// ChunkMatch is a chunk found by a similarity search and its cosine similarity to the query.
+type ChunkMatch struct {
+	Chunk
+	Score float64
+}
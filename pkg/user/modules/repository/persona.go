@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertPersona stores a new version of a persona, the version number follows the latest stored one
+func (r *MongoDBRepository) InsertPersona(ctx context.Context, persona Persona) (Persona, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertPersona")
+	defer apm.EndTransaction(span)
+
+	// the unique name and version index turns a concurrent create of the same version into a retry
+	var err error
+	for attempt := 0; attempt < versionInsertAttempts; attempt++ {
+		latest, getErr := r.GetPersona(ctx, persona.Name, 0)
+		persona.Version = 1
+		if getErr == nil {
+			persona.Version = latest.Version + 1
+		}
+
+		persona.ID = primitive.NewObjectID()
+		persona.CreatedAt = time.Now()
+		_, err = r.db.Collection("personas").InsertOne(ctx, persona)
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	return persona, err
+}
+
+// GetPersona finds a version of a persona, version 0 returns the latest one
+func (r *MongoDBRepository) GetPersona(ctx context.Context, name string, version int) (Persona, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetPersona")
+	defer apm.EndTransaction(span)
+
+	filter := bson.M{"name": name}
+	if version > 0 {
+		filter["version"] = version
+	}
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	var persona Persona
+	err := r.db.Collection("personas").FindOne(ctx, filter, findOptions).Decode(&persona)
+	return persona, err
+}
+
+// ListPersonas returns the latest version of every persona sorted by name
+func (r *MongoDBRepository) ListPersonas(ctx context.Context) ([]Persona, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListPersonas")
+	defer apm.EndTransaction(span)
+
+	pipeline := []bson.M{
+		{"$sort": bson.D{{Key: "name", Value: 1}, {Key: "version", Value: -1}}},
+		{"$group": bson.M{"_id": "$name", "latest": bson.M{"$first": "$$ROOT"}}},
+		{"$replaceRoot": bson.M{"newRoot": "$latest"}},
+		{"$sort": bson.M{"name": 1}},
+	}
+	cursor, err := r.db.Collection("personas").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	personas := []Persona{}
+	if err := cursor.All(ctx, &personas); err != nil {
+		return nil, err
+	}
+	return personas, nil
+}
+
+// ListPersonaVersions returns every version of a persona, newest first
+func (r *MongoDBRepository) ListPersonaVersions(ctx context.Context, name string) ([]Persona, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListPersonaVersions")
+	defer apm.EndTransaction(span)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := r.db.Collection("personas").Find(ctx, bson.M{"name": name}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	personas := []Persona{}
+	if err := cursor.All(ctx, &personas); err != nil {
+		return nil, err
+	}
+	return personas, nil
+}
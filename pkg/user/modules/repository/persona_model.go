@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Persona represents one version of a named system prompt with its default model parameters.
+type Persona struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`         // Unique identifier for the persona version
+	Name         string             `bson:"name"`                  // Name shared by all versions
+	Version      int                `bson:"version"`               // Version number, starting at 1
+	Description  string             `bson:"description"`           // Free-text description
+	SystemPrompt string             `bson:"system_prompt"`         // System message put at the head of prompts
+	Model        string             `bson:"model,omitempty"`       // Default model
+	Temperature  *float64           `bson:"temperature,omitempty"` // Default temperature
+	TopP         *float64           `bson:"top_p,omitempty"`       // Default top_p
+	MaxTokens    *int               `bson:"max_tokens,omitempty"`  // Default max_tokens
+	CreatedBy    string             `bson:"created_by"`            // Email of the admin who created the version
+	CreatedAt    time.Time          `bson:"created_at"`            // Timestamp when the version was created
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertPromptTemplate stores a new version of a prompt template, the version number follows the latest stored one
+func (r *MongoDBRepository) InsertPromptTemplate(ctx context.Context, tmpl PromptTemplate) (PromptTemplate, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertPromptTemplate")
+	defer apm.EndTransaction(span)
+
+	// the unique name and version index turns a concurrent create of the same version into a retry
+	var err error
+	for attempt := 0; attempt < versionInsertAttempts; attempt++ {
+		latest, getErr := r.GetPromptTemplate(ctx, tmpl.Name, 0)
+		tmpl.Version = 1
+		if getErr == nil {
+			tmpl.Version = latest.Version + 1
+		}
+
+		tmpl.ID = primitive.NewObjectID()
+		tmpl.CreatedAt = time.Now()
+		_, err = r.db.Collection("prompt_templates").InsertOne(ctx, tmpl)
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	return tmpl, err
+}
+
+// GetPromptTemplate finds a version of a prompt template, version 0 returns the latest one
+func (r *MongoDBRepository) GetPromptTemplate(ctx context.Context, name string, version int) (PromptTemplate, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetPromptTemplate")
+	defer apm.EndTransaction(span)
+
+	filter := bson.M{"name": name}
+	if version > 0 {
+		filter["version"] = version
+	}
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	var tmpl PromptTemplate
+	err := r.db.Collection("prompt_templates").FindOne(ctx, filter, findOptions).Decode(&tmpl)
+	return tmpl, err
+}
+
+// ListPromptTemplates returns the latest version of every prompt template sorted by name
+func (r *MongoDBRepository) ListPromptTemplates(ctx context.Context) ([]PromptTemplate, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListPromptTemplates")
+	defer apm.EndTransaction(span)
+
+	pipeline := []bson.M{
+		{"$sort": bson.D{{Key: "name", Value: 1}, {Key: "version", Value: -1}}},
+		{"$group": bson.M{"_id": "$name", "latest": bson.M{"$first": "$$ROOT"}}},
+		{"$replaceRoot": bson.M{"newRoot": "$latest"}},
+		{"$sort": bson.M{"name": 1}},
+	}
+	cursor, err := r.db.Collection("prompt_templates").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []PromptTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// ListPromptTemplateVersions returns every version of a prompt template, newest first
+func (r *MongoDBRepository) ListPromptTemplateVersions(ctx context.Context, name string) ([]PromptTemplate, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListPromptTemplateVersions")
+	defer apm.EndTransaction(span)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := r.db.Collection("prompt_templates").Find(ctx, bson.M{"name": name}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []PromptTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
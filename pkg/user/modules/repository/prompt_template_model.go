@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PromptTemplate represents one version of a named prompt template.
+type PromptTemplate struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"` // Unique identifier for the template version
+	Name        string             `bson:"name"`          // Name shared by all versions
+	Version     int                `bson:"version"`       // Version number, starting at 1
+	Description string             `bson:"description"`   // Free-text description
+	Messages    []TemplateMessage  `bson:"messages"`      // Messages rendered into the prompt
+	Variables   []TemplateVariable `bson:"variables"`     // Variables the messages may use
+	CreatedBy   string             `bson:"created_by"`    // Email of the admin who registered the version
+	CreatedAt   time.Time          `bson:"created_at"`    // Timestamp when the version was registered
+}
+
+// TemplateMessage represents a message of a prompt template in text/template syntax.
+type TemplateMessage struct {
+	Role     string `bson:"role"`     // Role of the rendered message
+	Template string `bson:"template"` // text/template source
+}
+
+// TemplateVariable represents a typed variable declared by a prompt template.
+type TemplateVariable struct {
+	Name        string `bson:"name"`              // Name used as {{.name}}
+	Type        string `bson:"type"`              // string, number, integer, boolean, array, or object
+	Required    bool   `bson:"required"`          // Whether callers must set the variable
+	Default     string `bson:"default,omitempty"` // JSON encoded value of an optional variable left out by the caller
+	Description string `bson:"description"`       // Free-text description
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PurgeConversations removes the conversations of users whose email domain is in domains, or of every
+// user outside excludeDomains when domains is empty, that were last updated before the given time.
+// Their messages and shares go with them. It returns the number of conversations removed and the blob references
+// their messages held.
+func (r *MongoDBRepository) PurgeConversations(ctx context.Context, domains []string, excludeDomains []string, before time.Time) (int64, []string, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::PurgeConversations")
+	defer apm.EndTransaction(span)
+
+	filter := bson.M{"updated_at": bson.M{"$lt": before}}
+	if len(domains) > 0 {
+		filter["user_id"] = bson.M{"$in": domainPatterns(domains)}
+	} else if len(excludeDomains) > 0 {
+		filter["user_id"] = bson.M{"$nin": domainPatterns(excludeDomains)}
+	}
+	return r.deleteConversations(ctx, filter)
+}
+
+// PurgeServiceMessages removes the prompts and answers of a backend service stored before the given time.
+// It returns the number of messages removed and the blob references they held.
+func (r *MongoDBRepository) PurgeServiceMessages(ctx context.Context, service string, before time.Time) (int64, []string, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::PurgeServiceMessages")
+	defer apm.EndTransaction(span)
+
+	filter := bson.M{"service": service, "timestamp": bson.M{"$lt": before}}
+	images, err := r.messageImages(ctx, filter)
+	if err != nil {
+		return 0, nil, err
+	}
+	result, err := r.db.Collection("messages").DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, nil, err
+	}
+	return result.DeletedCount, images, nil
+}
+
+// DeleteUserConversations removes every conversation of a user with their messages and shares
+func (r *MongoDBRepository) DeleteUserConversations(ctx context.Context, userID string) (int64, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::DeleteUserConversations")
+	defer apm.EndTransaction(span)
+
+	count, _, err := r.deleteConversations(ctx, bson.M{"user_id": userID})
+	return count, err
+}
+
+// DeleteShares removes every share created by a user
+func (r *MongoDBRepository) DeleteShares(ctx context.Context, owner string) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::DeleteShares")
+	defer apm.EndTransaction(span)
+
+	_, err := r.db.Collection("shares").DeleteMany(ctx, bson.M{"owner": owner})
+	return err
+}
+
+// ListConversationImages returns the distinct blob references found in the conversations of a user
+func (r *MongoDBRepository) ListConversationImages(ctx context.Context, userID string) ([]string, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListConversationImages")
+	defer apm.EndTransaction(span)
+
+	ids, err := r.conversationIDs(ctx, bson.M{"user_id": userID})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return r.messageImages(ctx, bson.M{"conversation_id": bson.M{"$in": ids}})
+}
+
+// messageImages returns the distinct blob references found in the messages matching filter
+func (r *MongoDBRepository) messageImages(ctx context.Context, filter bson.M) ([]string, error) {
+	withImages := bson.M{"content.image_url": bson.M{"$regex": "^blob://"}}
+	for k, v := range filter {
+		withImages[k] = v
+	}
+	values, err := r.db.Collection("messages").Distinct(ctx, "content.image_url", withImages)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := []string{}
+	for _, v := range values {
+		if ref, ok := v.(string); ok && strings.HasPrefix(ref, "blob://") {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// ImageReferenced reports whether a message or a share still refers to an image
+func (r *MongoDBRepository) ImageReferenced(ctx context.Context, url string) (bool, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ImageReferenced")
+	defer apm.EndTransaction(span)
+
+	count, err := r.db.Collection("messages").CountDocuments(ctx, bson.M{"content.image_url": url}, options.Count().SetLimit(1))
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+	count, err = r.db.Collection("shares").CountDocuments(ctx, bson.M{"messages.content.image_url": url}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// PseudonymizeUsage replaces the principal of usage records with a pseudonym and drops their rendered prompts,
+// the records are kept for chargeback. It returns the number of records changed.
+func (r *MongoDBRepository) PseudonymizeUsage(ctx context.Context, principal string, pseudonym string) (int64, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::PseudonymizeUsage")
+	defer apm.EndTransaction(span)
+
+	result, err := r.db.Collection("usage").UpdateMany(ctx, bson.M{"principal": principal, "principal_type": "user"}, bson.M{
+		"$set":   bson.M{"principal": pseudonym},
+		"$unset": bson.M{"rendered_prompt": ""},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// PseudonymizeAuditEntries replaces the principal of audit entries with a pseudonym and drops their details.
+// It returns the number of entries changed.
+func (r *MongoDBRepository) PseudonymizeAuditEntries(ctx context.Context, principal string, pseudonym string) (int64, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::PseudonymizeAuditEntries")
+	defer apm.EndTransaction(span)
+
+	result, err := r.db.Collection("audit").UpdateMany(ctx, bson.M{"principal": principal, "principal_type": "user"}, bson.M{
+		"$set":   bson.M{"principal": pseudonym},
+		"$unset": bson.M{"detail": ""},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// DeleteUser removes the user document of an email
+func (r *MongoDBRepository) DeleteUser(ctx context.Context, email string) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::DeleteUser")
+	defer apm.EndTransaction(span)
+
+	_, err := r.db.Collection("users").DeleteOne(ctx, bson.M{"email": email})
+	return err
+}
+
+// deleteConversations removes the conversations matching filter, then their messages and shares.
+// It returns the blob references the removed messages held.
+func (r *MongoDBRepository) deleteConversations(ctx context.Context, filter bson.M) (int64, []string, error) {
+	ids, err := r.conversationIDs(ctx, filter)
+	if err != nil || len(ids) == 0 {
+		return 0, nil, err
+	}
+
+	byConversation := bson.M{"conversation_id": bson.M{"$in": ids}}
+	images, err := r.messageImages(ctx, byConversation)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// children go first, a failed run leaves conversations behind for the next one instead of orphans
+	if _, err = r.db.Collection("messages").DeleteMany(ctx, byConversation); err != nil {
+		return 0, nil, err
+	}
+	if _, err = r.db.Collection("shares").DeleteMany(ctx, byConversation); err != nil {
+		return 0, nil, err
+	}
+	result, err := r.db.Collection("conversations").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, nil, err
+	}
+	return result.DeletedCount, images, nil
+}
+
+func (r *MongoDBRepository) conversationIDs(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error) {
+	cursor, err := r.db.Collection("conversations").Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var conversation Conversation
+		if err = cursor.Decode(&conversation); err != nil {
+			return nil, err
+		}
+		ids = append(ids, conversation.ID)
+	}
+	return ids, cursor.Err()
+}
+
+// domainPatterns matches the emails of the given domains, ignoring case
+func domainPatterns(domains []string) bson.A {
+	patterns := bson.A{}
+	for _, d := range domains {
+		patterns = append(patterns, primitive.Regex{Pattern: "@" + regexp.QuoteMeta(d) + "$", Options: "i"})
+	}
+	return patterns
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertShare stores a new conversation share
+func (r *MongoDBRepository) InsertShare(ctx context.Context, share Share) (Share, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertShare")
+	defer apm.EndTransaction(span)
+
+	share.ID = primitive.NewObjectID()
+	share.CreatedAt = time.Now()
+	_, err := r.db.Collection("shares").InsertOne(ctx, share)
+	return share, err
+}
+
+// GetShareByTokenHash finds a share by the hash of its token
+func (r *MongoDBRepository) GetShareByTokenHash(ctx context.Context, tokenHash string) (Share, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetShareByTokenHash")
+	defer apm.EndTransaction(span)
+
+	var share Share
+	err := r.db.Collection("shares").FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&share)
+	return share, err
+}
+
+// ListShares returns the shares created by a user, newest first
+func (r *MongoDBRepository) ListShares(ctx context.Context, owner string) ([]Share, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListShares")
+	defer apm.EndTransaction(span)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetProjection(bson.M{"messages": 0})
+	cursor, err := r.db.Collection("shares").Find(ctx, bson.M{"owner": owner}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	shares := []Share{}
+	if err = cursor.All(ctx, &shares); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// DeleteShare removes a share of a user, the link stops working at once
+func (r *MongoDBRepository) DeleteShare(ctx context.Context, owner string, id primitive.ObjectID) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::DeleteShare")
+	defer apm.EndTransaction(span)
+
+	result, err := r.db.Collection("shares").DeleteOne(ctx, bson.M{"_id": id, "owner": owner})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
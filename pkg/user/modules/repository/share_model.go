@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Share represents a read-only snapshot of a conversation reachable through a share token.
+type Share struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`        // Unique identifier for the share
+	TokenHash      string             `bson:"token_hash"`           // SHA-256 of the share token, the token itself is not kept
+	Owner          string             `bson:"owner"`                // Email of the user who shared the conversation
+	ConversationID primitive.ObjectID `bson:"conversation_id"`      // Conversation the snapshot was taken from
+	Persona        string             `bson:"persona,omitempty"`    // Persona reference (name@vN) of the conversation
+	Messages       []Message          `bson:"messages"`             // Current branch of the conversation when it was shared
+	Domain         string             `bson:"domain,omitempty"`     // Email domain viewers must sign in with, unset for anyone
+	ExpiresAt      *time.Time         `bson:"expires_at,omitempty"` // Time the share stops working, unset for never
+	CreatedAt      time.Time          `bson:"created_at"`           // Timestamp when the share was created
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// usageGroupFields maps a report grouping to the expression used as the group key
+var usageGroupFields = map[string]interface{}{
+	"day":     bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$created_at"}},
+	"user":    "$principal",
+	"service": "$principal",
+	"tribe":   "$tribe",
+	"model":   "$model",
+}
+
+// InsertUsage stores a usage record in the usage collection
+func (r *MongoDBRepository) InsertUsage(ctx context.Context, usage Usage) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertUsage")
+	defer apm.EndTransaction(span)
+
+	_, err := r.db.Collection("usage").InsertOne(ctx, usage)
+	return err
+}
+
+// AggregateUsage sums usage records created in [from, to) grouped by day, user, service, tribe or model
+func (r *MongoDBRepository) AggregateUsage(ctx context.Context, groupBy string, from time.Time, to time.Time) ([]UsageAggregate, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::AggregateUsage")
+	defer apm.EndTransaction(span)
+
+	groupField, ok := usageGroupFields[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported group by: %s", groupBy)
+	}
+
+	match := bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}
+	switch groupBy {
+	case "user":
+		match["principal_type"] = "user"
+	case "service":
+		match["principal_type"] = "service"
+	case "tribe":
+		// users of a domain without a tribe aren't charged to anyone
+		match["tribe"] = bson.M{"$ne": ""}
+	}
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":               groupField,
+			"requests":          bson.M{"$sum": 1},
+			"errors":            bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", "error"}}, 1, 0}}},
+			"prompt_tokens":     bson.M{"$sum": "$prompt_tokens"},
+			"completion_tokens": bson.M{"$sum": "$completion_tokens"},
+			"total_tokens":      bson.M{"$sum": "$total_tokens"},
+			"cost":              bson.M{"$sum": "$cost"},
+			"avg_latency_ms":    bson.M{"$avg": "$latency_ms"},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := r.db.Collection("usage").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []UsageAggregate{}
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Usage represents a single upstream call recorded for chargeback.
+type Usage struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty"`             // Unique identifier for the record
+	Principal        string             `bson:"principal"`                 // User email or service name
+	PrincipalType    string             `bson:"principal_type"`            // Either user or service
+	Tribe            string             `bson:"tribe"`                     // Tribe owning the principal, if known
+	Kind             string             `bson:"kind"`                      // Either prompt, summary, or service
+	Model            string             `bson:"model"`                     // Model reported by the upstream
+	Upstream         string             `bson:"upstream"`                  // Upstream host that served the call
+	PromptTokens     int                `bson:"prompt_tokens"`             // Tokens in the prompt
+	CompletionTokens int                `bson:"completion_tokens"`         // Tokens in the completion
+	TotalTokens      int                `bson:"total_tokens"`              // Prompt plus completion tokens
+	Cost             float64            `bson:"cost"`                      // Cost computed from the configured pricing
+	LatencyMs        int64              `bson:"latency_ms"`                // Upstream latency in milliseconds
+	Status           string             `bson:"status"`                    // Either success or error
+	Error            string             `bson:"error,omitempty"`           // Error message when status is error
+	Template         string             `bson:"template,omitempty"`        // name@vN of the prompt template the prompt was rendered from
+	RenderedPrompt   string             `bson:"rendered_prompt,omitempty"` // Prompt rendered from the template
+	CreatedAt        time.Time          `bson:"created_at"`                // Timestamp when the call was made
+}
+
+// UsageAggregate represents usage records grouped by a single key.
+type UsageAggregate struct {
+	Key              string  `bson:"_id"`               // Value of the grouped field
+	Requests         int     `bson:"requests"`          // Number of calls
+	Errors           int     `bson:"errors"`            // Number of failed calls
+	PromptTokens     int     `bson:"prompt_tokens"`     // Sum of prompt tokens
+	CompletionTokens int     `bson:"completion_tokens"` // Sum of completion tokens
+	TotalTokens      int     `bson:"total_tokens"`      // Sum of total tokens
+	Cost             float64 `bson:"cost"`              // Sum of cost
+	AvgLatencyMs     float64 `bson:"avg_latency_ms"`    // Average upstream latency
+}
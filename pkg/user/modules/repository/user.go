@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetUser finds a user by email, returns mongo.ErrNoDocuments if the user is unknown
+func (r *MongoDBRepository) GetUser(ctx context.Context, email string) (User, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetUser")
+	defer apm.EndTransaction(span)
+
+	var user User
+	err := r.db.Collection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	return user, err
+}
+
+// UpsertUserRole sets the role of a user, creating the user if needed
+func (r *MongoDBRepository) UpsertUserRole(ctx context.Context, email string, role string) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::UpsertUserRole")
+	defer apm.EndTransaction(span)
+
+	now := time.Now()
+	update := bson.M{
+		"$set":         bson.M{"role": role, "updated_at": now},
+		"$setOnInsert": bson.M{"email": email, "created_at": now},
+	}
+	_, err := r.db.Collection("users").UpdateOne(ctx, bson.M{"email": email}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// TouchUser records the user as seen now, creating the user if needed
+func (r *MongoDBRepository) TouchUser(ctx context.Context, email string, name string) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::TouchUser")
+	defer apm.EndTransaction(span)
+
+	now := time.Now()
+	set := bson.M{"last_seen_at": now}
+	if name != "" {
+		set["name"] = name
+	}
+	update := bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{"email": email, "created_at": now, "updated_at": now},
+	}
+	_, err := r.db.Collection("users").UpdateOne(ctx, bson.M{"email": email}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// SetUserBlocked blocks or unblocks a user, creating the user if needed
+func (r *MongoDBRepository) SetUserBlocked(ctx context.Context, email string, blocked bool) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::SetUserBlocked")
+	defer apm.EndTransaction(span)
+
+	now := time.Now()
+	update := bson.M{
+		"$set":         bson.M{"blocked": blocked, "updated_at": now},
+		"$setOnInsert": bson.M{"email": email, "created_at": now},
+	}
+	_, err := r.db.Collection("users").UpdateOne(ctx, bson.M{"email": email}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// ListUsers returns users ordered by last activity and the total number of users
+func (r *MongoDBRepository) ListUsers(ctx context.Context, offset int, limit int) ([]User, int64, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListUsers")
+	defer apm.EndTransaction(span)
+
+	usersCollection := r.db.Collection("users")
+
+	count, err := usersCollection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "last_seen_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := usersCollection.Find(ctx, bson.M{}, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := []User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+	return users, count, nil
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User represents a known SSO user and the role granted to them.
+type User struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"` // Unique identifier for the user
+	Email      string             `bson:"email"`         // Email from the IdP token, used as the user ID
+	Name       string             `bson:"name"`          // Display name from the IdP token
+	Role       string             `bson:"role"`          // Either admin, user, or service, empty means derived from claims
+	Blocked    bool               `bson:"blocked"`       // Blocked users are rejected by the auth guard
+	LastSeenAt time.Time          `bson:"last_seen_at"`  // Timestamp when the user was last active
+	CreatedAt  time.Time          `bson:"created_at"`    // Timestamp when the user was created
+	UpdatedAt  time.Time          `bson:"updated_at"`    // Timestamp when the user was last updated
+}
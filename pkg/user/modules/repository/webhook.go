@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/abialemuel/poly-kit/infrastructure/apm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertWebhookDelivery stores a new webhook delivery
+func (r *MongoDBRepository) InsertWebhookDelivery(ctx context.Context, delivery WebhookDelivery) (WebhookDelivery, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::InsertWebhookDelivery")
+	defer apm.EndTransaction(span)
+
+	delivery.ID = primitive.NewObjectID()
+	delivery.Attempts = []WebhookAttempt{}
+	delivery.CreatedAt = time.Now()
+	delivery.UpdatedAt = delivery.CreatedAt
+	_, err := r.db.Collection("webhook_deliveries").InsertOne(ctx, delivery)
+	return delivery, err
+}
+
+// UpdateWebhookDelivery replaces a webhook delivery
+func (r *MongoDBRepository) UpdateWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	ctx, span := apm.StartTransaction(ctx, "Repository::UpdateWebhookDelivery")
+	defer apm.EndTransaction(span)
+
+	delivery.UpdatedAt = time.Now()
+	_, err := r.db.Collection("webhook_deliveries").ReplaceOne(ctx, bson.M{"_id": delivery.ID}, delivery)
+	return err
+}
+
+// ClaimDueWebhookDelivery takes a delivery whose next attempt is due, it is hidden from other claims
+// for lease so a single instance makes the attempt. It returns mongo.ErrNoDocuments when none is due.
+func (r *MongoDBRepository) ClaimDueWebhookDelivery(ctx context.Context, lease time.Duration) (WebhookDelivery, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ClaimDueWebhookDelivery")
+	defer apm.EndTransaction(span)
+
+	now := time.Now()
+	filter := bson.M{"status": "delivering", "next_attempt_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease), "updated_at": now}}
+
+	var delivery WebhookDelivery
+	err := r.db.Collection("webhook_deliveries").
+		FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).
+		Decode(&delivery)
+	return delivery, err
+}
+
+// ClaimStalePendingWebhookDelivery takes a delivery still waiting for its result since before,
+// it moves to delivering so it is claimed once. It returns mongo.ErrNoDocuments when there is none.
+func (r *MongoDBRepository) ClaimStalePendingWebhookDelivery(ctx context.Context, before time.Time) (WebhookDelivery, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ClaimStalePendingWebhookDelivery")
+	defer apm.EndTransaction(span)
+
+	filter := bson.M{"status": "pending", "created_at": bson.M{"$lt": before}}
+	update := bson.M{"$set": bson.M{"status": "delivering", "updated_at": time.Now()}}
+
+	var delivery WebhookDelivery
+	err := r.db.Collection("webhook_deliveries").
+		FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).
+		Decode(&delivery)
+	return delivery, err
+}
+
+// GetWebhookDelivery finds a webhook delivery by ID
+func (r *MongoDBRepository) GetWebhookDelivery(ctx context.Context, id string) (WebhookDelivery, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::GetWebhookDelivery")
+	defer apm.EndTransaction(span)
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return WebhookDelivery{}, mongo.ErrNoDocuments
+	}
+
+	var delivery WebhookDelivery
+	err = r.db.Collection("webhook_deliveries").FindOne(ctx, bson.M{"_id": objectID}).Decode(&delivery)
+	return delivery, err
+}
+
+// ListWebhookDeliveries returns the latest webhook deliveries of an owner
+func (r *MongoDBRepository) ListWebhookDeliveries(ctx context.Context, owner string, limit int) ([]WebhookDelivery, error) {
+	ctx, span := apm.StartTransaction(ctx, "Repository::ListWebhookDeliveries")
+	defer apm.EndTransaction(span)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.db.Collection("webhook_deliveries").Find(ctx, bson.M{"owner": owner}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookAttempt represents a single attempt to deliver a webhook.
+type WebhookAttempt struct {
+	At         time.Time `bson:"at"`              // Timestamp when the attempt was made
+	StatusCode int       `bson:"status_code"`     // HTTP status returned by the callback, 0 if none
+	Error      string    `bson:"error,omitempty"` // Transport error, if any
+	DurationMs int64     `bson:"duration_ms"`     // Time taken by the attempt
+}
+
+// WebhookDelivery represents a callback to a backend service and every attempt made to deliver it.
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`   // Unique identifier for the delivery
+	Owner         string             `bson:"owner"`           // Service the callback belongs to
+	Event         string             `bson:"event"`           // What is being delivered, e.g. service_prompt.completed
+	CallbackURL   string             `bson:"callback_url"`    // Allow-listed URL the payload is POSTed to
+	CorrelationID string             `bson:"correlation_id"`  // Correlation ID of the originating request
+	Payload       string             `bson:"payload"`         // JSON body, empty while the result is pending
+	Status        string             `bson:"status"`          // pending, delivering, succeeded, or failed
+	Attempts      []WebhookAttempt   `bson:"attempts"`        // Every delivery attempt
+	NextAttemptAt *time.Time         `bson:"next_attempt_at"` // When the next attempt is due while delivering
+	CreatedAt     time.Time          `bson:"created_at"`      // Timestamp when the delivery was created
+	UpdatedAt     time.Time          `bson:"updated_at"`      // Timestamp when the delivery was last updated
+}
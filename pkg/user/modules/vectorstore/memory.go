@@ -0,0 +1,222 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/abialemuel/AI-Proxy-Service/pkg/common/cache"
+)
+
+const (
+	redisKeyIndex = "vectorstore-%s"
+	redisKeyEntry = "vectorstore-%s-%s"
+	redisKeyOwner = "vectorstore-owner-%s"
+)
+
+// MemoryStore is an in-process vector index searched by brute-force cosine similarity.
+// When persist is set, entries are also written to it and loaded back lazily per namespace,
+// so the index survives restarts and is shared between instances on first use.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	namespaces map[string][]Entry
+	loaded     map[string]bool
+	maxEntries int
+	ttl        time.Duration
+	persist    cache.CacheInterface
+}
+
+// NewMemoryStore creates a store keeping at most maxEntries per namespace for ttl, ttl 0 keeps entries forever
+func NewMemoryStore(maxEntries int, ttl time.Duration, persist cache.CacheInterface) *MemoryStore {
+	return &MemoryStore{
+		namespaces: make(map[string][]Entry),
+		loaded:     make(map[string]bool),
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		persist:    persist,
+	}
+}
+
+// Search returns the most similar live entry of the namespace if its score is at least minScore
+func (s *MemoryStore) Search(ctx context.Context, namespace string, vector []float32, minScore float64) (Match, bool) {
+	s.load(ctx, namespace)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var best Match
+	found := false
+	for _, entry := range s.namespaces[namespace] {
+		if s.expired(entry) {
+			continue
+		}
+		score := Cosine(vector, entry.Vector)
+		if score >= minScore && (!found || score > best.Score) {
+			best = Match{Entry: entry, Score: score}
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Add stores an entry, evicting expired entries and then the oldest ones beyond maxEntries
+func (s *MemoryStore) Add(ctx context.Context, namespace string, entry Entry) error {
+	s.load(ctx, namespace)
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	s.mu.Lock()
+	entries := []Entry{}
+	for _, e := range s.namespaces[namespace] {
+		if !s.expired(e) {
+			entries = append(entries, e)
+		}
+	}
+	entries = append(entries, entry)
+	if s.maxEntries > 0 && len(entries) > s.maxEntries {
+		entries = entries[len(entries)-s.maxEntries:]
+	}
+	s.namespaces[namespace] = entries
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	s.mu.Unlock()
+
+	if s.persist == nil {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := s.persist.Set(ctx, fmt.Sprintf(redisKeyEntry, namespace, entry.ID), data, s.ttl); err != nil {
+		return err
+	}
+	index, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	if err := s.persist.Set(ctx, fmt.Sprintf(redisKeyIndex, namespace), index, s.ttl); err != nil {
+		return err
+	}
+	if entry.Owner == "" {
+		return nil
+	}
+
+	// the entries of an owner are spread over namespaces that may not be loaded, keep a list of them
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refs := append(s.ownerRefs(ctx, entry.Owner), entryRef{Namespace: namespace, ID: entry.ID})
+	data, err = json.Marshal(refs)
+	if err != nil {
+		return err
+	}
+	return s.persist.Set(ctx, fmt.Sprintf(redisKeyOwner, entry.Owner), data, s.ttl)
+}
+
+// DeleteOwner removes every entry of an owner, from memory and the persistence layer, and returns how many there were
+func (s *MemoryStore) DeleteOwner(ctx context.Context, owner string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := map[entryRef]bool{}
+	for namespace, entries := range s.namespaces {
+		kept := []Entry{}
+		for _, e := range entries {
+			if e.Owner == owner {
+				removed[entryRef{Namespace: namespace, ID: e.ID}] = true
+				continue
+			}
+			kept = append(kept, e)
+		}
+		s.namespaces[namespace] = kept
+	}
+	if s.persist == nil {
+		return len(removed), nil
+	}
+
+	// namespaces are loaded lazily, entries missing from their index are skipped on load
+	for _, ref := range s.ownerRefs(ctx, owner) {
+		removed[ref] = true
+	}
+	for ref := range removed {
+		if err := s.persist.Delete(ctx, fmt.Sprintf(redisKeyEntry, ref.Namespace, ref.ID)); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.persist.Delete(ctx, fmt.Sprintf(redisKeyOwner, owner)); err != nil {
+		return 0, err
+	}
+	return len(removed), nil
+}
+
+// ownerRefs returns the persisted entries of an owner, callers hold the lock
+func (s *MemoryStore) ownerRefs(ctx context.Context, owner string) []entryRef {
+	var refs []entryRef
+	if data, found := s.persist.Get(ctx, fmt.Sprintf(redisKeyOwner, owner)); found {
+		json.Unmarshal([]byte(data.(string)), &refs)
+	}
+	return refs
+}
+
+// load reads a namespace from the persistence layer the first time it is used
+func (s *MemoryStore) load(ctx context.Context, namespace string) {
+	if s.persist == nil {
+		return
+	}
+	s.mu.RLock()
+	loaded := s.loaded[namespace]
+	s.mu.RUnlock()
+	if loaded {
+		return
+	}
+
+	var entries []Entry
+	var ids []string
+	if data, found := s.persist.Get(ctx, fmt.Sprintf(redisKeyIndex, namespace)); found {
+		json.Unmarshal([]byte(data.(string)), &ids)
+	}
+	for _, id := range ids {
+		data, found := s.persist.Get(ctx, fmt.Sprintf(redisKeyEntry, namespace, id))
+		if !found {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(data.(string)), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loaded[namespace] {
+		s.namespaces[namespace] = append(entries, s.namespaces[namespace]...)
+		s.loaded[namespace] = true
+	}
+}
+
+func (s *MemoryStore) expired(entry Entry) bool {
+	return s.ttl > 0 && time.Since(entry.CreatedAt) > s.ttl
+}
+
+// Cosine returns the cosine similarity of two vectors, 0 when their lengths differ
+func Cosine(a []float32, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,24 @@
+package vectorstore
+
+import "time"
+
+// Entry is a vector and the payload it was computed for
+type Entry struct {
+	ID        string    `json:"id"`
+	Vector    []float32 `json:"vector"`
+	Payload   string    `json:"payload"`
+	Owner     string    `json:"owner,omitempty"` // who the payload was computed for, entries are deleted by owner
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// entryRef locates an entry in the persistence layer
+type entryRef struct {
+	Namespace string `json:"namespace"`
+	ID        string `json:"id"`
+}
+
+// Match is an entry found by a search and its cosine similarity to the query
+type Match struct {
+	Entry
+	Score float64
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+type WebhookSender struct {
+	client *http.Client
+}
+
+func NewWebhookSender(timeout time.Duration) WebhookSender {
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 2,
+		},
+		// callbacks must hit the allow-listed host, never follow redirects elsewhere
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return WebhookSender{client: client}
+}
+
+// Send POSTs the JSON body to url and returns the response status code
+func (ws WebhookSender) Send(ctx context.Context, url string, headers map[string]string, body []byte) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+	for k, v := range headers {
+		request.Header.Add(k, v)
+	}
+
+	response, err := ws.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+
+	return response.StatusCode, nil
+}